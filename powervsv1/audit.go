@@ -0,0 +1,508 @@
+package powervsv1
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// Formats supported by ExportAuditEvents
+const (
+	AuditFormatJSONL  = "jsonl"
+	AuditFormatCSV    = "csv"
+	AuditFormatCEF    = "cef"
+	AuditFormatSyslog = "syslog"
+)
+
+// DefaultAuditLookback is how far back the first export goes when no checkpoint exists
+const DefaultAuditLookback = 24 * time.Hour
+
+// AuditCSVHeader : Columns of the CSV audit format
+var AuditCSVHeader = []string{"time", "eventID", "level", "resource", "action", "userID", "userName", "userEmail", "message", "metadata"}
+
+// syslogFacilityAudit RFC 5424 facility of log audit messages
+const syslogFacilityAudit = 13
+
+// syslogEnterpriseID Private enterprise number of the structured data element
+const syslogEnterpriseID = "32473"
+
+// AuditExportOptions : The ExportAuditEvents options.
+type AuditExportOptions struct {
+	CloudInstanceID string `validate:"required"`
+
+	// One of the AuditFormat constants.
+	Format string `validate:"required"`
+
+	// Destination of the encoded events.
+	Sink AuditSink `validate:"required"`
+
+	// File keeping the last exported event between runs. Without it every run starts from Since.
+	CheckpointPath string
+
+	// Start of the export when there is no checkpoint, defaults to DefaultAuditLookback ago.
+	Since time.Time
+
+	// Size of the event windows queried one at a time, defaults to DefaultEventWindow.
+	WindowSize time.Duration
+
+	// HOSTNAME of syslog messages, defaults to the local host name.
+	Hostname string
+
+	// APP-NAME of syslog messages, defaults to "powervs".
+	AppName string
+}
+
+// AuditExportResult : Outcome of ExportAuditEvents
+type AuditExportResult struct {
+	// Number of events written to the sink.
+	Exported int
+
+	Checkpoint *AuditCheckpoint
+}
+
+// AuditCheckpoint : Position of an audit export, saved after every batch written to the sink
+type AuditCheckpoint struct {
+	CloudInstanceID string `json:"cloudInstanceID"`
+
+	// Time of the last exported event.
+	LastEventTime time.Time `json:"lastEventTime"`
+
+	// IDs of the exported events sharing LastEventTime, skipped by the next run.
+	LastEventIDs []string `json:"lastEventIDs"`
+}
+
+// ReadAuditCheckpoint : Load a checkpoint, nil when the file does not exist
+func ReadAuditCheckpoint(path string) (*AuditCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := &AuditCheckpoint{}
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse audit checkpoint %s: %w", path, err)
+	}
+	return checkpoint, nil
+}
+
+// WriteFile : Save the checkpoint, replacing the file atomically
+func (checkpoint *AuditCheckpoint) WriteFile(path string) error {
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// exported Whether an event was written by a previous batch
+func (checkpoint *AuditCheckpoint) exported(event Event) bool {
+	t := eventTime(event)
+	if t.Before(checkpoint.LastEventTime) {
+		return true
+	}
+	if !t.Equal(checkpoint.LastEventTime) {
+		return false
+	}
+	id := core.StringNilMapper(event.EventID)
+	for _, last := range checkpoint.LastEventIDs {
+		if last == id {
+			return true
+		}
+	}
+	return false
+}
+
+// advance Record an event written to the sink
+func (checkpoint *AuditCheckpoint) advance(event Event) {
+	t := eventTime(event)
+	id := core.StringNilMapper(event.EventID)
+	if t.After(checkpoint.LastEventTime) {
+		checkpoint.LastEventTime = t
+		checkpoint.LastEventIDs = []string{id}
+	} else if t.Equal(checkpoint.LastEventTime) {
+		checkpoint.LastEventIDs = append(checkpoint.LastEventIDs, id)
+	}
+}
+
+// ExportAuditEvents : Ship the events of a workspace to a sink, resuming after the last checkpoint
+func (powervs *PowervsV1) ExportAuditEvents(ctx context.Context, options *AuditExportOptions) (*AuditExportResult, error) {
+	if err := core.ValidateNotNil(options, "options cannot be nil"); err != nil {
+		return nil, err
+	}
+	if err := core.ValidateStruct(options, "auditExportOptions"); err != nil {
+		return nil, err
+	}
+	encoder, err := newAuditEncoder(options)
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint *AuditCheckpoint
+	if options.CheckpointPath != "" {
+		if checkpoint, err = ReadAuditCheckpoint(options.CheckpointPath); err != nil {
+			return nil, err
+		}
+	}
+	if checkpoint != nil && checkpoint.CloudInstanceID != options.CloudInstanceID {
+		return nil, fmt.Errorf("checkpoint %s belongs to workspace %s", options.CheckpointPath, checkpoint.CloudInstanceID)
+	}
+	first := checkpoint == nil
+	if first {
+		since := options.Since
+		if since.IsZero() {
+			since = time.Now().Add(-DefaultAuditLookback)
+		}
+		// Start right before Since so that events at Since are exported
+		checkpoint = &AuditCheckpoint{CloudInstanceID: options.CloudInstanceID, LastEventTime: since.Add(-time.Nanosecond)}
+	}
+
+	result := &AuditExportResult{Checkpoint: checkpoint}
+	pager, err := powervs.NewEventPager(&EventPagerOptions{
+		CloudInstanceID: options.CloudInstanceID,
+		From:            checkpoint.LastEventTime.Truncate(time.Second),
+		WindowSize:      options.WindowSize,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	save := func() error {
+		if options.CheckpointPath == "" {
+			return nil
+		}
+		if err := checkpoint.WriteFile(options.CheckpointPath); err != nil {
+			return fmt.Errorf("failed to save audit checkpoint: %w", err)
+		}
+		return nil
+	}
+	if first && options.Format == AuditFormatCSV {
+		if err := options.Sink.Write([][]byte{encodeCSVRecord(AuditCSVHeader)}); err != nil {
+			return result, fmt.Errorf("failed to write to audit sink: %w", err)
+		}
+		// Save right away so that later runs do not write the header again
+		if err := save(); err != nil {
+			return result, err
+		}
+	}
+	for pager.HasNext() {
+		events, err := pager.GetNextWithContext(ctx)
+		if err != nil {
+			return result, err
+		}
+		var records [][]byte
+		var batch []Event
+		for _, event := range events {
+			if checkpoint.exported(event) {
+				continue
+			}
+			record, err := encoder(event)
+			if err != nil {
+				return result, fmt.Errorf("failed to encode event %s: %w", core.StringNilMapper(event.EventID), err)
+			}
+			records = append(records, record)
+			batch = append(batch, event)
+		}
+		if len(records) == 0 {
+			continue
+		}
+		if err := options.Sink.Write(records); err != nil {
+			return result, fmt.Errorf("failed to write to audit sink: %w", err)
+		}
+		for _, event := range batch {
+			checkpoint.advance(event)
+		}
+		result.Exported += len(records)
+		if err := save(); err != nil {
+			return result, err
+		}
+	}
+	// A first run without events still records where the next run starts
+	if first && result.Exported == 0 {
+		if err := save(); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// newAuditEncoder Encoder of one event in the requested format
+func newAuditEncoder(options *AuditExportOptions) (func(Event) ([]byte, error), error) {
+	switch options.Format {
+	case AuditFormatJSONL:
+		return func(event Event) ([]byte, error) { return json.Marshal(event) }, nil
+	case AuditFormatCSV:
+		return EncodeAuditCSV, nil
+	case AuditFormatCEF:
+		return func(event Event) ([]byte, error) { return EncodeAuditCEF(event), nil }, nil
+	case AuditFormatSyslog:
+		hostname := options.Hostname
+		if hostname == "" {
+			hostname, _ = os.Hostname()
+		}
+		appName := options.AppName
+		if appName == "" {
+			appName = "powervs"
+		}
+		return func(event Event) ([]byte, error) { return EncodeAuditSyslog(event, hostname, appName), nil }, nil
+	default:
+		return nil, fmt.Errorf("unsupported audit format %q", options.Format)
+	}
+}
+
+// EncodeAuditCSV : Encode an event as a CSV row with the AuditCSVHeader columns
+func EncodeAuditCSV(event Event) ([]byte, error) {
+	metadata := ""
+	if len(event.Metadata) > 0 {
+		raw, err := json.Marshal(event.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		metadata = string(raw)
+	}
+	user := event.User
+	if user == nil {
+		user = &EventUser{}
+	}
+	return encodeCSVRecord([]string{
+		eventTime(event).UTC().Format(time.RFC3339Nano),
+		core.StringNilMapper(event.EventID),
+		core.StringNilMapper(event.Level),
+		core.StringNilMapper(event.Resource),
+		core.StringNilMapper(event.Action),
+		core.StringNilMapper(user.UserID),
+		core.StringNilMapper(user.Name),
+		core.StringNilMapper(user.Email),
+		core.StringNilMapper(event.Message),
+		metadata,
+	}), nil
+}
+
+// encodeCSVRecord One CSV row without its line terminator
+func encodeCSVRecord(fields []string) []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write(fields)
+	w.Flush()
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// EncodeAuditCEF : Encode an event in the ArcSight Common Event Format
+func EncodeAuditCEF(event Event) []byte {
+	header := strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	extension := strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`)
+
+	resource := core.StringNilMapper(event.Resource)
+	action := core.StringNilMapper(event.Action)
+	message := core.StringNilMapper(event.Message)
+	severity := map[string]int{"debug": 1, "info": 3, "notice": 4, "warning": 6, "error": 8, "critical": 10}[strings.ToLower(core.StringNilMapper(event.Level))]
+	if severity == 0 {
+		severity = 5
+	}
+
+	fields := []string{
+		"rt=" + strconv.FormatInt(eventTime(event).UnixMilli(), 10),
+		"externalId=" + extension.Replace(core.StringNilMapper(event.EventID)),
+		"act=" + extension.Replace(action),
+		"cs1Label=resource",
+		"cs1=" + extension.Replace(resource),
+	}
+	if event.User != nil {
+		fields = append(fields, "suid="+extension.Replace(core.StringNilMapper(event.User.UserID)))
+		if event.User.Name != nil {
+			fields = append(fields, "suser="+extension.Replace(*event.User.Name))
+		}
+	}
+	fields = append(fields, "msg="+extension.Replace(message))
+
+	return []byte(fmt.Sprintf("CEF:0|IBM|Power Virtual Server|1.0|%s|%s|%d|%s",
+		header.Replace(resource+"."+action), header.Replace(message), severity, strings.Join(fields, " ")))
+}
+
+// EncodeAuditSyslog : Encode an event as an RFC 5424 syslog message of the log audit facility
+func EncodeAuditSyslog(event Event, hostname string, appName string) []byte {
+	severity, ok := map[string]int{"critical": 2, "error": 3, "warning": 4, "notice": 5, "info": 6, "debug": 7}[strings.ToLower(core.StringNilMapper(event.Level))]
+	if !ok {
+		severity = 5
+	}
+	param := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+	data := fmt.Sprintf(`[powervs@%s eventID="%s" resource="%s" action="%s"`, syslogEnterpriseID,
+		param.Replace(core.StringNilMapper(event.EventID)), param.Replace(core.StringNilMapper(event.Resource)), param.Replace(core.StringNilMapper(event.Action)))
+	if event.User != nil {
+		data += fmt.Sprintf(` userID="%s"`, param.Replace(core.StringNilMapper(event.User.UserID)))
+	}
+	data += "]"
+
+	return []byte(fmt.Sprintf("<%d>1 %s %s %s - %s %s %s",
+		syslogFacilityAudit*8+severity,
+		eventTime(event).UTC().Format(time.RFC3339Nano),
+		syslogHeaderField(hostname, 255),
+		syslogHeaderField(appName, 48),
+		syslogHeaderField(core.StringNilMapper(event.Action), 32),
+		data,
+		core.StringNilMapper(event.Message)))
+}
+
+// syslogHeaderField Printable header field of at most max characters, "-" when empty
+func syslogHeaderField(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	if len(s) > max {
+		s = s[:max]
+	}
+	return s
+}
+
+// AuditSink : Destination of encoded audit events
+type AuditSink interface {
+	// Write delivers a batch of records, each without line terminator.
+	Write(records [][]byte) error
+
+	Close() error
+}
+
+// WriterAuditSink : AuditSink writing one record per line, to os.Stdout for instance
+type WriterAuditSink struct {
+	w io.Writer
+}
+
+// NewWriterAuditSink : Instantiate WriterAuditSink
+func NewWriterAuditSink(w io.Writer) *WriterAuditSink {
+	return &WriterAuditSink{w: w}
+}
+
+// Write : Write the records, one per line
+func (sink *WriterAuditSink) Write(records [][]byte) error {
+	var buf bytes.Buffer
+	for _, record := range records {
+		buf.Write(record)
+		buf.WriteByte('\n')
+	}
+	_, err := sink.w.Write(buf.Bytes())
+	return err
+}
+
+// Close : Close the writer if it is an io.Closer other than stdout or stderr
+func (sink *WriterAuditSink) Close() error {
+	if closer, ok := sink.w.(io.Closer); ok && sink.w != os.Stdout && sink.w != os.Stderr {
+		return closer.Close()
+	}
+	return nil
+}
+
+// NewFileAuditSink : AuditSink appending one record per line to a file
+func NewFileAuditSink(path string) (*WriterAuditSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &WriterAuditSink{w: file}, nil
+}
+
+// SyslogAuditSink : AuditSink sending records to a syslog server over TCP or UDP
+type SyslogAuditSink struct {
+	network string
+	conn    net.Conn
+}
+
+// NewSyslogAuditSink : Connect to a syslog server, network is "tcp" or "udp".
+// TCP messages are framed with octet counting as described by RFC 6587.
+func NewSyslogAuditSink(network string, address string) (*SyslogAuditSink, error) {
+	if !strings.HasPrefix(network, "tcp") && !strings.HasPrefix(network, "udp") {
+		return nil, fmt.Errorf("unsupported syslog network %q", network)
+	}
+	conn, err := net.DialTimeout(network, address, 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogAuditSink{network: network, conn: conn}, nil
+}
+
+// Write : Send one message per record
+func (sink *SyslogAuditSink) Write(records [][]byte) error {
+	for _, record := range records {
+		message := record
+		if strings.HasPrefix(sink.network, "tcp") {
+			message = append([]byte(strconv.Itoa(len(record))+" "), record...)
+		}
+		if _, err := sink.conn.Write(message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close : Close the connection
+func (sink *SyslogAuditSink) Close() error {
+	return sink.conn.Close()
+}
+
+// WebhookAuditSink : AuditSink posting each batch to an HTTP endpoint, one record per line
+type WebhookAuditSink struct {
+	url     string
+	client  *http.Client
+	headers http.Header
+}
+
+// NewWebhookAuditSink : Instantiate WebhookAuditSink, headers such as Authorization are sent with every batch
+func NewWebhookAuditSink(url string, headers http.Header) *WebhookAuditSink {
+	return &WebhookAuditSink{url: url, client: &http.Client{Timeout: time.Minute}, headers: headers}
+}
+
+// Write : Post the batch, failing on any non 2xx status
+func (sink *WebhookAuditSink) Write(records [][]byte) error {
+	request, err := http.NewRequest(http.MethodPost, sink.url, bytes.NewReader(bytes.Join(records, []byte("\n"))))
+	if err != nil {
+		return err
+	}
+	for key, values := range sink.headers {
+		request.Header[key] = values
+	}
+	if request.Header.Get("Content-Type") == "" {
+		request.Header.Set("Content-Type", "text/plain")
+	}
+	response, err := sink.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook %s returned %s", sink.url, response.Status)
+	}
+	return nil
+}
+
+// Close : Nothing to release
+func (sink *WebhookAuditSink) Close() error {
+	return nil
+}
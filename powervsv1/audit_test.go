@@ -0,0 +1,176 @@
+package powervsv1
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/go-openapi/strfmt"
+)
+
+func TestEncodeAuditEvent(t *testing.T) {
+	at := strfmt.DateTime(time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC))
+	event := Event{
+		EventID:  core.StringPtr("ev-1"),
+		Action:   core.StringPtr("create"),
+		Level:    core.StringPtr("warning"),
+		Resource: core.StringPtr("pvm-instance"),
+		Message:  core.StringPtr(`created "app" a=b|c`),
+		Time:     &at,
+		User:     &EventUser{UserID: core.StringPtr("u-1"), Name: core.StringPtr("Ops Team")},
+		Metadata: map[string]interface{}{"serverName": "app"},
+	}
+
+	csvRecord, err := EncodeAuditCSV(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		format string
+		got    string
+		want   string
+	}{
+		{AuditFormatCSV, string(csvRecord),
+			`2024-03-10T12:00:00Z,ev-1,warning,pvm-instance,create,u-1,Ops Team,,"created ""app"" a=b|c","{""serverName"":""app""}"`},
+		{AuditFormatCEF, string(EncodeAuditCEF(event)),
+			`CEF:0|IBM|Power Virtual Server|1.0|pvm-instance.create|created "app" a=b\|c|6|rt=1710072000000 externalId=ev-1 act=create cs1Label=resource cs1=pvm-instance suid=u-1 suser=Ops Team msg=created "app" a\=b|c`},
+		{AuditFormatSyslog, string(EncodeAuditSyslog(event, "host 1", "powervs")),
+			`<108>1 2024-03-10T12:00:00Z host_1 powervs - create [powervs@32473 eventID="ev-1" resource="pvm-instance" action="create" userID="u-1"] created "app" a=b|c`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("encoded =\n%s\nwant\n%s", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportAuditEvents(t *testing.T) {
+	events := []string{
+		`{"eventID": "1", "action": "create", "resource": "volume", "level": "info", "message": "m1", "time": "2024-03-10T10:00:00Z", "timestamp": 0}`,
+		`{"eventID": "2", "action": "delete", "resource": "volume", "level": "info", "message": "m2", "time": "2024-03-10T10:30:00Z", "timestamp": 0}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		from, _ := time.Parse(time.RFC3339, r.URL.Query().Get("from_time"))
+		to, _ := time.Parse(time.RFC3339, r.URL.Query().Get("to_time"))
+		var matching []string
+		for _, event := range events {
+			at, _ := time.Parse(time.RFC3339, strings.Split(strings.Split(event, `"time": "`)[1], `"`)[0])
+			if !at.Before(from) && !at.After(to) {
+				matching = append(matching, event)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"events": [%s]}`, strings.Join(matching, ","))
+	}))
+	defer server.Close()
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	options := &AuditExportOptions{
+		CloudInstanceID: "ws",
+		Format:          AuditFormatJSONL,
+		Sink:            NewWriterAuditSink(&out),
+		CheckpointPath:  filepath.Join(t.TempDir(), "checkpoint.json"),
+		Since:           time.Date(2024, 3, 10, 10, 0, 0, 0, time.UTC),
+		WindowSize:      100000 * time.Hour,
+	}
+	result, err := powervs.ExportAuditEvents(context.Background(), options)
+	if err != nil {
+		t.Fatalf("ExportAuditEvents() error = %v", err)
+	}
+	if result.Exported != 2 || strings.Count(out.String(), "\n") != 2 {
+		t.Fatalf("first run exported %d events:\n%s", result.Exported, out.String())
+	}
+
+	events = append(events, `{"eventID": "3", "action": "attach", "resource": "volume", "level": "info", "message": "m3", "time": "2024-03-10T10:30:00Z", "timestamp": 0}`)
+	out.Reset()
+	result, err = powervs.ExportAuditEvents(context.Background(), options)
+	if err != nil {
+		t.Fatalf("ExportAuditEvents() error = %v", err)
+	}
+	if result.Exported != 1 || !strings.Contains(out.String(), `"eventID":"3"`) {
+		t.Errorf("second run exported %d events:\n%s", result.Exported, out.String())
+	}
+
+	checkpoint, err := ReadAuditCheckpoint(options.CheckpointPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !checkpoint.LastEventTime.Equal(time.Date(2024, 3, 10, 10, 30, 0, 0, time.UTC)) || len(checkpoint.LastEventIDs) != 2 {
+		t.Errorf("checkpoint = %+v", checkpoint)
+	}
+
+	options.CloudInstanceID = "other"
+	if _, err := powervs.ExportAuditEvents(context.Background(), options); err == nil {
+		t.Error("ExportAuditEvents() expected error for a checkpoint of another workspace")
+	}
+}
+
+func TestExportAuditEventsWithoutEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"events": []}`))
+	}))
+	defer server.Close()
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	options := &AuditExportOptions{
+		CloudInstanceID: "ws",
+		Format:          AuditFormatCSV,
+		Sink:            NewWriterAuditSink(&out),
+		CheckpointPath:  filepath.Join(t.TempDir(), "checkpoint.json"),
+		Since:           time.Date(2024, 3, 10, 10, 0, 0, 0, time.UTC),
+		WindowSize:      100000 * time.Hour,
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := powervs.ExportAuditEvents(context.Background(), options); err != nil {
+			t.Fatalf("ExportAuditEvents() error = %v", err)
+		}
+	}
+	if header := string(encodeCSVRecord(AuditCSVHeader)) + "\n"; out.String() != header {
+		t.Errorf("ExportAuditEvents() wrote %q, want the header once", out.String())
+	}
+	if checkpoint, err := ReadAuditCheckpoint(options.CheckpointPath); checkpoint == nil || err != nil {
+		t.Errorf("ReadAuditCheckpoint() = %v, error = %v", checkpoint, err)
+	}
+}
+
+func TestWebhookAuditSink(t *testing.T) {
+	var body, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := new(bytes.Buffer)
+		_, _ = buf.ReadFrom(r.Body)
+		body, auth = buf.String(), r.Header.Get("Authorization")
+		if auth == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	sink := NewWebhookAuditSink(server.URL, http.Header{"Authorization": []string{"Bearer t"}})
+	if err := sink.Write([][]byte{[]byte("a"), []byte("b")}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if body != "a\nb" || auth != "Bearer t" {
+		t.Errorf("webhook received %q with Authorization %q", body, auth)
+	}
+	if err := NewWebhookAuditSink(server.URL, nil).Write([][]byte{[]byte("a")}); err == nil {
+		t.Error("Write() expected error on 401")
+	}
+}
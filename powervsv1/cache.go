@@ -0,0 +1,482 @@
+package powervsv1
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Operations served by the read-through cache
+const (
+	CacheOperationStockImages  = "PcloudCloudinstancesStockimagesGetall"
+	CacheOperationImages       = "PcloudImagesGetall"
+	CacheOperationStorageTypes = "PcloudStoragecapacityTypesGetall"
+	CacheOperationStorageTiers = "PcloudCloudinstancesStoragetiersGetall"
+	CacheOperationDatacenters  = "DatacentersGetall"
+	CacheOperationCatalog      = "CatalogGet"
+	CacheOperationSapProfiles  = "PcloudSapGetall"
+)
+
+// cacheOperationPaths Path templates of the cached operations, "{}" matches any segment
+var cacheOperationPaths = map[string]string{
+	CacheOperationStockImages:  "/pcloud/v1/cloud-instances/{}/stock-images",
+	CacheOperationImages:       "/pcloud/v1/images",
+	CacheOperationStorageTypes: "/pcloud/v1/cloud-instances/{}/storage-capacity/storage-types",
+	CacheOperationStorageTiers: "/pcloud/v1/cloud-instances/{}/storage-tiers",
+	CacheOperationDatacenters:  "/v1/datacenters",
+	CacheOperationCatalog:      "/v2/catalog",
+	CacheOperationSapProfiles:  "/pcloud/v1/cloud-instances/{}/sap",
+}
+
+// DefaultCacheTTLs : Time to live of the cached operations when CacheOptions.TTLs does not set them
+var DefaultCacheTTLs = map[string]time.Duration{
+	CacheOperationStockImages:  time.Hour,
+	CacheOperationImages:       time.Hour,
+	CacheOperationStorageTypes: 15 * time.Minute,
+	CacheOperationStorageTiers: 15 * time.Minute,
+	CacheOperationDatacenters:  time.Hour,
+	CacheOperationCatalog:      24 * time.Hour,
+	CacheOperationSapProfiles:  24 * time.Hour,
+}
+
+// CacheOptions : Configuration of the read-through cache
+type CacheOptions struct {
+	// Where responses are kept, defaults to an in-memory store.
+	Store CacheStore
+
+	// Time to live by operation, overriding DefaultCacheTTLs. A negative value disables caching of the operation.
+	TTLs map[string]time.Duration
+
+	// Identity of the account or credentials in the cache keys, like an account ID. Defaults to a
+	// hash of the account and IAM ID claims of IAM bearer tokens, which survive token refreshes and
+	// new processes, or of the Authorization header for other credentials. A store shared by
+	// clients of different accounts or credentials must give each its own Namespace or rely on
+	// the default.
+	Namespace string
+}
+
+// CacheEntry : Response kept by a CacheStore
+type CacheEntry struct {
+	// Cache key, the operation name followed by the namespace and the request URL.
+	Key string `json:"key"`
+
+	StatusCode int `json:"statusCode"`
+
+	Header http.Header `json:"header"`
+
+	Body []byte `json:"body"`
+
+	// When the response was fetched or last revalidated.
+	StoredAt time.Time `json:"storedAt"`
+}
+
+// CacheStore : Storage backend of the read-through cache
+type CacheStore interface {
+	// Get returns nil when the key is not cached.
+	Get(key string) (*CacheEntry, error)
+
+	Set(entry *CacheEntry) error
+
+	// DeletePrefix removes every entry whose key starts with prefix.
+	DeletePrefix(prefix string) error
+}
+
+// EnableCache : Serve rarely changing catalog operations from a cache.
+// Expired entries holding an ETag are revalidated with If-None-Match.
+// Entries are kept per namespace, see CacheOptions.Namespace, so that a store is never shared
+// across accounts or credentials. Stores with a DeleteExpired method, like DiskCacheStore, are
+// cleared of the entries older than the longest TTL.
+// It can be combined with EnableRetries and EnableOptionsValidation in any order.
+func (powervs *PowervsV1) EnableCache(options *CacheOptions) {
+	if options == nil {
+		options = &CacheOptions{}
+	}
+	store := options.Store
+	if store == nil {
+		store = NewMemoryCacheStore()
+	}
+	ttls := make(map[string]time.Duration, len(DefaultCacheTTLs))
+	for operation, ttl := range DefaultCacheTTLs {
+		ttls[operation] = ttl
+	}
+	for operation, ttl := range options.TTLs {
+		ttls[operation] = ttl
+	}
+
+	// Entries older than every TTL can no longer be served
+	if expiring, ok := store.(interface{ DeleteExpired(time.Duration) error }); ok {
+		maxTTL := time.Duration(0)
+		for _, ttl := range ttls {
+			if ttl > maxTTL {
+				maxTTL = ttl
+			}
+		}
+		_ = expiring.DeleteExpired(maxTTL)
+	}
+
+	powervs.DisableCache()
+	powervs.addTransportLayer(func(next http.RoundTripper) transportLayer {
+		return &cacheTransport{next: next, store: store, ttls: ttls, namespace: options.Namespace, now: time.Now}
+	})
+}
+
+// DisableCache : Stop using the cache, its content is left in the store
+func (powervs *PowervsV1) DisableCache() {
+	powervs.removeTransportLayer(isCacheTransport)
+}
+
+// InvalidateCache : Drop the cached responses of the given operations, or of all operations when none is given
+func (powervs *PowervsV1) InvalidateCache(operations ...string) error {
+	transport := powervs.cacheTransport()
+	if transport == nil {
+		return errors.New("cache is not enabled")
+	}
+	if len(operations) == 0 {
+		return transport.store.DeletePrefix("")
+	}
+	for _, operation := range operations {
+		if _, ok := cacheOperationPaths[operation]; !ok {
+			return fmt.Errorf("operation %s is not cached", operation)
+		}
+		if err := transport.store.DeletePrefix(operation + " "); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cacheTransport Caching transport installed by EnableCache, nil when disabled
+func (powervs *PowervsV1) cacheTransport() *cacheTransport {
+	transport, _ := powervs.findTransportLayer(isCacheTransport).(*cacheTransport)
+	return transport
+}
+
+// isCacheTransport Whether layer is the caching transport
+func isCacheTransport(layer transportLayer) bool {
+	_, ok := layer.(*cacheTransport)
+	return ok
+}
+
+// cacheTransport http.RoundTripper serving cached operations from a CacheStore
+type cacheTransport struct {
+	next      http.RoundTripper
+	store     CacheStore
+	ttls      map[string]time.Duration
+	namespace string
+	now       func() time.Time
+}
+
+// nextTransport Transport sending the requests that are not served from the cache
+func (t *cacheTransport) nextTransport() *http.RoundTripper {
+	return &t.next
+}
+
+// RoundTrip Serve fresh entries, revalidate stale ones and store new responses
+func (t *cacheTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	operation := cacheOperation(request)
+	ttl := t.ttls[operation]
+	if operation == "" || ttl <= 0 {
+		return t.next.RoundTrip(request)
+	}
+	namespace := t.namespace
+	if namespace == "" {
+		// The authenticator has set the credentials of the request already
+		namespace = cacheNamespace(request.Header.Get("Authorization"))
+	}
+	key := operation + " " + namespace + " " + request.URL.String()
+
+	// Store failures only cost a request to the server
+	entry, _ := t.store.Get(key)
+	if entry != nil && t.now().Sub(entry.StoredAt) < ttl {
+		return entry.response(request), nil
+	}
+
+	outgoing := request
+	if etag := entryETag(entry); etag != "" {
+		outgoing = request.Clone(request.Context())
+		outgoing.Header.Set("If-None-Match", etag)
+	}
+	response, err := t.next.RoundTrip(outgoing)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode == http.StatusNotModified && entry != nil {
+		response.Body.Close()
+		entry.StoredAt = t.now()
+		_ = t.store.Set(entry)
+		return entry.response(request), nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return response, nil
+	}
+	body, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	entry = &CacheEntry{Key: key, StatusCode: response.StatusCode, Header: response.Header.Clone(), Body: body, StoredAt: t.now()}
+	_ = t.store.Set(entry)
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	return response, nil
+}
+
+// cacheNamespace Default namespace of a request: a hash of the account and IAM ID claims of an
+// IAM bearer token, or of the whole Authorization header when it holds other credentials
+func cacheNamespace(authorization string) string {
+	identity := authorization
+	if token := strings.TrimPrefix(authorization, "Bearer "); token != authorization {
+		if parts := strings.Split(token, "."); len(parts) == 3 {
+			var claims struct {
+				IamID   string `json:"iam_id"`
+				Account struct {
+					Bss string `json:"bss"`
+				} `json:"account"`
+			}
+			payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+			if err == nil && json.Unmarshal(payload, &claims) == nil && claims.IamID != "" {
+				identity = claims.Account.Bss + "/" + claims.IamID
+			}
+		}
+	}
+	sum := sha256.Sum256([]byte(identity))
+	return hex.EncodeToString(sum[:8])
+}
+
+// cacheOperation Cached operation invoked by a request, empty if none
+func cacheOperation(request *http.Request) string {
+	if request.Method != http.MethodGet {
+		return ""
+	}
+	for operation, path := range cacheOperationPaths {
+		if matchPathTemplate(request.URL.Path, path) {
+			return operation
+		}
+	}
+	return ""
+}
+
+// matchPathTemplate Whether a request path ends with a path template, "{}" matching any segment.
+// Matching on the end of the path lets the service URL hold a base path.
+func matchPathTemplate(path string, template string) bool {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	templateSegments := strings.Split(strings.Trim(template, "/"), "/")
+	if len(segments) < len(templateSegments) {
+		return false
+	}
+	tail := segments[len(segments)-len(templateSegments):]
+	for i, segment := range templateSegments {
+		if segment != "{}" && segment != tail[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// entryETag ETag of a cached response, empty when absent
+func entryETag(entry *CacheEntry) string {
+	if entry == nil {
+		return ""
+	}
+	return entry.Header.Get("ETag")
+}
+
+// response HTTP response replaying the cached one
+func (entry *CacheEntry) response(request *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.StatusCode, http.StatusText(entry.StatusCode)),
+		StatusCode:    entry.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        entry.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       request,
+	}
+}
+
+// MemoryCacheStore : CacheStore keeping entries in memory for the life of the process
+type MemoryCacheStore struct {
+	mu      sync.Mutex
+	entries map[string]CacheEntry
+}
+
+// NewMemoryCacheStore : Instantiate MemoryCacheStore
+func NewMemoryCacheStore() *MemoryCacheStore {
+	return &MemoryCacheStore{entries: map[string]CacheEntry{}}
+}
+
+// Get : Cached entry of a key
+func (store *MemoryCacheStore) Get(key string) (*CacheEntry, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	entry, ok := store.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+// Set : Cache an entry
+func (store *MemoryCacheStore) Set(entry *CacheEntry) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.entries[entry.Key] = *entry
+	return nil
+}
+
+// DeletePrefix : Drop the entries whose key starts with prefix
+func (store *MemoryCacheStore) DeletePrefix(prefix string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for key := range store.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(store.entries, key)
+		}
+	}
+	return nil
+}
+
+// DiskCacheStore : CacheStore keeping one JSON file per entry in a directory, shared across processes
+type DiskCacheStore struct {
+	dir string
+}
+
+// NewDiskCacheStore : Instantiate DiskCacheStore, the directory is created if needed.
+// An empty dir selects DefaultCacheDir.
+func NewDiskCacheStore(dir string) (*DiskCacheStore, error) {
+	if dir == "" {
+		var err error
+		if dir, err = DefaultCacheDir(); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &DiskCacheStore{dir: dir}, nil
+}
+
+// DefaultCacheDir : Per-user directory of the on-disk cache
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "powervs-go-sdk"), nil
+}
+
+// Get : Cached entry of a key
+func (store *DiskCacheStore) Get(key string) (*CacheEntry, error) {
+	data, err := os.ReadFile(store.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry := &CacheEntry{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, err
+	}
+	// Guard against hash collisions
+	if entry.Key != key {
+		return nil, nil
+	}
+	return entry, nil
+}
+
+// Set : Cache an entry, replacing the file atomically
+func (store *DiskCacheStore) Set(entry *CacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(store.dir, ".entry-*")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), store.path(entry.Key))
+}
+
+// DeletePrefix : Drop the entries whose key starts with prefix
+func (store *DiskCacheStore) DeletePrefix(prefix string) error {
+	paths, err := filepath.Glob(filepath.Join(store.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if prefix != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			var entry CacheEntry
+			if json.Unmarshal(data, &entry) == nil && !strings.HasPrefix(entry.Key, prefix) {
+				continue
+			}
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteExpired : Drop the entries stored more than maxAge ago, unreadable entries and
+// temporary files left by interrupted writes
+func (store *DiskCacheStore) DeleteExpired(maxAge time.Duration) error {
+	paths, err := filepath.Glob(filepath.Join(store.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	temporary, err := filepath.Glob(filepath.Join(store.dir, ".entry-*"))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, path := range append(paths, temporary...) {
+		info, err := os.Stat(path)
+		if err != nil || now.Sub(info.ModTime()) < maxAge {
+			continue
+		}
+		if !strings.HasPrefix(filepath.Base(path), ".entry-") {
+			var entry CacheEntry
+			data, err := os.ReadFile(path)
+			if err == nil && json.Unmarshal(data, &entry) == nil && now.Sub(entry.StoredAt) < maxAge {
+				continue
+			}
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// path File holding the entry of a key
+func (store *DiskCacheStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(store.dir, hex.EncodeToString(sum[:])+".json")
+}
@@ -0,0 +1,208 @@
+package powervsv1
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+func TestCacheOperation(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{"GET", "/pcloud/v1/cloud-instances/ws/stock-images", CacheOperationStockImages},
+		{"GET", "/base/pcloud/v1/images", CacheOperationImages},
+		{"GET", "/pcloud/v1/cloud-instances/ws/images", ""},
+		{"GET", "/pcloud/v1/cloud-instances/ws/storage-capacity/storage-types", CacheOperationStorageTypes},
+		{"GET", "/v2/catalog", CacheOperationCatalog},
+		{"GET", "/pcloud/v1/cloud-instances/ws/sap/profile-1", ""},
+		{"POST", "/pcloud/v1/images", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, tt.path, nil)
+			if got := cacheOperation(request); got != tt.want {
+				t.Errorf("cacheOperation() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnableCache(t *testing.T) {
+	stores := map[string]func(t *testing.T) CacheStore{
+		"memory": func(t *testing.T) CacheStore { return NewMemoryCacheStore() },
+		"disk": func(t *testing.T) CacheStore {
+			store, err := NewDiskCacheStore(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return store
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			var requests, revalidated int
+			version := "v1"
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				etag := `"` + version + `"`
+				if r.Header.Get("If-None-Match") == etag {
+					revalidated++
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("ETag", etag)
+				fmt.Fprintf(w, `{"images": [{"imageID": "%s", "name": "aix", "href": "h", "state": "active"}]}`, version)
+			}))
+			defer server.Close()
+			powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			now := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+			powervs.EnableCache(&CacheOptions{Store: newStore(t), TTLs: map[string]time.Duration{CacheOperationImages: time.Minute}})
+			powervs.cacheTransport().now = func() time.Time { return now }
+
+			get := func() string {
+				images, _, err := powervs.PcloudImagesGetallWithContext(context.Background(), powervs.NewPcloudImagesGetallOptions())
+				if err != nil {
+					t.Fatal(err)
+				}
+				return *images.Images[0].ImageID
+			}
+			get()
+			if got := get(); got != "v1" || requests != 1 {
+				t.Errorf("fresh entry: image %s after %d requests, want v1 after 1", got, requests)
+			}
+
+			now = now.Add(2 * time.Minute)
+			if got := get(); got != "v1" || requests != 2 || revalidated != 1 {
+				t.Errorf("stale entry: image %s after %d requests (%d revalidated)", got, requests, revalidated)
+			}
+
+			version = "v2"
+			if err := powervs.InvalidateCache(CacheOperationImages); err != nil {
+				t.Fatal(err)
+			}
+			if got := get(); got != "v2" || requests != 3 {
+				t.Errorf("invalidated entry: image %s after %d requests", got, requests)
+			}
+
+			powervs.DisableCache()
+			get()
+			if requests != 4 {
+				t.Errorf("disabled cache: %d requests, want 4", requests)
+			}
+			if err := powervs.InvalidateCache(); err == nil {
+				t.Error("InvalidateCache() expected error when disabled")
+			}
+		})
+	}
+}
+
+func TestCacheNamespaces(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"images": [{"imageID": "%s", "name": "aix", "href": "h", "state": "active"}]}`, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	}))
+	defer server.Close()
+	store := NewMemoryCacheStore()
+	client := func(token string, namespace string) *PowervsV1 {
+		authenticator, err := core.NewBearerTokenAuthenticator(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: authenticator})
+		if err != nil {
+			t.Fatal(err)
+		}
+		powervs.EnableCache(&CacheOptions{Store: store, Namespace: namespace})
+		return powervs
+	}
+	get := func(powervs *PowervsV1) string {
+		images, _, err := powervs.PcloudImagesGetallWithContext(context.Background(), powervs.NewPcloudImagesGetallOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *images.Images[0].ImageID
+	}
+
+	jwt := func(account, iamID, issuedAt string) string {
+		payload := base64.RawURLEncoding.EncodeToString([]byte(`{"iam_id": "` + iamID + `", "account": {"bss": "` + account + `"}, "iat": ` + issuedAt + `}`))
+		return "eyJhbGciOiJSUzI1NiJ9." + payload + ".signature"
+	}
+	tests := []struct {
+		token, namespace string
+		want             string
+	}{
+		{jwt("account-1", "IBMid-1", "1"), "", jwt("account-1", "IBMid-1", "1")},
+		// A refreshed IAM token of the same user and account keeps the entries
+		{jwt("account-1", "IBMid-1", "2"), "", jwt("account-1", "IBMid-1", "1")},
+		{jwt("account-2", "IBMid-1", "3"), "", jwt("account-2", "IBMid-1", "3")},
+		{"alice", "", "alice"},
+		{"bob", "", "bob"},
+		{"alice", "", "alice"},
+		{"carol", "account-1", "carol"},
+		// A refreshed token of the same account keeps the entries of its namespace
+		{"carol-refreshed", "account-1", "carol"},
+		{"dave", "account-2", "dave"},
+	}
+	for _, tt := range tests {
+		if got := get(client(tt.token, tt.namespace)); got != tt.want {
+			t.Errorf("client %s in namespace %q got image %s, want %s", tt.token, tt.namespace, got, tt.want)
+		}
+	}
+}
+
+func TestDiskCacheStoreDeleteExpired(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewDiskCacheStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	for key, storedAt := range map[string]time.Time{"fresh": time.Now(), "expired": old} {
+		if err := store.Set(&CacheEntry{Key: key, StatusCode: http.StatusOK, StoredAt: storedAt}); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(store.path(key), storedAt, storedAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	leftover := filepath.Join(dir, ".entry-123")
+	if err := os.WriteFile(leftover, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(leftover, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: "http://localhost", Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	powervs.EnableCache(&CacheOptions{Store: store})
+	if entry, _ := store.Get("fresh"); entry == nil {
+		t.Error("EnableCache() deleted a fresh entry")
+	}
+	if entry, _ := store.Get("expired"); entry != nil {
+		t.Error("EnableCache() kept an expired entry")
+	}
+	if _, err := os.Stat(leftover); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("EnableCache() kept a temporary file, stat error = %v", err)
+	}
+}
@@ -0,0 +1,356 @@
+package powervsv1
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// DeployCapacityCheck : One constraint evaluated by CheckDeployCapacity
+type DeployCapacityCheck struct {
+	// Constraint name such as "quota.memory", "systemPool.s922" or "storage.tier1".
+	Name string `json:"name"`
+
+	Required float64 `json:"required"`
+
+	Available float64 `json:"available"`
+
+	Passed bool `json:"passed"`
+
+	Message string `json:"message,omitempty"`
+}
+
+// DeployCapacityReport : Feasibility of an instance deployment
+type DeployCapacityReport struct {
+	// Whether every check passed.
+	Feasible bool `json:"feasible"`
+
+	Checks []DeployCapacityCheck `json:"checks"`
+
+	// System types with room for every requested instance, in the system pools and the pod.
+	FittingSysTypes []string `json:"fittingSysTypes"`
+
+	// Storage type of the boot volumes.
+	StorageType string `json:"storageType"`
+
+	// Storage pools of StorageType able to hold the boot volumes.
+	FittingStoragePools []string `json:"fittingStoragePools"`
+}
+
+// Failed : Checks that did not pass
+func (report *DeployCapacityReport) Failed() (failed []DeployCapacityCheck) {
+	for _, check := range report.Checks {
+		if !check.Passed {
+			failed = append(failed, check)
+		}
+	}
+	return
+}
+
+// String : Human readable rendering of the report
+func (report *DeployCapacityReport) String() string {
+	lines := []string{}
+	if report.Feasible {
+		lines = append(lines, "Deployment fits.")
+	} else {
+		lines = append(lines, "Deployment does not fit.")
+	}
+	for _, check := range report.Checks {
+		status := "ok  "
+		if !check.Passed {
+			status = "FAIL"
+		}
+		line := fmt.Sprintf("  %s %s: requires %s, available %s", status, check.Name, formatFloat64(&check.Required), formatFloat64(&check.Available))
+		if check.Message != "" {
+			line += " (" + check.Message + ")"
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "Fitting system types: "+strings.Join(report.FittingSysTypes, ", "))
+	lines = append(lines, fmt.Sprintf("Fitting %s storage pools: %s", report.StorageType, strings.Join(report.FittingStoragePools, ", ")))
+	return strings.Join(lines, "\n")
+}
+
+// CheckDeployCapacity : Check that the workspace quota, the system pools, the pod and the
+// storage pools have room for the instances an instance creation request would deploy
+func (powervs *PowervsV1) CheckDeployCapacity(options *PcloudPvminstancesPostOptions) (*DeployCapacityReport, error) {
+	return powervs.CheckDeployCapacityWithContext(context.Background(), options)
+}
+
+// CheckDeployCapacityWithContext : Check deploy capacity with a context
+func (powervs *PowervsV1) CheckDeployCapacityWithContext(ctx context.Context, options *PcloudPvminstancesPostOptions) (*DeployCapacityReport, error) {
+	if err := core.ValidateNotNil(options, "options cannot be nil"); err != nil {
+		return nil, err
+	}
+	if err := core.ValidateStruct(options, "pcloudPvminstancesPostOptions"); err != nil {
+		return nil, err
+	}
+	// The checks divide by the requested resources
+	var errs ValidationErrors
+	if !(*options.Processors > 0) {
+		errs.add("processors", "processors must be positive, got %s", formatFloat64(options.Processors))
+	}
+	if !(*options.Memory > 0) {
+		errs.add("memory", "memory must be positive, got %s", formatFloat64(options.Memory))
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	cloudInstanceID := *options.CloudInstanceID
+
+	cloudInstance, _, err := powervs.PcloudCloudinstancesGetWithContext(ctx, powervs.NewPcloudCloudinstancesGetOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace %s: %w", cloudInstanceID, err)
+	}
+	systemPools, _, err := powervs.PcloudSystempoolsGetWithContext(ctx, powervs.NewPcloudSystempoolsGetOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get system pools: %w", err)
+	}
+	podCapacity, _, err := powervs.PcloudPodcapacityGetWithContext(ctx, powervs.NewPcloudPodcapacityGetOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pod capacity: %w", err)
+	}
+	storageCapacity, _, err := powervs.PcloudStoragecapacityTypesGetallWithContext(ctx, powervs.NewPcloudStoragecapacityTypesGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage capacity: %w", err)
+	}
+	image, err := powervs.getDeployImage(ctx, cloudInstanceID, *options.ImageID)
+	if err != nil {
+		return nil, err
+	}
+
+	request := newDeployRequest(options, image)
+	report := &DeployCapacityReport{StorageType: request.storageType}
+	report.Checks = append(report.Checks, request.checkQuota(cloudInstance.Limits, cloudInstance.Usage)...)
+	checks, fitting := request.checkSystemPools(systemPools, podCapacity)
+	report.Checks = append(report.Checks, checks...)
+	report.FittingSysTypes = fitting
+	check, pools := request.checkStorage(storageCapacity)
+	report.Checks = append(report.Checks, check)
+	report.FittingStoragePools = pools
+
+	report.Feasible = len(report.Failed()) == 0
+	return report, nil
+}
+
+// getDeployImage Image of the workspace, or stock image, an instance is deployed from
+func (powervs *PowervsV1) getDeployImage(ctx context.Context, cloudInstanceID string, imageID string) (*Image, error) {
+	image, response, err := powervs.PcloudCloudinstancesImagesGetWithContext(ctx, powervs.NewPcloudCloudinstancesImagesGetOptions(cloudInstanceID, imageID))
+	if err == nil {
+		return image, nil
+	}
+	if !isNotFound(response) {
+		return nil, fmt.Errorf("failed to get image %s: %w", imageID, err)
+	}
+	image, _, err = powervs.PcloudCloudinstancesStockimagesGetWithContext(ctx, powervs.NewPcloudCloudinstancesStockimagesGetOptions(cloudInstanceID, imageID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image %s: %w", imageID, err)
+	}
+	return image, nil
+}
+
+// deployRequest Resources requested by an instance creation
+type deployRequest struct {
+	replicas    float64
+	processors  float64
+	memory      float64
+	dedicated   bool
+	sysType     string
+	storageType string
+	storagePool string
+	// Size of one boot volume in GB.
+	bootSize float64
+}
+
+func newDeployRequest(options *PcloudPvminstancesPostOptions, image *Image) *deployRequest {
+	request := &deployRequest{
+		replicas:    1,
+		processors:  *options.Processors,
+		memory:      *options.Memory,
+		dedicated:   *options.ProcType == PcloudPvminstancesPostOptionsProcTypeDedicatedConst,
+		sysType:     core.StringNilMapper(options.SysType),
+		storageType: core.StringNilMapper(options.StorageType),
+		storagePool: core.StringNilMapper(options.StoragePool),
+		bootSize:    floatValue(image.Size),
+	}
+	if options.Replicants != nil && *options.Replicants > 1 {
+		request.replicas = *options.Replicants
+	}
+	if request.storageType == "" {
+		request.storageType = core.StringNilMapper(image.StorageType)
+	}
+	return request
+}
+
+// checkQuota Compare the workspace usage after deployment with its limits, a missing or zero limit is not enforced.
+// Boot volumes of ssd and tier1 storage also count against the SSD quota, those of standard and tier3 against the
+// standard quota.
+func (request *deployRequest) checkQuota(limits *CloudInstanceUsageLimits, usage *CloudInstanceUsageLimits) (checks []DeployCapacityCheck) {
+	if limits == nil {
+		return nil
+	}
+	if usage == nil {
+		usage = &CloudInstanceUsageLimits{}
+	}
+	// Shared and capped instances use whole virtual processors for their processing units
+	virtualProcessors := request.processors
+	if !request.dedicated {
+		virtualProcessors = math.Ceil(request.processors)
+	}
+	quota := func(name string, limit *float64, used *float64, required float64) {
+		if limit == nil || *limit <= 0 {
+			return
+		}
+		check := DeployCapacityCheck{Name: name, Required: required, Available: *limit - floatValue(used)}
+		check.Passed = check.Required <= check.Available
+		if !check.Passed {
+			check.Message = "workspace quota exceeded"
+		}
+		checks = append(checks, check)
+	}
+	quota("quota.instances", limits.Instances, usage.Instances, request.replicas)
+	quota("quota.processors", limits.Processors, usage.Processors, virtualProcessors*request.replicas)
+	quota("quota.procUnits", limits.ProcUnits, usage.ProcUnits, request.processors*request.replicas)
+	quota("quota.memory", limits.Memory, usage.Memory, request.memory*request.replicas)
+	// Storage quotas are in TB, boot volumes in GB
+	storage := request.bootSize * request.replicas
+	quota("quota.storage", terabytesToGigabytes(limits.Storage), terabytesToGigabytes(usage.Storage), storage)
+	switch request.storageType {
+	case "ssd", "tier1":
+		quota("quota.storageSSD", terabytesToGigabytes(limits.StorageSsd), terabytesToGigabytes(usage.StorageSsd), storage)
+	case "standard", "tier3":
+		quota("quota.storageStandard", terabytesToGigabytes(limits.StorageStandard), terabytesToGigabytes(usage.StorageStandard), storage)
+	}
+	quota("quota.instanceMemory", limits.InstanceMemory, nil, request.memory)
+	quota("quota.instanceProcUnits", limits.InstanceProcUnits, nil, request.processors)
+	return
+}
+
+// terabytesToGigabytes Convert a storage quota in TB to GB
+func terabytesToGigabytes(tb *float64) *float64 {
+	if tb == nil {
+		return nil
+	}
+	return core.Float64Ptr(*tb * 1024)
+}
+
+// checkSystemPools Count the instances each system type can host, on its systems and in the pod
+func (request *deployRequest) checkSystemPools(pools map[string]SystemPool, pod *PodCapacity) (checks []DeployCapacityCheck, fitting []string) {
+	sysTypes := make([]string, 0, len(pools))
+	for sysType := range pools {
+		sysTypes = append(sysTypes, sysType)
+	}
+	sort.Strings(sysTypes)
+
+	for _, sysType := range sysTypes {
+		hosted := request.instancesPerPool(pools[sysType])
+		if pod != nil {
+			if capacity, ok := pod.SystemPools[sysType]; ok {
+				byCores := math.Floor(floatValue(capacity.Cores) / request.processors)
+				byMemory := math.Floor(float64(int64Value(capacity.Memory)) / request.memory)
+				hosted = math.Min(hosted, math.Min(byCores, byMemory))
+			}
+		}
+		if hosted >= request.replicas {
+			fitting = append(fitting, sysType)
+		}
+		if request.sysType == sysType {
+			check := DeployCapacityCheck{Name: "systemPool." + sysType, Required: request.replicas, Available: hosted, Passed: hosted >= request.replicas}
+			if !check.Passed {
+				check.Message = fmt.Sprintf("room for %s of %s instances with %s cores and %sGB of memory", formatFloat64(&hosted), formatFloat64(&request.replicas), formatFloat64(&request.processors), formatFloat64(&request.memory))
+			}
+			checks = append(checks, check)
+		}
+	}
+
+	if request.sysType == "" {
+		check := DeployCapacityCheck{Name: "systemPool", Required: request.replicas, Passed: len(fitting) > 0}
+		if check.Passed {
+			check.Available = request.replicas
+		} else {
+			check.Message = "no system type can host the instances"
+		}
+		checks = append(checks, check)
+	} else if _, ok := pools[request.sysType]; !ok {
+		checks = append(checks, DeployCapacityCheck{Name: "systemPool." + request.sysType, Required: request.replicas, Message: "system type not available in the workspace"})
+	}
+	return
+}
+
+// instancesPerPool Number of requested instances the systems of a pool can host,
+// each instance having to fit on a single system
+func (request *deployRequest) instancesPerPool(pool SystemPool) float64 {
+	systems := pool.Systems
+	if len(systems) == 0 {
+		for _, system := range []*System{pool.MaxAvailable, pool.MaxCoresAvailable, pool.MaxMemoryAvailable} {
+			if system != nil {
+				systems = append(systems, *system)
+			}
+		}
+		// The largest systems only tell whether one instance fits
+		hosted := 0.0
+		for _, system := range systems {
+			hosted = math.Max(hosted, math.Min(1, request.instancesPerSystem(system)))
+		}
+		return hosted
+	}
+	hosted := 0.0
+	for _, system := range systems {
+		hosted += request.instancesPerSystem(system)
+	}
+	return hosted
+}
+
+// instancesPerSystem Number of requested instances a system can host
+func (request *deployRequest) instancesPerSystem(system System) float64 {
+	cores := floatValue(system.Cores)
+	if system.AvailableCores != nil {
+		cores = *system.AvailableCores
+	}
+	memory := float64(int64Value(system.Memory))
+	if system.AvailableMemory != nil {
+		memory = float64(*system.AvailableMemory)
+	}
+	return math.Min(math.Floor(cores/request.processors), math.Floor(memory/request.memory))
+}
+
+// checkStorage Find the storage pools of the requested type able to hold the boot volumes
+func (request *deployRequest) checkStorage(capacity *StorageTypesCapacity) (DeployCapacityCheck, []string) {
+	check := DeployCapacityCheck{Name: "storage." + request.storageType, Required: request.bootSize * request.replicas}
+	var fitting []string
+	if capacity != nil {
+		for _, storageType := range capacity.StorageTypesCapacity {
+			if core.StringNilMapper(storageType.StorageType) != request.storageType {
+				continue
+			}
+			for _, pool := range storageType.StoragePoolsCapacity {
+				name := core.StringNilMapper(pool.PoolName)
+				if request.storagePool != "" && name != request.storagePool {
+					continue
+				}
+				available := float64(int64Value(pool.MaxAllocationSize))
+				if pool.AvailableCapacity != nil {
+					available = float64(*pool.AvailableCapacity)
+				}
+				check.Available = math.Max(check.Available, available)
+				if float64(int64Value(pool.MaxAllocationSize)) >= request.bootSize && available >= check.Required {
+					fitting = append(fitting, name)
+				}
+			}
+		}
+	}
+	sort.Strings(fitting)
+	check.Passed = len(fitting) > 0
+	if !check.Passed {
+		if request.storagePool != "" {
+			check.Message = fmt.Sprintf("storage pool %s cannot hold %d boot volumes of %sGB", request.storagePool, int(request.replicas), formatFloat64(&request.bootSize))
+		} else {
+			check.Message = fmt.Sprintf("no %s storage pool can hold %d boot volumes of %sGB", request.storageType, int(request.replicas), formatFloat64(&request.bootSize))
+		}
+	}
+	return check, fitting
+}
@@ -0,0 +1,123 @@
+package powervsv1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+func TestCheckDeployCapacity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch strings.TrimPrefix(r.URL.Path, "/pcloud/v1") {
+		case "/cloud-instances/ws":
+			_, _ = w.Write([]byte(`{"cloudInstanceID": "ws", "tenantID": "tenant", "enabled": true, "initialized": true, "name": "ws", "openstackID": "o", "region": "dal", "capabilities": [], "pvmInstances": [],
+				"limits": {"instances": 10, "memory": 256, "procUnits": 16, "processors": 16, "storage": 2, "storageSSD": 1.5, "storageStandard": 0.1},
+				"usage": {"instances": 8, "memory": 200, "procUnits": 10, "processors": 12, "storage": 1.5, "storageSSD": 1.2, "storageStandard": 0}}`))
+		case "/cloud-instances/ws/images/img-1":
+			_, _ = w.Write([]byte(`{"imageID": "img-1", "name": "aix", "size": 100, "storageType": "tier1"}`))
+		case "/cloud-instances/ws/system-pools":
+			_, _ = w.Write([]byte(`{
+				"s922": {"type": "s922", "systems": [{"id": 1, "cores": 20, "availableCores": 3, "memory": 512, "availableMemory": 64}, {"id": 2, "cores": 20, "availableCores": 1.5, "memory": 512, "availableMemory": 16}]},
+				"e980": {"type": "e980", "maxAvailable": {"cores": 40, "availableCores": 0.5, "memory": 1024, "availableMemory": 512}}}`))
+		case "/cloud-instances/ws/pod-capacity":
+			_, _ = w.Write([]byte(`{"systemPools": {"s922": {"cores": 100, "memory": 1000}}}`))
+		case "/cloud-instances/ws/storage-capacity/storage-types":
+			_, _ = w.Write([]byte(`{"storageTypesCapacity": [
+				{"storageType": "tier1", "storagePoolsCapacity": [{"poolName": "p1", "availableCapacity": 150, "maxAllocationSize": 150}, {"poolName": "p2", "availableCapacity": 900, "maxAllocationSize": 500}]},
+				{"storageType": "tier3", "storagePoolsCapacity": [{"poolName": "p3", "availableCapacity": 5000, "maxAllocationSize": 5000}]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		processors   float64
+		memory       float64
+		replicas     float64
+		sysType      string
+		storagePool  string
+		storageType  string
+		wantFeasible bool
+		wantFailed   []string
+		wantSysTypes []string
+		wantPools    []string
+	}{
+		{"fits", 1, 16, 2, "s922", "", "", true, nil, []string{"s922"}, []string{"p2"}},
+		{"any system type", 0.5, 8, 1, "", "", "", true, nil, []string{"e980", "s922"}, []string{"p1", "p2"}},
+		{"quota exceeded", 1, 32, 3, "s922", "", "", false, []string{"quota.instances", "quota.memory", "systemPool.s922"}, nil, []string{"p2"}},
+		{"system pool full", 2, 8, 1, "e980", "", "", false, []string{"systemPool.e980"}, []string{"s922"}, []string{"p1", "p2"}},
+		{"unknown system type", 1, 8, 1, "s1022", "", "", false, []string{"systemPool.s1022"}, []string{"s922"}, []string{"p1", "p2"}},
+		{"storage pool too small", 1, 8, 2, "", "p1", "", false, []string{"storage.tier1"}, []string{"s922"}, nil},
+		{"storage quota exceeded", 1, 8, 2, "", "", "tier3", false, []string{"quota.storageStandard"}, []string{"s922"}, []string{"p3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := powervs.NewPcloudPvminstancesPostOptions("ws", "img-1", tt.memory, PcloudPvminstancesPostOptionsProcTypeSharedConst, tt.processors, "vm")
+			options.Replicants = core.Float64Ptr(tt.replicas)
+			if tt.sysType != "" {
+				options.SysType = core.StringPtr(tt.sysType)
+			}
+			if tt.storagePool != "" {
+				options.StoragePool = core.StringPtr(tt.storagePool)
+			}
+			if tt.storageType != "" {
+				options.StorageType = core.StringPtr(tt.storageType)
+			}
+			report, err := powervs.CheckDeployCapacity(options)
+			if err != nil {
+				t.Fatalf("CheckDeployCapacity() error = %v", err)
+			}
+			var failed []string
+			for _, check := range report.Failed() {
+				failed = append(failed, check.Name)
+			}
+			if report.Feasible != tt.wantFeasible || !reflect.DeepEqual(failed, tt.wantFailed) {
+				t.Errorf("CheckDeployCapacity() feasible = %v, failed = %v\n%s", report.Feasible, failed, report)
+			}
+			if !reflect.DeepEqual(report.FittingSysTypes, tt.wantSysTypes) || !reflect.DeepEqual(report.FittingStoragePools, tt.wantPools) {
+				t.Errorf("CheckDeployCapacity() system types = %v, storage pools = %v", report.FittingSysTypes, report.FittingStoragePools)
+			}
+		})
+	}
+
+	if _, err := powervs.CheckDeployCapacity(&PcloudPvminstancesPostOptions{}); err == nil {
+		t.Error("CheckDeployCapacity() expected error for invalid options")
+	}
+	options := powervs.NewPcloudPvminstancesPostOptions("ws", "img-1", 0, "shared", 0, "app")
+	if _, err := powervs.CheckDeployCapacity(options); err == nil || err.Error() != "invalid options: processors: processors must be positive, got 0; memory: memory must be positive, got 0" {
+		t.Errorf("CheckDeployCapacity() error = %v", err)
+	}
+}
+
+func TestDeployCapacityReportString(t *testing.T) {
+	report := &DeployCapacityReport{
+		Checks: []DeployCapacityCheck{
+			{Name: "quota.memory", Required: 64, Available: 56, Message: "workspace quota exceeded"},
+			{Name: "storage.tier1", Required: 100, Available: 900, Passed: true},
+		},
+		FittingSysTypes:     []string{"s922"},
+		StorageType:         "tier1",
+		FittingStoragePools: []string{"p2"},
+	}
+	want := strings.Join([]string{
+		"Deployment does not fit.",
+		"  FAIL quota.memory: requires 64, available 56 (workspace quota exceeded)",
+		"  ok   storage.tier1: requires 100, available 900",
+		"Fitting system types: s922",
+		"Fitting tier1 storage pools: p2",
+	}, "\n")
+	if got := report.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}
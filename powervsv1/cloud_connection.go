@@ -0,0 +1,335 @@
+package powervsv1
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// Link statuses of a cloud connection
+const (
+	CloudConnectionLinkStatusUp          = "up"
+	CloudConnectionLinkStatusDown        = "down"
+	CloudConnectionLinkStatusConfiguring = "configuring"
+	CloudConnectionLinkStatusIdle        = "idle"
+)
+
+// DefaultCloudConnectionTimeout Time given to the link of a cloud connection to come up before it is reported as failed
+const DefaultCloudConnectionTimeout = 30 * time.Minute
+
+// CloudConnectionUpdateOptions : The UpdateCloudConnection options.
+type CloudConnectionUpdateOptions struct {
+	CloudInstanceID string `validate:"required"`
+
+	CloudConnectionID string `validate:"required"`
+
+	Name *string
+
+	// Speed in Mbps.
+	Speed *int64
+
+	Metered *bool
+
+	GlobalRouting *bool
+
+	// VPCs to attach, those already attached are skipped.
+	AttachVPCs []CloudConnectionVPC `validate:"omitempty,dive"`
+
+	// IDs of the VPCs to detach, those not attached are skipped.
+	DetachVPCs []string
+
+	// Apply the update even when it reports warnings.
+	Force bool
+}
+
+// CloudConnectionChange : Update of a cloud connection and the warnings it raises
+type CloudConnectionChange struct {
+	// Connection before the update, or after it once applied.
+	Connection *CloudConnection
+
+	// Update to send, nil when the connection already matches the options.
+	Update *PcloudCloudconnectionsPutOptions
+
+	// Changes that may disrupt traffic or billing, like speed downgrades or metering changes.
+	Warnings []string
+
+	Applied bool
+}
+
+// CreateCloudConnection : Create a cloud connection and wait up to DefaultCloudConnectionTimeout
+// for its link to come up
+func (powervs *PowervsV1) CreateCloudConnection(ctx context.Context, options *PcloudCloudconnectionsPostOptions) (*CloudConnection, error) {
+	if err := core.ValidateNotNil(options, "options cannot be nil"); err != nil {
+		return nil, err
+	}
+	connection, _, err := powervs.PcloudCloudconnectionsPostWithContext(ctx, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cloud connection %s: %w", core.StringNilMapper(options.Name), err)
+	}
+	connectionID := core.StringNilMapper(connection.CloudConnectionID)
+	if err := powervs.waitForCloudConnectionLink(ctx, *options.CloudInstanceID, connectionID, false, func(c *CloudConnection) (bool, bool) {
+		connection = c
+		return true, true
+	}); err != nil {
+		return connection, fmt.Errorf("cloud connection %s did not come up: %w", connectionID, err)
+	}
+	return connection, nil
+}
+
+// DeleteCloudConnection : Delete a cloud connection and wait until it is gone
+func (powervs *PowervsV1) DeleteCloudConnection(ctx context.Context, cloudInstanceID string, cloudConnectionID string) error {
+	_, response, err := powervs.PcloudCloudconnectionsDeleteWithContext(ctx, powervs.NewPcloudCloudconnectionsDeleteOptions(cloudInstanceID, cloudConnectionID))
+	if err = ignoreNotFound(response, err); err != nil {
+		return fmt.Errorf("failed to delete cloud connection %s: %w", cloudConnectionID, err)
+	}
+	return waitFor(ctx, func() (bool, error) {
+		_, response, err := powervs.PcloudCloudconnectionsGetWithContext(ctx, powervs.NewPcloudCloudconnectionsGetOptions(cloudInstanceID, cloudConnectionID))
+		if isNotFound(response) {
+			return true, nil
+		}
+		return false, err
+	})
+}
+
+// AttachCloudConnectionNetwork : Attach a network to a cloud connection and wait until the
+// connection lists it. Networks already attached are left as is.
+func (powervs *PowervsV1) AttachCloudConnectionNetwork(ctx context.Context, cloudInstanceID string, cloudConnectionID string, networkID string) error {
+	connection, _, err := powervs.PcloudCloudconnectionsGetWithContext(ctx, powervs.NewPcloudCloudconnectionsGetOptions(cloudInstanceID, cloudConnectionID))
+	if err != nil {
+		return fmt.Errorf("failed to get cloud connection %s: %w", cloudConnectionID, err)
+	}
+	if cloudConnectionHasNetwork(connection, networkID) {
+		return nil
+	}
+	if _, _, err := powervs.PcloudCloudconnectionsNetworksPutWithContext(ctx, powervs.NewPcloudCloudconnectionsNetworksPutOptions(cloudInstanceID, cloudConnectionID, networkID)); err != nil {
+		return fmt.Errorf("failed to attach network %s to cloud connection %s: %w", networkID, cloudConnectionID, err)
+	}
+	return powervs.waitForCloudConnection(ctx, cloudInstanceID, cloudConnectionID, func(c *CloudConnection) bool {
+		return cloudConnectionHasNetwork(c, networkID)
+	})
+}
+
+// DetachCloudConnectionNetwork : Detach a network from a cloud connection and wait until the
+// connection no longer lists it. Networks not attached are left as is.
+func (powervs *PowervsV1) DetachCloudConnectionNetwork(ctx context.Context, cloudInstanceID string, cloudConnectionID string, networkID string) error {
+	_, response, err := powervs.PcloudCloudconnectionsNetworksDeleteWithContext(ctx, powervs.NewPcloudCloudconnectionsNetworksDeleteOptions(cloudInstanceID, cloudConnectionID, networkID))
+	if err = ignoreNotFound(response, err); err != nil {
+		return fmt.Errorf("failed to detach network %s from cloud connection %s: %w", networkID, cloudConnectionID, err)
+	}
+	return powervs.waitForCloudConnection(ctx, cloudInstanceID, cloudConnectionID, func(c *CloudConnection) bool {
+		return !cloudConnectionHasNetwork(c, networkID)
+	})
+}
+
+// PlanCloudConnectionUpdate : Compare a cloud connection with the update options and report the
+// update to send, with warnings for speed downgrades, metering and global routing changes and
+// VPC detachments
+func (powervs *PowervsV1) PlanCloudConnectionUpdate(ctx context.Context, options *CloudConnectionUpdateOptions) (*CloudConnectionChange, error) {
+	if err := core.ValidateNotNil(options, "options cannot be nil"); err != nil {
+		return nil, err
+	}
+	if err := core.ValidateStruct(options, "cloudConnectionUpdateOptions"); err != nil {
+		return nil, err
+	}
+	connection, _, err := powervs.PcloudCloudconnectionsGetWithContext(ctx, powervs.NewPcloudCloudconnectionsGetOptions(options.CloudInstanceID, options.CloudConnectionID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cloud connection %s: %w", options.CloudConnectionID, err)
+	}
+	change := &CloudConnectionChange{Connection: connection}
+	update := powervs.NewPcloudCloudconnectionsPutOptions(options.CloudInstanceID, options.CloudConnectionID)
+	changed := false
+
+	if options.Name != nil && *options.Name != core.StringNilMapper(connection.Name) {
+		update.SetName(*options.Name)
+		changed = true
+	}
+	if current := int64Value(connection.Speed); options.Speed != nil && *options.Speed != current {
+		if *options.Speed < current {
+			change.Warnings = append(change.Warnings, fmt.Sprintf("speed is lowered from %d to %d Mbps", current, *options.Speed))
+		}
+		update.SetSpeed(*options.Speed)
+		changed = true
+	}
+	if current := boolValue(connection.Metered); options.Metered != nil && *options.Metered != current {
+		if *options.Metered {
+			change.Warnings = append(change.Warnings, "metering is enabled, outbound traffic will be billed")
+		} else {
+			change.Warnings = append(change.Warnings, "metering is disabled, the connection moves to unlimited pricing")
+		}
+		update.SetMetered(*options.Metered)
+		changed = true
+	}
+	if current := boolValue(connection.GlobalRouting); options.GlobalRouting != nil && *options.GlobalRouting != current {
+		if !*options.GlobalRouting {
+			change.Warnings = append(change.Warnings, "global routing is disabled, other regions lose access")
+		}
+		update.SetGlobalRouting(*options.GlobalRouting)
+		changed = true
+	}
+
+	// The service replaces the VPC list, so the update sends the whole resulting list
+	var vpcs []CloudConnectionVPC
+	if connection.VPC != nil {
+		vpcs = append(vpcs, connection.VPC.Vpcs...)
+	}
+	attached := map[string]bool{}
+	for _, vpc := range vpcs {
+		attached[core.StringNilMapper(vpc.VPCID)] = true
+	}
+	vpcsChanged := false
+	detach := map[string]bool{}
+	for _, vpcID := range options.DetachVPCs {
+		if attached[vpcID] && !detach[vpcID] {
+			detach[vpcID] = true
+			vpcsChanged = true
+		}
+	}
+	var detached []string
+	kept := []CloudConnectionVPC{}
+	for _, vpc := range vpcs {
+		if detach[core.StringNilMapper(vpc.VPCID)] {
+			detached = append(detached, core.StringNilMapper(vpc.VPCID))
+		} else {
+			kept = append(kept, vpc)
+		}
+	}
+	for _, vpc := range options.AttachVPCs {
+		if vpcID := core.StringNilMapper(vpc.VPCID); !attached[vpcID] {
+			attached[vpcID] = true
+			kept = append(kept, vpc)
+			vpcsChanged = true
+		}
+	}
+	if len(detached) > 0 {
+		sort.Strings(detached)
+		change.Warnings = append(change.Warnings, fmt.Sprintf("VPCs %s are detached", strings.Join(detached, ", ")))
+	}
+	if vpcsChanged {
+		update.SetVPC(&CloudConnectionEndpointVPC{Enabled: core.BoolPtr(len(kept) > 0), Vpcs: kept})
+		changed = true
+	}
+
+	if changed {
+		change.Update = update
+	}
+	return change, nil
+}
+
+// UpdateCloudConnection : Plan a cloud connection update with PlanCloudConnectionUpdate and apply
+// it. It waits until the connection shows the update and, when the speed, routing or VPCs
+// change, up to DefaultCloudConnectionTimeout for the link to come back up. The update fails
+// when a link that was up goes down. An update raising warnings is not applied unless
+// options.Force is set, the change is then returned with an error listing the warnings.
+func (powervs *PowervsV1) UpdateCloudConnection(ctx context.Context, options *CloudConnectionUpdateOptions) (*CloudConnectionChange, error) {
+	change, err := powervs.PlanCloudConnectionUpdate(ctx, options)
+	if err != nil || change.Update == nil {
+		return change, err
+	}
+	if len(change.Warnings) > 0 && !options.Force {
+		return change, fmt.Errorf("update of cloud connection %s not applied, set Force to accept: %s", options.CloudConnectionID, strings.Join(change.Warnings, "; "))
+	}
+	if _, _, err := powervs.PcloudCloudconnectionsPutWithContext(ctx, change.Update); err != nil {
+		return change, fmt.Errorf("failed to update cloud connection %s: %w", options.CloudConnectionID, err)
+	}
+	change.Applied = true
+	wasUp := core.StringNilMapper(change.Connection.LinkStatus) == CloudConnectionLinkStatusUp
+	// Renaming or metering changes leave the link as is, idle connections without endpoints included
+	linkChanges := change.Update.Speed != nil || change.Update.GlobalRouting != nil || change.Update.VPC != nil
+	if err := powervs.waitForCloudConnectionLink(ctx, options.CloudInstanceID, options.CloudConnectionID, wasUp, func(c *CloudConnection) (bool, bool) {
+		change.Connection = c
+		return cloudConnectionUpdated(c, change.Update), linkChanges
+	}); err != nil {
+		return change, fmt.Errorf("cloud connection %s did not come back up: %w", options.CloudConnectionID, err)
+	}
+	return change, nil
+}
+
+// waitForCloudConnection Poll a cloud connection until the condition holds
+func (powervs *PowervsV1) waitForCloudConnection(ctx context.Context, cloudInstanceID string, cloudConnectionID string, done func(*CloudConnection) bool) error {
+	return waitFor(ctx, func() (bool, error) {
+		connection, _, err := powervs.PcloudCloudconnectionsGetWithContext(ctx, powervs.NewPcloudCloudconnectionsGetOptions(cloudInstanceID, cloudConnectionID))
+		if err != nil {
+			return false, err
+		}
+		return done(connection), nil
+	})
+}
+
+// waitForCloudConnectionLink Poll a cloud connection until it is updated and, when needed, its
+// link is up. check reports whether the polled connection shows the update and whether its link
+// must then be up. When the link was up before the wait, it going down once updated fails the
+// wait. The link is given DefaultCloudConnectionTimeout to come up.
+func (powervs *PowervsV1) waitForCloudConnectionLink(ctx context.Context, cloudInstanceID string, cloudConnectionID string, wasUp bool, check func(*CloudConnection) (updated bool, needsUp bool)) error {
+	waitCtx, cancel := context.WithTimeout(ctx, DefaultCloudConnectionTimeout)
+	defer cancel()
+	err := waitFor(waitCtx, func() (bool, error) {
+		connection, _, err := powervs.PcloudCloudconnectionsGetWithContext(waitCtx, powervs.NewPcloudCloudconnectionsGetOptions(cloudInstanceID, cloudConnectionID))
+		if err != nil {
+			return false, err
+		}
+		updated, needsUp := check(connection)
+		if !updated {
+			return false, nil
+		}
+		switch core.StringNilMapper(connection.LinkStatus) {
+		case CloudConnectionLinkStatusUp:
+			return true, nil
+		case CloudConnectionLinkStatusDown:
+			if needsUp && wasUp {
+				return false, fmt.Errorf("link of cloud connection %s went down", cloudConnectionID)
+			}
+		}
+		return !needsUp, nil
+	})
+	if err != nil && waitCtx.Err() != nil && ctx.Err() == nil {
+		return fmt.Errorf("link of cloud connection %s did not come up within %s", cloudConnectionID, DefaultCloudConnectionTimeout)
+	}
+	return err
+}
+
+// cloudConnectionUpdated Whether a cloud connection shows the values of an update
+func cloudConnectionUpdated(connection *CloudConnection, update *PcloudCloudconnectionsPutOptions) bool {
+	if update.Name != nil && *update.Name != core.StringNilMapper(connection.Name) {
+		return false
+	}
+	if update.Speed != nil && *update.Speed != int64Value(connection.Speed) {
+		return false
+	}
+	if update.Metered != nil && *update.Metered != boolValue(connection.Metered) {
+		return false
+	}
+	if update.GlobalRouting != nil && *update.GlobalRouting != boolValue(connection.GlobalRouting) {
+		return false
+	}
+	if update.VPC != nil {
+		var current, desired []string
+		if connection.VPC != nil {
+			for _, vpc := range connection.VPC.Vpcs {
+				current = append(current, core.StringNilMapper(vpc.VPCID))
+			}
+		}
+		for _, vpc := range update.VPC.Vpcs {
+			desired = append(desired, core.StringNilMapper(vpc.VPCID))
+		}
+		sort.Strings(current)
+		sort.Strings(desired)
+		if strings.Join(current, ",") != strings.Join(desired, ",") {
+			return false
+		}
+	}
+	return true
+}
+
+// cloudConnectionHasNetwork Whether a network is attached to a cloud connection
+func cloudConnectionHasNetwork(connection *CloudConnection, networkID string) bool {
+	for _, network := range connection.Networks {
+		if core.StringNilMapper(network.NetworkID) == networkID {
+			return true
+		}
+	}
+	return false
+}
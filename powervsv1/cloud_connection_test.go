@@ -0,0 +1,226 @@
+package powervsv1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+func TestPlanCloudConnectionUpdate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch strings.TrimPrefix(r.URL.Path, "/pcloud/v1") {
+		case "/cloud-instances/ws/cloud-connections/cc-1":
+			_, _ = w.Write([]byte(`{"cloudConnectionID": "cc-1", "name": "to-vpc", "speed": 5000, "globalRouting": true, "metered": false, "linkStatus": "up", "port": "p",
+				"ibmIPAddress": "169.254.0.1", "userIPAddress": "169.254.0.2", "creationDate": "2024-01-01T00:00:00.000Z",
+				"vpc": {"enabled": true, "vpcs": [{"vpcID": "vpc-1", "name": "app"}, {"vpcID": "vpc-2", "name": "db"}]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		options      CloudConnectionUpdateOptions
+		wantUpdate   bool
+		wantVPCs     []string
+		wantWarnings []string
+	}{
+		{
+			name:    "already matching",
+			options: CloudConnectionUpdateOptions{Speed: core.Int64Ptr(5000), Metered: core.BoolPtr(false), AttachVPCs: []CloudConnectionVPC{{VPCID: core.StringPtr("vpc-1")}}, DetachVPCs: []string{"vpc-3"}},
+		},
+		{
+			name:       "speed upgrade and new vpc",
+			options:    CloudConnectionUpdateOptions{Speed: core.Int64Ptr(10000), AttachVPCs: []CloudConnectionVPC{{VPCID: core.StringPtr("vpc-3")}, {VPCID: core.StringPtr("vpc-2")}}},
+			wantUpdate: true,
+			wantVPCs:   []string{"vpc-1", "vpc-2", "vpc-3"},
+		},
+		{
+			name:         "downgrade, metering and detach",
+			options:      CloudConnectionUpdateOptions{Speed: core.Int64Ptr(1000), Metered: core.BoolPtr(true), GlobalRouting: core.BoolPtr(false), DetachVPCs: []string{"vpc-2", "vpc-2"}},
+			wantUpdate:   true,
+			wantVPCs:     []string{"vpc-1"},
+			wantWarnings: []string{"speed is lowered from 5000 to 1000 Mbps", "metering is enabled, outbound traffic will be billed", "global routing is disabled, other regions lose access", "VPCs vpc-2 are detached"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.options.CloudInstanceID, tt.options.CloudConnectionID = "ws", "cc-1"
+			change, err := powervs.PlanCloudConnectionUpdate(context.Background(), &tt.options)
+			if err != nil {
+				t.Fatalf("PlanCloudConnectionUpdate() error = %v", err)
+			}
+			if (change.Update != nil) != tt.wantUpdate {
+				t.Fatalf("PlanCloudConnectionUpdate() update = %+v, want update %v", change.Update, tt.wantUpdate)
+			}
+			if !reflect.DeepEqual(change.Warnings, tt.wantWarnings) {
+				t.Errorf("PlanCloudConnectionUpdate() warnings = %q, want %q", change.Warnings, tt.wantWarnings)
+			}
+			var vpcs []string
+			if change.Update != nil && change.Update.VPC != nil {
+				for _, vpc := range change.Update.VPC.Vpcs {
+					vpcs = append(vpcs, *vpc.VPCID)
+				}
+			}
+			if !reflect.DeepEqual(vpcs, tt.wantVPCs) {
+				t.Errorf("PlanCloudConnectionUpdate() vpcs = %v, want %v", vpcs, tt.wantVPCs)
+			}
+		})
+	}
+
+	if _, err := powervs.PlanCloudConnectionUpdate(context.Background(), &CloudConnectionUpdateOptions{CloudInstanceID: "ws"}); err == nil {
+		t.Errorf("PlanCloudConnectionUpdate() without connection expected error")
+	}
+}
+
+func TestCloudConnectionLifecycle(t *testing.T) {
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = time.Millisecond
+
+	var mu sync.Mutex
+	var connection map[string]interface{}
+	var requests []string
+	polls := 0
+	// Update sent but not shown yet, and polls since it was sent
+	var update map[string]interface{}
+	updatePolls := 0
+	// Link status the connection gets stuck in after an update
+	stuck := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.Path, "/pcloud/v1/cloud-instances/ws/cloud-connections")
+		if r.Method != http.MethodGet {
+			requests = append(requests, r.Method+" "+path)
+		}
+		switch {
+		case path == "" && r.Method == http.MethodPost:
+			connection = map[string]interface{}{"cloudConnectionID": "cc-1", "speed": 1000, "globalRouting": false, "metered": false, "linkStatus": "configuring", "port": "p",
+				"ibmIPAddress": "169.254.0.1", "userIPAddress": "169.254.0.2", "creationDate": "2024-01-01T00:00:00.000Z", "networks": []interface{}{}}
+			_ = json.NewDecoder(r.Body).Decode(&connection)
+		case connection == nil:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"description": "not found"}`))
+			return
+		case path == "/cc-1" && r.Method == http.MethodGet:
+			// Updates show from the second poll, the link comes up, and attached networks appear,
+			// after a few polls
+			if update != nil {
+				if updatePolls++; updatePolls > 1 {
+					for key, value := range update {
+						connection[key] = value
+					}
+					connection["linkStatus"] = "configuring"
+					if stuck != "" {
+						connection["linkStatus"] = stuck
+					}
+					update = nil
+				}
+			}
+			if polls++; polls%3 == 0 && stuck == "" && update == nil {
+				connection["linkStatus"] = "up"
+				if network, ok := connection["pending"]; ok {
+					connection["networks"] = append(connection["networks"].([]interface{}), map[string]interface{}{"networkID": network, "name": network, "href": "h", "type": "vlan", "vlanID": 10})
+					delete(connection, "pending")
+				}
+			}
+		case path == "/cc-1" && r.Method == http.MethodPut:
+			update, updatePolls = map[string]interface{}{}, 0
+			_ = json.NewDecoder(r.Body).Decode(&update)
+		case path == "/cc-1" && r.Method == http.MethodDelete:
+			connection = nil
+			_, _ = w.Write([]byte(`{}`))
+			return
+		case path == "/cc-1/networks/net-1" && r.Method == http.MethodPut:
+			connection["pending"] = "net-1"
+			_, _ = w.Write([]byte(`{}`))
+			return
+		case path == "/cc-1/networks/net-1" && r.Method == http.MethodDelete:
+			connection["networks"] = []interface{}{}
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(connection)
+	}))
+	defer server.Close()
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	created, err := powervs.CreateCloudConnection(ctx, powervs.NewPcloudCloudconnectionsPostOptions("ws", "to-vpc", 1000))
+	if err != nil || *created.LinkStatus != CloudConnectionLinkStatusUp || *created.Name != "to-vpc" {
+		t.Fatalf("CreateCloudConnection() = %+v, error = %v", created, err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := powervs.AttachCloudConnectionNetwork(ctx, "ws", "cc-1", "net-1"); err != nil {
+			t.Fatalf("AttachCloudConnectionNetwork() error = %v", err)
+		}
+	}
+
+	options := &CloudConnectionUpdateOptions{CloudInstanceID: "ws", CloudConnectionID: "cc-1", Speed: core.Int64Ptr(50), AttachVPCs: []CloudConnectionVPC{{VPCID: core.StringPtr("vpc-1")}}}
+	change, err := powervs.UpdateCloudConnection(ctx, options)
+	if err == nil || change.Applied || !strings.Contains(err.Error(), "speed is lowered from 1000 to 50 Mbps") {
+		t.Errorf("UpdateCloudConnection() = %+v, error = %v", change, err)
+	}
+	options.Force = true
+	change, err = powervs.UpdateCloudConnection(ctx, options)
+	if err != nil || !change.Applied || *change.Connection.Speed != 50 || *change.Connection.LinkStatus != CloudConnectionLinkStatusUp || len(change.Connection.VPC.Vpcs) != 1 {
+		t.Errorf("UpdateCloudConnection() = %+v, error = %v", change, err)
+	}
+	change, err = powervs.UpdateCloudConnection(ctx, options)
+	if err != nil || change.Applied {
+		t.Errorf("UpdateCloudConnection() of matching connection = %+v, error = %v", change, err)
+	}
+
+	// A link that was up and goes down fails the update before ctx ends
+	mu.Lock()
+	stuck = "down"
+	mu.Unlock()
+	options = &CloudConnectionUpdateOptions{CloudInstanceID: "ws", CloudConnectionID: "cc-1", Speed: core.Int64Ptr(100)}
+	change, err = powervs.UpdateCloudConnection(ctx, options)
+	if err == nil || ctx.Err() != nil || !change.Applied || !strings.Contains(err.Error(), "link of cloud connection cc-1 went down") {
+		t.Errorf("UpdateCloudConnection() with failing link = %+v, error = %v", change, err)
+	}
+
+	// Renaming an idle connection does not wait for its link
+	mu.Lock()
+	stuck = "idle"
+	connection["linkStatus"] = "idle"
+	mu.Unlock()
+	options = &CloudConnectionUpdateOptions{CloudInstanceID: "ws", CloudConnectionID: "cc-1", Name: core.StringPtr("renamed")}
+	change, err = powervs.UpdateCloudConnection(ctx, options)
+	if err != nil || !change.Applied || *change.Connection.Name != "renamed" {
+		t.Errorf("UpdateCloudConnection() of idle connection = %+v, error = %v", change, err)
+	}
+	mu.Lock()
+	stuck = ""
+	mu.Unlock()
+
+	if err := powervs.DetachCloudConnectionNetwork(ctx, "ws", "cc-1", "net-1"); err != nil {
+		t.Fatalf("DetachCloudConnectionNetwork() error = %v", err)
+	}
+	if err := powervs.DeleteCloudConnection(ctx, "ws", "cc-1"); err != nil {
+		t.Fatalf("DeleteCloudConnection() error = %v", err)
+	}
+	want := []string{"POST ", "PUT /cc-1/networks/net-1", "PUT /cc-1", "PUT /cc-1", "PUT /cc-1", "DELETE /cc-1/networks/net-1", "DELETE /cc-1"}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests = %q, want %q", requests, want)
+	}
+}
@@ -0,0 +1,305 @@
+package powervsv1
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// Operating system families of an image
+const (
+	OperatingSystemAIX   = "aix"
+	OperatingSystemIBMi  = "ibmi"
+	OperatingSystemLinux = "linux"
+)
+
+// defaultSharedProcessorStep Granularity of shared and capped processors when the hardware platform does not report it
+const defaultSharedProcessorStep = 0.25
+
+// CompatibilityRules : Deployment constraints not reported by the API. Empty by default.
+type CompatibilityRules struct {
+	// Processor types not supported, by system type.
+	UnsupportedProcTypes map[string][]string
+
+	// Operating system families not supported, by system type.
+	UnsupportedOperatingSystems map[string][]string
+}
+
+// CompatibilityIssue : Incompatible deployment setting
+type CompatibilityIssue struct {
+	// Field of PcloudPvminstancesPostOptions, or "image".
+	Field string `json:"field"`
+
+	Message string `json:"message"`
+}
+
+// SysTypeCompatibility : Whether an image can be deployed on a system type
+type SysTypeCompatibility struct {
+	SysType string `json:"sysType"`
+
+	Compatible bool `json:"compatible"`
+
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// CompatibilityReport : Result of CompatibilityMatrix.Validate
+type CompatibilityReport struct {
+	Valid bool `json:"valid"`
+
+	Issues []CompatibilityIssue `json:"issues,omitempty"`
+
+	// System types the image and processor type can be deployed on.
+	CompatibleSysTypes []string `json:"compatibleSysTypes"`
+}
+
+// String : Human readable rendering of the report
+func (report *CompatibilityReport) String() string {
+	if report.Valid {
+		return "Deployment settings are compatible."
+	}
+	lines := []string{"Deployment settings are not compatible:"}
+	for _, issue := range report.Issues {
+		lines = append(lines, fmt.Sprintf("  %s: %s", issue.Field, issue.Message))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CompatibilityMatrix : System types of a workspace and their hardware platforms, checked
+// against image specifications and instance settings
+type CompatibilityMatrix struct {
+	CloudInstanceID string
+
+	// System pools of the workspace, by system type.
+	SystemPools map[string]SystemPool
+
+	// Hardware platforms of the workspace region, by system type.
+	HardwarePlatforms map[string]HardwarePlatform
+
+	Rules CompatibilityRules
+}
+
+// NewCompatibilityMatrix : Load the system pools of a workspace and the hardware platforms of its region
+func (powervs *PowervsV1) NewCompatibilityMatrix(ctx context.Context, cloudInstanceID string) (*CompatibilityMatrix, error) {
+	cloudInstance, _, err := powervs.PcloudCloudinstancesGetWithContext(ctx, powervs.NewPcloudCloudinstancesGetOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace %s: %w", cloudInstanceID, err)
+	}
+	systemPools, _, err := powervs.PcloudSystempoolsGetWithContext(ctx, powervs.NewPcloudSystempoolsGetOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get system pools: %w", err)
+	}
+	platformsOptions := powervs.NewServiceBrokerHardwareplatformsGetOptions()
+	if cloudInstance.Region != nil {
+		platformsOptions.SetRegionZone(*cloudInstance.Region)
+	}
+	platforms, _, err := powervs.ServiceBrokerHardwareplatformsGetWithContext(ctx, platformsOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hardware platforms: %w", err)
+	}
+	return &CompatibilityMatrix{CloudInstanceID: cloudInstanceID, SystemPools: systemPools, HardwarePlatforms: platforms}, nil
+}
+
+// ValidateDeployCompatibility : Check an instance creation request against the system types,
+// hardware platforms and image of its workspace
+func (powervs *PowervsV1) ValidateDeployCompatibility(ctx context.Context, options *PcloudPvminstancesPostOptions) (*CompatibilityReport, error) {
+	if err := core.ValidateNotNil(options, "options cannot be nil"); err != nil {
+		return nil, err
+	}
+	if err := core.ValidateStruct(options, "pcloudPvminstancesPostOptions"); err != nil {
+		return nil, err
+	}
+	matrix, err := powervs.NewCompatibilityMatrix(ctx, *options.CloudInstanceID)
+	if err != nil {
+		return nil, err
+	}
+	image, err := powervs.getDeployImage(ctx, *options.CloudInstanceID, *options.ImageID)
+	if err != nil {
+		return nil, err
+	}
+	return matrix.Validate(options, image), nil
+}
+
+// ImageOperatingSystem : Operating system family of an image, one of the OperatingSystem constants
+func ImageOperatingSystem(image *Image) string {
+	if image.Specifications == nil {
+		return ""
+	}
+	return operatingSystemFamily(core.StringNilMapper(image.Specifications.OperatingSystem))
+}
+
+// operatingSystemFamily OperatingSystem constant of an operating system name
+func operatingSystemFamily(name string) string {
+	os := strings.ToLower(name)
+	switch {
+	case os == "":
+		return ""
+	case strings.HasPrefix(os, OperatingSystemAIX):
+		return OperatingSystemAIX
+	case strings.HasPrefix(os, OperatingSystemIBMi), strings.HasPrefix(os, "ibm i"), strings.HasPrefix(os, "os400"):
+		return OperatingSystemIBMi
+	default:
+		return OperatingSystemLinux
+	}
+}
+
+// SysTypes : Compatibility of every system type of the workspace with an image and a processor
+// type, any processor type when empty
+func (matrix *CompatibilityMatrix) SysTypes(image *Image, procType string) []SysTypeCompatibility {
+	sysTypes := make([]string, 0, len(matrix.SystemPools))
+	for sysType := range matrix.SystemPools {
+		sysTypes = append(sysTypes, sysType)
+	}
+	sort.Strings(sysTypes)
+
+	imageIssues := imageCompatibilityIssues(image)
+	result := make([]SysTypeCompatibility, 0, len(sysTypes))
+	for _, sysType := range sysTypes {
+		compatibility := SysTypeCompatibility{SysType: sysType}
+		for _, issue := range imageIssues {
+			compatibility.Reasons = append(compatibility.Reasons, issue.Message)
+		}
+		compatibility.Reasons = append(compatibility.Reasons, matrix.sysTypeReasons(sysType, image, procType)...)
+		compatibility.Compatible = len(compatibility.Reasons) == 0
+		result = append(result, compatibility)
+	}
+	return result
+}
+
+// CompatibleSysTypes : System types of the workspace an image can be deployed on with a processor type
+func (matrix *CompatibilityMatrix) CompatibleSysTypes(image *Image, procType string) (sysTypes []string) {
+	for _, compatibility := range matrix.SysTypes(image, procType) {
+		if compatibility.Compatible {
+			sysTypes = append(sysTypes, compatibility.SysType)
+		}
+	}
+	return
+}
+
+// Validate : Check the system type, processor type, processors, memory and IBM i licenses of an
+// instance creation request deploying an image
+func (matrix *CompatibilityMatrix) Validate(options *PcloudPvminstancesPostOptions, image *Image) *CompatibilityReport {
+	procType := core.StringNilMapper(options.ProcType)
+	sysType := core.StringNilMapper(options.SysType)
+	report := &CompatibilityReport{CompatibleSysTypes: matrix.CompatibleSysTypes(image, procType)}
+	issue := func(field string, format string, args ...interface{}) {
+		report.Issues = append(report.Issues, CompatibilityIssue{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	report.Issues = append(report.Issues, imageCompatibilityIssues(image)...)
+
+	switch {
+	case sysType == "" && len(report.CompatibleSysTypes) == 0:
+		issue("sysType", "no system type of the workspace supports this image with %s processors", procType)
+	case sysType != "":
+		for _, reason := range matrix.sysTypeReasons(sysType, image, procType) {
+			if len(report.CompatibleSysTypes) > 0 {
+				reason += ", use one of: " + strings.Join(report.CompatibleSysTypes, ", ")
+			}
+			issue("sysType", "%s", reason)
+		}
+	}
+
+	processors := floatValue(options.Processors)
+	platform, hasPlatform := matrix.HardwarePlatforms[sysType]
+	switch procType {
+	case PcloudPvminstancesPostOptionsProcTypeDedicatedConst:
+		if processors < 1 || processors != math.Trunc(processors) {
+			issue("processors", "dedicated instances need a whole number of processors, got %s", formatFloat64(&processors))
+		}
+		if options.SharedProcessorPool != nil {
+			issue("sharedProcessorPool", "shared processor pools only host shared or capped instances, use procType shared")
+		}
+	case PcloudPvminstancesPostOptionsProcTypeSharedConst, PcloudPvminstancesPostOptionsProcTypeCappedConst:
+		step := defaultSharedProcessorStep
+		if hasPlatform && floatValue(platform.SharedProcessorStep) > 0 {
+			step = *platform.SharedProcessorStep
+		}
+		// Compare in hundredths to avoid floating point remainders
+		if processors < step || math.Mod(math.Round(processors*100), math.Round(step*100)) != 0 {
+			lower := math.Max(step, math.Floor(processors/step)*step)
+			upper := lower + step
+			issue("processors", "%s processors must be a multiple of %s, use %s or %s", procType, formatFloat64(&step), formatFloat64(&lower), formatFloat64(&upper))
+		}
+	default:
+		issue("procType", "unknown processor type %q, use dedicated, shared or capped", procType)
+	}
+
+	if hasPlatform {
+		if limit := floatValue(platform.Processors); limit > 0 && processors > limit {
+			issue("processors", "%s allows at most %s processors", sysType, formatFloat64(&limit))
+		}
+		memory := floatValue(options.Memory)
+		if limit := floatValue(platform.Memory); limit > 0 && memory > limit {
+			issue("memory", "%s allows at most %sGB of memory", sysType, formatFloat64(&limit))
+		}
+		if ratio := floatValue(platform.ProcessorMemoryRatio); ratio > 0 && memory > ratio*math.Ceil(processors) {
+			needed := math.Ceil(memory / ratio)
+			issue("memory", "%s allows %sGB of memory per processor, use at least %s processors for %sGB",
+				sysType, formatFloat64(&ratio), formatFloat64(&needed), formatFloat64(&memory))
+		}
+	}
+
+	if licenses := options.SoftwareLicenses; licenses != nil {
+		if ImageOperatingSystem(image) != OperatingSystemIBMi {
+			issue("softwareLicenses", "software licenses only apply to IBM i images, remove them")
+		}
+		users := int64Value(licenses.IbmiRdsUsers)
+		if boolValue(licenses.IbmiRds) && users <= 0 {
+			issue("softwareLicenses", "ibmiRDS needs ibmiRDSUsers set to the number of users")
+		}
+		if !boolValue(licenses.IbmiRds) && users > 0 {
+			issue("softwareLicenses", "ibmiRDSUsers needs ibmiRDS enabled")
+		}
+	}
+
+	report.Valid = len(report.Issues) == 0
+	return report
+}
+
+// sysTypeReasons Why an image and processor type cannot be deployed on a system type
+func (matrix *CompatibilityMatrix) sysTypeReasons(sysType string, image *Image, procType string) (reasons []string) {
+	if _, ok := matrix.SystemPools[sysType]; !ok {
+		return []string{fmt.Sprintf("system type %s is not available in the workspace", sysType)}
+	}
+	if len(matrix.HardwarePlatforms) > 0 {
+		if _, ok := matrix.HardwarePlatforms[sysType]; !ok {
+			reasons = append(reasons, fmt.Sprintf("system type %s is not offered in the region", sysType))
+		}
+	}
+	for _, unsupported := range matrix.Rules.UnsupportedProcTypes[sysType] {
+		if unsupported == procType {
+			reasons = append(reasons, fmt.Sprintf("system type %s does not support %s processors", sysType, procType))
+		}
+	}
+	os := ImageOperatingSystem(image)
+	for _, unsupported := range matrix.Rules.UnsupportedOperatingSystems[sysType] {
+		if unsupported == os {
+			reasons = append(reasons, fmt.Sprintf("system type %s does not support %s images", sysType, os))
+		}
+	}
+	return
+}
+
+// imageCompatibilityIssues Architecture and endianness of an image not deployable on Power systems
+func imageCompatibilityIssues(image *Image) (issues []CompatibilityIssue) {
+	if image.Specifications == nil {
+		return nil
+	}
+	name := core.StringNilMapper(image.Name)
+	architecture := strings.ToLower(core.StringNilMapper(image.Specifications.Architecture))
+	if architecture != "" && !strings.HasPrefix(architecture, "ppc64") {
+		issues = append(issues, CompatibilityIssue{Field: "image", Message: fmt.Sprintf("image %s is built for %s, use a ppc64 or ppc64le image", name, architecture)})
+	}
+	endianness := strings.ToLower(core.StringNilMapper(image.Specifications.Endianness))
+	os := ImageOperatingSystem(image)
+	switch {
+	case endianness == "big" && os == OperatingSystemLinux:
+		issues = append(issues, CompatibilityIssue{Field: "image", Message: fmt.Sprintf("image %s is a big endian Linux, use a little endian (ppc64le) image", name)})
+	case endianness == "little" && (os == OperatingSystemAIX || os == OperatingSystemIBMi):
+		issues = append(issues, CompatibilityIssue{Field: "image", Message: fmt.Sprintf("image %s is a little endian %s image, %s requires big endian", name, os, os)})
+	}
+	return
+}
@@ -0,0 +1,117 @@
+package powervsv1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+func TestCompatibilityMatrixValidate(t *testing.T) {
+	matrix := &CompatibilityMatrix{
+		SystemPools: map[string]SystemPool{"s922": {}, "e980": {}, "s1022": {}},
+		HardwarePlatforms: map[string]HardwarePlatform{
+			"s922":  {Processors: core.Float64Ptr(15), Memory: core.Float64Ptr(942), SharedProcessorStep: core.Float64Ptr(0.25), ProcessorMemoryRatio: core.Float64Ptr(64)},
+			"e980":  {Processors: core.Float64Ptr(143), Memory: core.Float64Ptr(15307), SharedProcessorStep: core.Float64Ptr(0.25)},
+			"s1022": {Processors: core.Float64Ptr(40), Memory: core.Float64Ptr(2000), SharedProcessorStep: core.Float64Ptr(0.25)},
+		},
+		Rules: CompatibilityRules{UnsupportedProcTypes: map[string][]string{"s1022": {"dedicated"}}},
+	}
+	aix := &Image{Name: core.StringPtr("aix"), Specifications: &ImageSpecifications{OperatingSystem: core.StringPtr("aix"), Architecture: core.StringPtr("ppc64"), Endianness: core.StringPtr("big")}}
+	ibmi := &Image{Name: core.StringPtr("ibmi"), Specifications: &ImageSpecifications{OperatingSystem: core.StringPtr("IBMi"), Architecture: core.StringPtr("ppc64"), Endianness: core.StringPtr("big")}}
+	rhel := &Image{Name: core.StringPtr("rhel"), Specifications: &ImageSpecifications{OperatingSystem: core.StringPtr("rhel"), Architecture: core.StringPtr("ppc64"), Endianness: core.StringPtr("big")}}
+
+	tests := []struct {
+		name       string
+		image      *Image
+		sysType    string
+		procType   string
+		processors float64
+		memory     float64
+		licenses   *SoftwareLicenses
+		want       []CompatibilityIssue
+	}{
+		{"valid", aix, "s922", "shared", 0.5, 16, nil, nil},
+		{"processor step", aix, "s922", "capped", 0.3, 16, nil, []CompatibilityIssue{
+			{"processors", "capped processors must be a multiple of 0.25, use 0.25 or 0.5"},
+		}},
+		{"dedicated fraction", aix, "e980", "dedicated", 1.5, 16, nil, []CompatibilityIssue{
+			{"processors", "dedicated instances need a whole number of processors, got 1.5"},
+		}},
+		{"unsupported proc type", aix, "s1022", "dedicated", 2, 16, nil, []CompatibilityIssue{
+			{"sysType", "system type s1022 does not support dedicated processors, use one of: e980, s922"},
+		}},
+		{"unknown sys type", aix, "e1080", "shared", 1, 16, nil, []CompatibilityIssue{
+			{"sysType", "system type e1080 is not available in the workspace, use one of: e980, s1022, s922"},
+		}},
+		{"platform limits", aix, "s922", "shared", 16, 64, nil, []CompatibilityIssue{
+			{"processors", "s922 allows at most 15 processors"},
+		}},
+		{"memory ratio", aix, "s922", "shared", 1, 256, nil, []CompatibilityIssue{
+			{"memory", "s922 allows 64GB of memory per processor, use at least 4 processors for 256GB"},
+		}},
+		{"big endian linux", rhel, "s922", "shared", 1, 16, nil, []CompatibilityIssue{
+			{"image", "image rhel is a big endian Linux, use a little endian (ppc64le) image"},
+		}},
+		{"licenses on aix", aix, "s922", "shared", 1, 16, &SoftwareLicenses{IbmiCss: core.BoolPtr(true)}, []CompatibilityIssue{
+			{"softwareLicenses", "software licenses only apply to IBM i images, remove them"},
+		}},
+		{"rds users", ibmi, "s922", "shared", 1, 16, &SoftwareLicenses{IbmiRds: core.BoolPtr(true)}, []CompatibilityIssue{
+			{"softwareLicenses", "ibmiRDS needs ibmiRDSUsers set to the number of users"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := (&PowervsV1{}).NewPcloudPvminstancesPostOptions("ws", "img", tt.memory, tt.procType, tt.processors, "vm")
+			options.SetSysType(tt.sysType)
+			options.SoftwareLicenses = tt.licenses
+			report := matrix.Validate(options, tt.image)
+			if !reflect.DeepEqual(report.Issues, tt.want) {
+				t.Errorf("Validate() issues =\n%+v\nwant\n%+v", report.Issues, tt.want)
+			}
+			if report.Valid != (len(tt.want) == 0) {
+				t.Errorf("Validate() valid = %v", report.Valid)
+			}
+		})
+	}
+}
+
+func TestValidateDeployCompatibility(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch strings.TrimPrefix(r.URL.Path, "/pcloud/v1") {
+		case "/cloud-instances/ws/system-pools":
+			_, _ = w.Write([]byte(`{"s922": {"type": "s922"}, "e980": {"type": "e980"}}`))
+		case "/broker/v1/hardware-platforms":
+			_, _ = w.Write([]byte(`{"s922": {"processors": 15, "memory": 942, "sharedProcessorStep": 0.25}}`))
+		case "/cloud-instances/ws/stock-images/img-9":
+			_, _ = w.Write([]byte(`{"imageID": "img-9", "name": "ubuntu", "specifications": {"operatingSystem": "ubuntu", "architecture": "x86_64", "endianness": "little"}}`))
+		case "/cloud-instances/ws":
+			_, _ = w.Write([]byte(`{"cloudInstanceID": "ws", "tenantID": "tenant", "enabled": true, "initialized": true, "name": "ws", "openstackID": "o", "region": "dal", "capabilities": [],
+				"limits": {}, "usage": {}, "pvmInstances": []}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"description": "not found"}`))
+		}
+	}))
+	defer server.Close()
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := powervs.ValidateDeployCompatibility(context.Background(), powervs.NewPcloudPvminstancesPostOptions("ws", "img-9", 8, "shared", 1, "vm").SetSysType("e980"))
+	if err != nil {
+		t.Fatalf("ValidateDeployCompatibility() error = %v", err)
+	}
+	want := []CompatibilityIssue{
+		{"image", "image ubuntu is built for x86_64, use a ppc64 or ppc64le image"},
+		{"sysType", "system type e980 is not offered in the region"},
+	}
+	if report.Valid || !reflect.DeepEqual(report.Issues, want) || report.CompatibleSysTypes != nil {
+		t.Errorf("ValidateDeployCompatibility() =\n%s\ncompatible system types %v", report, report.CompatibleSysTypes)
+	}
+}
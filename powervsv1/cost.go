@@ -0,0 +1,290 @@
+package powervsv1
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"sigs.k8s.io/yaml"
+)
+
+// PriceTableSchemaVersion Version of the price table format read by ParsePriceTable
+const PriceTableSchemaVersion = "powervs.prices/v1"
+
+// Components of a CostItem
+const (
+	CostComponentCores           = "cores"
+	CostComponentMemory          = "memory"
+	CostComponentStorage         = "storage"
+	CostComponentOperatingSystem = "operatingSystem"
+	CostComponentLicense         = "license"
+	CostComponentSnapshot        = "snapshot"
+)
+
+// PriceTable : Monthly prices used to estimate the cost of resources
+type PriceTable struct {
+	SchemaVersion string `json:"schemaVersion"`
+
+	// Version of the prices, such as the date they were published.
+	Version string `json:"version"`
+
+	Currency string `json:"currency"`
+
+	// Price of a core by processor type (dedicated, shared, capped).
+	Cores map[string]float64 `json:"cores"`
+
+	// Price of a GB of memory.
+	Memory float64 `json:"memory"`
+
+	// Price of a GB of storage by tier.
+	Storage map[string]float64 `json:"storage"`
+
+	// Price of a core by operating system (aix, ibmi, linux).
+	OperatingSystems map[string]float64 `json:"operatingSystems,omitempty"`
+
+	Licenses LicensePrices `json:"licenses,omitempty"`
+
+	// Price of a GB of snapshot.
+	Snapshot float64 `json:"snapshot,omitempty"`
+}
+
+// LicensePrices : Monthly prices of the IBM i license add-ons
+type LicensePrices struct {
+	// Price of a core.
+	IbmiCss float64 `json:"ibmiCSS,omitempty"`
+
+	// Price of a core.
+	IbmiDbq float64 `json:"ibmiDBQ,omitempty"`
+
+	// Price of a core.
+	IbmiPha float64 `json:"ibmiPHA,omitempty"`
+
+	// Price of a user.
+	IbmiRdsUser float64 `json:"ibmiRDSUser,omitempty"`
+}
+
+// CostItem : Monthly cost of one component of a resource
+type CostItem struct {
+	// Kind of resource such as instance or volume.
+	Resource string `json:"resource"`
+
+	Name string `json:"name"`
+
+	// One of the CostComponent constants.
+	Component string `json:"component"`
+
+	// Tier, processor type, operating system or license the price applies to.
+	Detail string `json:"detail,omitempty"`
+
+	Quantity float64 `json:"quantity"`
+
+	UnitPrice float64 `json:"unitPrice"`
+
+	Cost float64 `json:"cost"`
+}
+
+// CostEstimate : Monthly cost of a set of resources
+type CostEstimate struct {
+	PriceVersion string `json:"priceVersion"`
+
+	Currency string `json:"currency"`
+
+	Items []CostItem `json:"items"`
+
+	Total float64 `json:"total"`
+}
+
+// Add : Append the items of another estimate
+func (estimate *CostEstimate) Add(other *CostEstimate) {
+	estimate.Items = append(estimate.Items, other.Items...)
+	estimate.Total += other.Total
+}
+
+// ByResource : Total cost of each resource, keyed by resource kind and name
+func (estimate *CostEstimate) ByResource() map[string]float64 {
+	totals := map[string]float64{}
+	for _, item := range estimate.Items {
+		totals[item.Resource+"/"+item.Name] += item.Cost
+	}
+	return totals
+}
+
+// String : Table of the cost items followed by the total
+func (estimate *CostEstimate) String() string {
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESOURCE\tNAME\tCOMPONENT\tQUANTITY\tUNIT PRICE\tCOST")
+	for _, item := range estimate.Items {
+		component := item.Component
+		if item.Detail != "" {
+			component += " (" + item.Detail + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%.2f\t%.2f\n", item.Resource, item.Name, component, formatFloat64(&item.Quantity), item.UnitPrice, item.Cost)
+	}
+	tw.Flush()
+	fmt.Fprintf(&b, "Total: %.2f %s per month (prices %s)", estimate.Total, estimate.Currency, estimate.PriceVersion)
+	return b.String()
+}
+
+// ParsePriceTable : Decode a JSON or YAML price table
+func ParsePriceTable(data []byte) (*PriceTable, error) {
+	table := &PriceTable{}
+	if err := yaml.UnmarshalStrict(data, table); err != nil {
+		return nil, fmt.Errorf("failed to parse price table: %w", err)
+	}
+	if table.SchemaVersion != PriceTableSchemaVersion {
+		return nil, fmt.Errorf("unsupported price table schema version %q, expected %q", table.SchemaVersion, PriceTableSchemaVersion)
+	}
+	if table.Version == "" {
+		return nil, fmt.Errorf("price table has no version")
+	}
+	return table, nil
+}
+
+// LoadPriceTable : Read a JSON or YAML price table from a file
+func LoadPriceTable(path string) (*PriceTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePriceTable(data)
+}
+
+// costEstimator Accumulate the cost items of resources, remembering missing prices
+type costEstimator struct {
+	table    *PriceTable
+	estimate *CostEstimate
+	missing  []string
+}
+
+func (table *PriceTable) newEstimator() *costEstimator {
+	return &costEstimator{table: table, estimate: &CostEstimate{PriceVersion: table.Version, Currency: table.Currency}}
+}
+
+// add Record an item when its quantity is not zero
+func (e *costEstimator) add(resource, name, component, detail string, quantity, unitPrice float64) {
+	if quantity == 0 {
+		return
+	}
+	item := CostItem{Resource: resource, Name: name, Component: component, Detail: detail, Quantity: quantity, UnitPrice: unitPrice, Cost: quantity * unitPrice}
+	e.estimate.Items = append(e.estimate.Items, item)
+	e.estimate.Total += item.Cost
+}
+
+// price Look up a price, recording it as missing when absent
+func (e *costEstimator) price(prices map[string]float64, kind, key string) float64 {
+	price, ok := prices[key]
+	if !ok {
+		missing := fmt.Sprintf("%s %q", kind, key)
+		for _, m := range e.missing {
+			if m == missing {
+				return 0
+			}
+		}
+		e.missing = append(e.missing, missing)
+	}
+	return price
+}
+
+// instance Cost items of replicas instances with the given configuration
+func (e *costEstimator) instance(name string, replicas float64, procType string, processors, memory float64, operatingSystem string, licenses *SoftwareLicenses) {
+	cores := replicas * processors
+	e.add("instance", name, CostComponentCores, procType, cores, e.price(e.table.Cores, "processor type", procType))
+	e.add("instance", name, CostComponentMemory, "", replicas*memory, e.table.Memory)
+	if operatingSystem != "" {
+		// Operating systems without a price, such as Linux, are free
+		e.add("instance", name, CostComponentOperatingSystem, operatingSystem, cores, e.table.OperatingSystems[operatingSystem])
+	}
+	if licenses != nil {
+		if boolValue(licenses.IbmiCss) {
+			e.add("instance", name, CostComponentLicense, "ibmiCSS", cores, e.table.Licenses.IbmiCss)
+		}
+		if boolValue(licenses.IbmiDbq) {
+			e.add("instance", name, CostComponentLicense, "ibmiDBQ", cores, e.table.Licenses.IbmiDbq)
+		}
+		if boolValue(licenses.IbmiPha) {
+			e.add("instance", name, CostComponentLicense, "ibmiPHA", cores, e.table.Licenses.IbmiPha)
+		}
+		if boolValue(licenses.IbmiRds) {
+			e.add("instance", name, CostComponentLicense, "ibmiRDS", replicas*float64(int64Value(licenses.IbmiRdsUsers)), e.table.Licenses.IbmiRdsUser)
+		}
+	}
+}
+
+// volume Cost item of a volume
+func (e *costEstimator) volume(name, diskType string, size float64) {
+	e.add("volume", name, CostComponentStorage, diskType, size, e.price(e.table.Storage, "storage tier", diskType))
+}
+
+// result The estimate, or an error listing the missing prices
+func (e *costEstimator) result() (*CostEstimate, error) {
+	if len(e.missing) > 0 {
+		return e.estimate, fmt.Errorf("price table %s has no price for %s", e.table.Version, strings.Join(e.missing, ", "))
+	}
+	return e.estimate, nil
+}
+
+// EstimateInstance : Monthly cost of an instance creation running the given operating system
+// (one of the OperatingSystem constants), including its replicants. The boot volume depends on
+// the image and is not included.
+func (table *PriceTable) EstimateInstance(options *PcloudPvminstancesPostOptions, operatingSystem string) (*CostEstimate, error) {
+	if err := core.ValidateNotNil(options, "options cannot be nil"); err != nil {
+		return nil, err
+	}
+	replicas := 1.0
+	if options.Replicants != nil && *options.Replicants > 1 {
+		replicas = *options.Replicants
+	}
+	e := table.newEstimator()
+	e.instance(core.StringNilMapper(options.ServerName), replicas, core.StringNilMapper(options.ProcType), floatValue(options.Processors), floatValue(options.Memory), operatingSystem, options.SoftwareLicenses)
+	return e.result()
+}
+
+// EstimateVolume : Monthly cost of a volume creation, on tier3 when no disk type is given
+func (table *PriceTable) EstimateVolume(options *PcloudCloudinstancesVolumesPostOptions) (*CostEstimate, error) {
+	if err := core.ValidateNotNil(options, "options cannot be nil"); err != nil {
+		return nil, err
+	}
+	diskType := core.StringNilMapper(options.DiskType)
+	if diskType == "" {
+		diskType = "tier3"
+	}
+	e := table.newEstimator()
+	e.volume(core.StringNilMapper(options.Name), diskType, floatValue(options.Size))
+	return e.result()
+}
+
+// EstimateInventory : Monthly cost of the instances, volumes and snapshots of an inventory.
+// Snapshots are priced at the size of the volumes they capture, an upper bound of their usage.
+func (table *PriceTable) EstimateInventory(inventory *WorkspaceInventory) (*CostEstimate, error) {
+	e := table.newEstimator()
+	for _, instance := range inventory.Instances {
+		e.instance(core.StringNilMapper(instance.ServerName), 1, core.StringNilMapper(instance.ProcType), floatValue(instance.Processors), floatValue(instance.Memory),
+			operatingSystemFamily(core.StringNilMapper(instance.OsType)), instance.SoftwareLicenses)
+	}
+	sizes := map[string]float64{}
+	for _, volume := range inventory.Volumes {
+		sizes[core.StringNilMapper(volume.VolumeID)] = floatValue(volume.Size)
+		e.volume(core.StringNilMapper(volume.Name), core.StringNilMapper(volume.DiskType), floatValue(volume.Size))
+	}
+	for _, snapshot := range inventory.Snapshots {
+		size := 0.0
+		for volumeID := range snapshot.VolumeSnapshots {
+			size += sizes[volumeID]
+		}
+		e.add("snapshot", core.StringNilMapper(snapshot.Name), CostComponentSnapshot, "", size, table.Snapshot)
+	}
+	return e.result()
+}
+
+// EstimateWorkspaceCost : Monthly cost of the resources of a live workspace
+func (powervs *PowervsV1) EstimateWorkspaceCost(ctx context.Context, cloudInstanceID string, table *PriceTable) (*CostEstimate, error) {
+	inventory, err := powervs.GetWorkspaceInventory(ctx, cloudInstanceID)
+	if err != nil {
+		return nil, err
+	}
+	return table.EstimateInventory(inventory)
+}
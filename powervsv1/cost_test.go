@@ -0,0 +1,117 @@
+package powervsv1
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+const fakePriceTable = `
+schemaVersion: powervs.prices/v1
+version: "2024-06"
+currency: USD
+cores:
+  shared: 50
+  capped: 50
+  dedicated: 100
+memory: 10
+storage:
+  tier1: 0.5
+  tier3: 0.25
+operatingSystems:
+  aix: 20
+  ibmi: 150
+licenses:
+  ibmiCSS: 30
+  ibmiRDSUser: 5
+snapshot: 0.1
+`
+
+func TestParsePriceTable(t *testing.T) {
+	table, err := ParsePriceTable([]byte(fakePriceTable))
+	if err != nil {
+		t.Fatalf("ParsePriceTable() error = %v", err)
+	}
+	if table.Version != "2024-06" || table.Storage["tier3"] != 0.25 || table.Licenses.IbmiRdsUser != 5 {
+		t.Errorf("ParsePriceTable() = %+v", table)
+	}
+	for _, data := range []string{`{"schemaVersion": "powervs.prices/v0", "version": "1"}`, `{"schemaVersion": "powervs.prices/v1"}`, `{"schemaVersion": "powervs.prices/v1", "version": "1", "gpu": 3}`} {
+		if _, err := ParsePriceTable([]byte(data)); err == nil {
+			t.Errorf("ParsePriceTable(%s) expected error", data)
+		}
+	}
+}
+
+func TestEstimateInstance(t *testing.T) {
+	table, err := ParsePriceTable([]byte(fakePriceTable))
+	if err != nil {
+		t.Fatal(err)
+	}
+	powervs := &PowervsV1{}
+	options := powervs.NewPcloudPvminstancesPostOptions("ws", "img", 32, "shared", 2, "erp").SetReplicants(2).
+		SetSoftwareLicenses(&SoftwareLicenses{IbmiCss: core.BoolPtr(true), IbmiRds: core.BoolPtr(true), IbmiRdsUsers: core.Int64Ptr(10)})
+	estimate, err := table.EstimateInstance(options, OperatingSystemIBMi)
+	if err != nil {
+		t.Fatalf("EstimateInstance() error = %v", err)
+	}
+	want := []CostItem{
+		{"instance", "erp", CostComponentCores, "shared", 4, 50, 200},
+		{"instance", "erp", CostComponentMemory, "", 64, 10, 640},
+		{"instance", "erp", CostComponentOperatingSystem, "ibmi", 4, 150, 600},
+		{"instance", "erp", CostComponentLicense, "ibmiCSS", 4, 30, 120},
+		{"instance", "erp", CostComponentLicense, "ibmiRDS", 20, 5, 100},
+	}
+	if !reflect.DeepEqual(estimate.Items, want) || estimate.Total != 1660 {
+		t.Errorf("EstimateInstance() =\n%s", estimate)
+	}
+
+	if _, err := table.EstimateVolume(powervs.NewPcloudCloudinstancesVolumesPostOptions("ws", "data", 100).SetDiskType("tier0")); err == nil || err.Error() != `price table 2024-06 has no price for storage tier "tier0"` {
+		t.Errorf("EstimateVolume() error = %v", err)
+	}
+	estimate, err = table.EstimateVolume(powervs.NewPcloudCloudinstancesVolumesPostOptions("ws", "data", 100))
+	if err != nil || estimate.Total != 25 {
+		t.Errorf("EstimateVolume() = %v, error = %v", estimate, err)
+	}
+}
+
+func TestEstimateInventory(t *testing.T) {
+	table, err := ParsePriceTable([]byte(fakePriceTable))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inventory, err := ParseInventory([]byte(`{"schemaVersion": "powervs.inventory/v1", "cloudInstanceID": "ws",
+		"instances": [
+			{"pvmInstanceID": "pvm-1", "serverName": "app", "memory": 8, "processors": 0.5, "procType": "shared", "osType": "aix"},
+			{"pvmInstanceID": "pvm-2", "serverName": "db", "memory": 4, "processors": 1, "procType": "dedicated", "osType": "aix"}],
+		"volumes": [
+			{"volumeID": "vol-1", "name": "boot", "size": 20, "diskType": "tier1"},
+			{"volumeID": "vol-2", "name": "data", "size": 100, "diskType": "tier1"},
+			{"volumeID": "vol-3", "name": "spare", "size": 10, "diskType": "tier3"}],
+		"snapshots": [{"snapshotID": "snap-1", "name": "before-patch", "volumeSnapshots": {"vol-1": "vs-1"}}]}`))
+	if err != nil {
+		t.Fatalf("ParseInventory() error = %v", err)
+	}
+	estimate, err := table.EstimateInventory(inventory)
+	if err != nil {
+		t.Fatalf("EstimateInventory() error = %v", err)
+	}
+	want := `RESOURCE  NAME          COMPONENT              QUANTITY  UNIT PRICE  COST
+instance  app           cores (shared)         0.5       50.00       25.00
+instance  app           memory                 8         10.00       80.00
+instance  app           operatingSystem (aix)  0.5       20.00       10.00
+instance  db            cores (dedicated)      1         100.00      100.00
+instance  db            memory                 4         10.00       40.00
+instance  db            operatingSystem (aix)  1         20.00       20.00
+volume    boot          storage (tier1)        20        0.50        10.00
+volume    data          storage (tier1)        100       0.50        50.00
+volume    spare         storage (tier3)        10        0.25        2.50
+snapshot  before-patch  snapshot               20        0.10        2.00
+Total: 339.50 USD per month (prices 2024-06)`
+	if got := estimate.String(); got != want {
+		t.Errorf("EstimateInventory() =\n%s\nwant\n%s", got, want)
+	}
+	if totals := estimate.ByResource(); totals["instance/app"] != 115 || totals["volume/spare"] != 2.5 {
+		t.Errorf("ByResource() = %v", totals)
+	}
+}
@@ -0,0 +1,348 @@
+package powervsv1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// DefaultEventWindow is the default size of the sub-windows queried by an EventPager
+const DefaultEventWindow = time.Hour
+
+// EventPagerOptions : The EventPager options.
+type EventPagerOptions struct {
+	CloudInstanceID string `validate:"required"`
+
+	// Start of the range, inclusive.
+	From time.Time
+
+	// End of the range, exclusive. Defaults to now.
+	To time.Time
+
+	// Relative range such as "last 6h" or "since yesterday", used when From is not set.
+	// See ParseTimeRange for the accepted expressions.
+	Range string
+
+	// Size of the sub-windows queried one at a time, defaults to DefaultEventWindow.
+	WindowSize time.Duration
+
+	// The language requested for the returned events.
+	AcceptLanguage string
+}
+
+// EventPager : Iterates over the events of a time range in chronological order,
+// querying one sub-window per page to keep responses small.
+type EventPager struct {
+	powervs    *PowervsV1
+	options    *EventPagerOptions
+	next       time.Time
+	to         time.Time
+	window     time.Duration
+	previousID map[string]bool
+}
+
+// NewEventPager : Instantiate an EventPager for the given range
+func (powervs *PowervsV1) NewEventPager(options *EventPagerOptions) (*EventPager, error) {
+	if err := core.ValidateNotNil(options, "options cannot be nil"); err != nil {
+		return nil, err
+	}
+	if err := core.ValidateStruct(options, "eventPagerOptions"); err != nil {
+		return nil, err
+	}
+	from, to := options.From, options.To
+	if from.IsZero() {
+		if options.Range == "" {
+			return nil, fmt.Errorf("either From or Range is required")
+		}
+		var err error
+		from, to, err = ParseTimeRange(options.Range, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("event range start %s is not before its end %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+	window := options.WindowSize
+	if window <= 0 {
+		window = DefaultEventWindow
+	}
+	return &EventPager{
+		powervs: powervs,
+		options: options,
+		next:    from,
+		to:      to,
+		window:  window,
+	}, nil
+}
+
+// HasNext : Whether more sub-windows remain to be queried
+func (pager *EventPager) HasNext() bool {
+	return pager.next.Before(pager.to)
+}
+
+// GetNext : Return the events of the next sub-window
+func (pager *EventPager) GetNext() ([]Event, error) {
+	return pager.GetNextWithContext(context.Background())
+}
+
+// GetNextWithContext : Return the events of the next sub-window, oldest first.
+// A window may be empty while later windows still hold events.
+func (pager *EventPager) GetNextWithContext(ctx context.Context) ([]Event, error) {
+	if !pager.HasNext() {
+		return nil, fmt.Errorf("no more results available")
+	}
+	from := pager.next
+	to := from.Add(pager.window)
+	if to.After(pager.to) {
+		to = pager.to
+	}
+
+	options := pager.powervs.NewPcloudEventsGetqueryOptions(pager.options.CloudInstanceID).
+		SetFromTime(from.UTC().Format(time.RFC3339)).
+		SetToTime(to.UTC().Format(time.RFC3339))
+	if pager.options.AcceptLanguage != "" {
+		options.SetAcceptLanguage(pager.options.AcceptLanguage)
+	}
+	result, _, err := pager.powervs.PcloudEventsGetqueryWithContext(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// Events on a window boundary may be returned by both windows
+	seen := map[string]bool{}
+	events := make([]Event, 0, len(result.Events))
+	for _, event := range result.Events {
+		id := core.StringNilMapper(event.EventID)
+		if pager.previousID[id] || seen[id] {
+			continue
+		}
+		seen[id] = true
+		events = append(events, event)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return eventTime(events[i]).Before(eventTime(events[j]))
+	})
+	pager.previousID = seen
+	pager.next = to
+	return events, nil
+}
+
+// GetAll : Return the events of the whole range
+func (pager *EventPager) GetAll() ([]Event, error) {
+	return pager.GetAllWithContext(context.Background())
+}
+
+// GetAllWithContext : Return the events of the whole range, oldest first
+func (pager *EventPager) GetAllWithContext(ctx context.Context) (allItems []Event, err error) {
+	for pager.HasNext() {
+		var nextPage []Event
+		nextPage, err = pager.GetNextWithContext(ctx)
+		if err != nil {
+			return
+		}
+		allItems = append(allItems, nextPage...)
+	}
+	return
+}
+
+// eventTime Time of an event, from Time or the Timestamp in milliseconds
+func eventTime(event Event) time.Time {
+	if event.Time != nil {
+		return time.Time(*event.Time)
+	}
+	if event.Timestamp != nil {
+		return time.UnixMilli(*event.Timestamp)
+	}
+	return time.Time{}
+}
+
+var relativeDurationRegex = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([a-z]+)$`)
+
+// parseRelativeDuration Parse durations such as "6h", "90 minutes", "2d" or "1w"
+func parseRelativeDuration(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+	m := relativeDurationRegex.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	value, _ := strconv.ParseFloat(m[1], 64)
+	units := map[string]time.Duration{
+		"s": time.Second, "sec": time.Second, "second": time.Second, "seconds": time.Second,
+		"m": time.Minute, "min": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+		"h": time.Hour, "hour": time.Hour, "hours": time.Hour,
+		"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+	}
+	unit, ok := units[m[2]]
+	if !ok {
+		return 0, fmt.Errorf("invalid duration unit %q", m[2])
+	}
+	return time.Duration(value * float64(unit)), nil
+}
+
+// parseTimePoint Parse an absolute or relative point in time
+func parseTimePoint(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	lower := strings.ToLower(s)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch lower {
+	case "now":
+		return now, nil
+	case "today":
+		return midnight, nil
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), nil
+	}
+	if strings.HasSuffix(lower, " ago") {
+		d, err := parseRelativeDuration(strings.TrimSuffix(lower, " ago"))
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(-d), nil
+	}
+	if epoch, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(epoch, 0), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+// ParseTimeRange : Parse a time range expression relative to now. Accepted expressions:
+//
+//	last 6h | last 2 days | past 30m   the given duration up to now
+//	since yesterday | since 3h ago     from a point in time up to now
+//	today | yesterday                  the calendar day, in the location of now
+//	<point> to <point>                 between two points in time
+//
+// Points in time are "now", "today", "yesterday", "<duration> ago", a unix epoch,
+// an ISO 8601 timestamp or a date.
+func ParseTimeRange(expr string, now time.Time) (from time.Time, to time.Time, err error) {
+	expr = strings.Join(strings.Fields(expr), " ")
+	// Keywords are matched case insensitively, timestamps keep their case
+	lower := strings.ToLower(expr)
+	to = now
+	switch {
+	case strings.HasPrefix(lower, "last "), strings.HasPrefix(lower, "past "):
+		var d time.Duration
+		d, err = parseRelativeDuration(expr[5:])
+		from = now.Add(-d)
+	case strings.HasPrefix(lower, "since "):
+		from, err = parseTimePoint(expr[6:], now)
+	case lower == "today":
+		from, err = parseTimePoint("today", now)
+	case lower == "yesterday":
+		from, _ = parseTimePoint("yesterday", now)
+		to, _ = parseTimePoint("today", now)
+	case strings.Contains(lower, " to "):
+		i := strings.Index(lower, " to ")
+		start := expr[:i]
+		if strings.HasPrefix(lower, "from ") {
+			start = expr[5:i]
+		}
+		from, err = parseTimePoint(start, now)
+		if err == nil {
+			to, err = parseTimePoint(expr[i+4:], now)
+		}
+	default:
+		err = fmt.Errorf("invalid time range %q", expr)
+	}
+	if err == nil && !from.Before(to) {
+		err = fmt.Errorf("time range %q is empty", expr)
+	}
+	return
+}
+
+// InstanceCreateEventMetadata : Metadata of a PVM instance creation event
+type InstanceCreateEventMetadata struct {
+	PvmInstanceID string  `json:"pvmInstanceID,omitempty"`
+	ServerName    string  `json:"serverName,omitempty"`
+	ImageID       string  `json:"imageID,omitempty"`
+	SysType       string  `json:"sysType,omitempty"`
+	ProcType      string  `json:"procType,omitempty"`
+	Processors    float64 `json:"processors,omitempty"`
+	Memory        float64 `json:"memory,omitempty"`
+}
+
+// VolumeAttachEventMetadata : Metadata of a volume attach or detach event
+type VolumeAttachEventMetadata struct {
+	VolumeID      string `json:"volumeID,omitempty"`
+	VolumeName    string `json:"volumeName,omitempty"`
+	PvmInstanceID string `json:"pvmInstanceID,omitempty"`
+	ServerName    string `json:"serverName,omitempty"`
+}
+
+// NetworkChangeEventMetadata : Metadata of a network creation, update or deletion event
+type NetworkChangeEventMetadata struct {
+	NetworkID   string `json:"networkID,omitempty"`
+	NetworkName string `json:"networkName,omitempty"`
+	CIDR        string `json:"cidr,omitempty"`
+	Type        string `json:"type,omitempty"`
+	VlanID      int64  `json:"vlanID,omitempty"`
+}
+
+var (
+	eventMetadataTypesMutex sync.RWMutex
+	eventMetadataTypes      = map[string]func() interface{}{
+		"pvm-instance/create": func() interface{} { return &InstanceCreateEventMetadata{} },
+		"volume/attach":       func() interface{} { return &VolumeAttachEventMetadata{} },
+		"volume/detach":       func() interface{} { return &VolumeAttachEventMetadata{} },
+		"network/create":      func() interface{} { return &NetworkChangeEventMetadata{} },
+		"network/update":      func() interface{} { return &NetworkChangeEventMetadata{} },
+		"network/delete":      func() interface{} { return &NetworkChangeEventMetadata{} },
+	}
+)
+
+// RegisterEventMetadataType : Decode the metadata of events with the given resource and action
+// into the struct returned by newMetadata
+func RegisterEventMetadataType(resource, action string, newMetadata func() interface{}) {
+	eventMetadataTypesMutex.Lock()
+	defer eventMetadataTypesMutex.Unlock()
+	eventMetadataTypes[resource+"/"+action] = newMetadata
+}
+
+// DecodeMetadata : Decode the metadata of the event into its registered type.
+// The raw metadata map is returned for events without a registered type.
+func (event *Event) DecodeMetadata() (interface{}, error) {
+	eventMetadataTypesMutex.RLock()
+	newMetadata, ok := eventMetadataTypes[core.StringNilMapper(event.Resource)+"/"+core.StringNilMapper(event.Action)]
+	eventMetadataTypesMutex.RUnlock()
+	if !ok {
+		return event.Metadata, nil
+	}
+	metadata := newMetadata()
+	if err := event.DecodeMetadataInto(metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// DecodeMetadataInto : Decode the metadata of the event into the given struct pointer
+func (event *Event) DecodeMetadataInto(metadata interface{}) error {
+	data, err := json.Marshal(event.Metadata)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, metadata); err != nil {
+		return fmt.Errorf("invalid metadata for event %s: %w", core.StringNilMapper(event.EventID), err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package powervsv1
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+func TestParseTimeRange(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expr     string
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  bool
+	}{
+		{"last 6h", now.Add(-6 * time.Hour), now, false},
+		{"Last 2 days", now.AddDate(0, 0, -2), now, false},
+		{"past 90m", now.Add(-90 * time.Minute), now, false},
+		{"since yesterday", time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC), now, false},
+		{"since 3h ago", now.Add(-3 * time.Hour), now, false},
+		{"since 2024-03-01", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), now, false},
+		{"today", time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), now, false},
+		{"yesterday", time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), false},
+		{"2024-03-01T10:00:00Z to 2024-03-01T12:00:00Z", time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), false},
+		{"last 6 fortnights", time.Time{}, time.Time{}, true},
+		{"since tomorrow", time.Time{}, time.Time{}, true},
+		{"now to 1h ago", time.Time{}, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			from, to, err := ParseTimeRange(tt.expr, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTimeRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Errorf("ParseTimeRange() = %v, %v, want %v, %v", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestEventPager(t *testing.T) {
+	var windows []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		from, to := r.URL.Query().Get("from_time"), r.URL.Query().Get("to_time")
+		windows = append(windows, from+"/"+to)
+		w.Header().Set("Content-Type", "application/json")
+		switch from {
+		case "2024-03-10T00:00:00Z":
+			// Out of order, with an event on the window boundary
+			fmt.Fprint(w, `{"events": [
+				{"eventID": "2", "action": "attach", "resource": "volume", "level": "info", "message": "m", "time": "2024-03-10T00:50:00Z", "timestamp": 0,
+				 "metadata": {"volumeID": "vol-1", "pvmInstanceID": "pvm-1"}},
+				{"eventID": "1", "action": "create", "resource": "pvm-instance", "level": "info", "message": "m", "time": "2024-03-10T00:10:00Z", "timestamp": 0,
+				 "metadata": {"serverName": "app-1", "processors": 0.5}},
+				{"eventID": "3", "action": "delete", "resource": "network", "level": "info", "message": "m", "time": "2024-03-10T01:00:00Z", "timestamp": 0}]}`)
+		case "2024-03-10T01:00:00Z":
+			fmt.Fprint(w, `{"events": [
+				{"eventID": "3", "action": "delete", "resource": "network", "level": "info", "message": "m", "time": "2024-03-10T01:00:00Z", "timestamp": 0},
+				{"eventID": "4", "action": "reboot", "resource": "pvm-instance", "level": "info", "message": "m", "time": "2024-03-10T01:20:00Z", "timestamp": 0,
+				 "metadata": {"reason": "patch"}}]}`)
+		default:
+			fmt.Fprint(w, `{"events": []}`)
+		}
+	}))
+	defer server.Close()
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pager, err := powervs.NewEventPager(&EventPagerOptions{
+		CloudInstanceID: "ws",
+		From:            time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+		To:              time.Date(2024, 3, 10, 2, 30, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := pager.GetAll()
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	wantWindows := []string{
+		"2024-03-10T00:00:00Z/2024-03-10T01:00:00Z",
+		"2024-03-10T01:00:00Z/2024-03-10T02:00:00Z",
+		"2024-03-10T02:00:00Z/2024-03-10T02:30:00Z",
+	}
+	if !reflect.DeepEqual(windows, wantWindows) {
+		t.Errorf("queried windows = %v, want %v", windows, wantWindows)
+	}
+	var ids []string
+	for _, e := range events {
+		ids = append(ids, *e.EventID)
+	}
+	if !reflect.DeepEqual(ids, []string{"1", "2", "3", "4"}) {
+		t.Errorf("event order = %v", ids)
+	}
+
+	create, err := events[0].DecodeMetadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := create.(*InstanceCreateEventMetadata); !ok || m.ServerName != "app-1" || m.Processors != 0.5 {
+		t.Errorf("DecodeMetadata() = %#v", create)
+	}
+	attach, _ := events[1].DecodeMetadata()
+	if m, ok := attach.(*VolumeAttachEventMetadata); !ok || m.VolumeID != "vol-1" {
+		t.Errorf("DecodeMetadata() = %#v", attach)
+	}
+	if raw, _ := events[3].DecodeMetadata(); !reflect.DeepEqual(raw, map[string]interface{}{"reason": "patch"}) {
+		t.Errorf("DecodeMetadata() = %#v", raw)
+	}
+}
+
+func TestNewEventPagerRange(t *testing.T) {
+	powervs := &PowervsV1{}
+	if _, err := powervs.NewEventPager(&EventPagerOptions{CloudInstanceID: "ws"}); err == nil {
+		t.Error("NewEventPager() expected error without range")
+	}
+	pager, err := powervs.NewEventPager(&EventPagerOptions{CloudInstanceID: "ws", Range: "last 6h", WindowSize: 2 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pager.to.Sub(pager.next); got != 6*time.Hour {
+		t.Errorf("NewEventPager() range = %v, want 6h", got)
+	}
+}
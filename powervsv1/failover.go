@@ -0,0 +1,585 @@
+package powervsv1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// Status of a runbook step
+const (
+	RunbookStepCompleted = "completed"
+	RunbookStepFailed    = "failed"
+)
+
+// RunbookLog : Step by step record of a disaster recovery runbook.
+// Passing the log of an interrupted run resumes it after its last completed step.
+type RunbookLog struct {
+	Runbook string `json:"runbook"`
+
+	// Hash of the runbook options, a log is only resumed with the options it was written for.
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	Steps []RunbookStep `json:"steps"`
+}
+
+// RunbookStep : One step of a disaster recovery runbook
+type RunbookStep struct {
+	Name string `json:"name"`
+
+	Status string `json:"status"`
+
+	Message string `json:"message,omitempty"`
+
+	StartedAt time.Time `json:"startedAt"`
+
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+
+	// Values produced by the step and reused by later steps, such as created resource IDs.
+	Outputs map[string]string `json:"outputs,omitempty"`
+}
+
+// ReadRunbookLog : Load a runbook log written by a previous run
+func ReadRunbookLog(path string) (*RunbookLog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	log := &RunbookLog{}
+	if err := json.Unmarshal(data, log); err != nil {
+		return nil, fmt.Errorf("invalid runbook log %s: %w", path, err)
+	}
+	return log, nil
+}
+
+// WriteFile : Save the runbook log as JSON
+func (log *RunbookLog) WriteFile(path string) error {
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Step : Find a step by name
+func (log *RunbookLog) Step(name string) *RunbookStep {
+	for i := range log.Steps {
+		if log.Steps[i].Name == name {
+			return &log.Steps[i]
+		}
+	}
+	return nil
+}
+
+// String : Human readable rendering of the log
+func (log *RunbookLog) String() string {
+	lines := []string{log.Runbook + ":"}
+	for _, s := range log.Steps {
+		line := fmt.Sprintf("  [%s] %s", s.Status, s.Name)
+		if s.Message != "" {
+			line += ": " + s.Message
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// runbook Runs steps once, recording them in the log and persisting it after each step
+type runbook struct {
+	log     *RunbookLog
+	logPath string
+}
+
+// newRunbook Start a runbook for options, resuming from the log at logPath when it exists
+func newRunbook(name, logPath string, options interface{}) (*runbook, error) {
+	data, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	fingerprint := hex.EncodeToString(sum[:])
+	r := &runbook{log: &RunbookLog{Runbook: name, Fingerprint: fingerprint}, logPath: logPath}
+	if logPath == "" {
+		return r, nil
+	}
+	log, err := ReadRunbookLog(logPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if log.Runbook != name {
+		return nil, fmt.Errorf("runbook log %s belongs to a %s runbook", logPath, log.Runbook)
+	}
+	if log.Fingerprint != fingerprint {
+		return nil, fmt.Errorf("runbook log %s was written for other %s options, pass the same options or another log path", logPath, name)
+	}
+	r.log = log
+	return r, nil
+}
+
+// run Execute fn unless the step already completed in a previous run
+func (r *runbook) run(name string, fn func(outputs map[string]string) (string, error)) (map[string]string, error) {
+	step := r.log.Step(name)
+	if step != nil && step.Status == RunbookStepCompleted {
+		return step.Outputs, nil
+	}
+	if step == nil {
+		r.log.Steps = append(r.log.Steps, RunbookStep{Name: name})
+		step = &r.log.Steps[len(r.log.Steps)-1]
+	}
+	step.StartedAt = time.Now().UTC()
+	step.Outputs = map[string]string{}
+
+	message, err := fn(step.Outputs)
+	if err != nil {
+		step.Status = RunbookStepFailed
+		step.Message = err.Error()
+	} else {
+		now := time.Now().UTC()
+		step.Status = RunbookStepCompleted
+		step.Message = message
+		step.CompletedAt = &now
+	}
+	if r.logPath != "" {
+		if saveErr := r.log.WriteFile(r.logPath); saveErr != nil && err == nil {
+			err = fmt.Errorf("failed to save runbook log: %w", saveErr)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("runbook step %s failed: %w", name, err)
+	}
+	return step.Outputs, nil
+}
+
+// FailoverOptions : The Failover options.
+type FailoverOptions struct {
+	// Workspace replicating its volumes.
+	SourceCloudInstanceID string `validate:"required"`
+
+	// CRN of the source workspace, used to onboard its auxiliary volumes.
+	SourceCRN string `validate:"required"`
+
+	// Workspace at the replication site taking over the workload.
+	TargetCloudInstanceID string `validate:"required"`
+
+	// Volume groups (consistency groups) of the source workspace to fail over.
+	VolumeGroupIDs []string `validate:"required,min=1"`
+
+	// Instances to create at the target from the onboarded volumes.
+	Instances []FailoverInstance
+
+	// Source network ID to target network ID.
+	NetworkMapping map[string]string
+
+	// File recording the runbook progress. An existing log resumes the failover, it must have
+	// been written for the same options.
+	LogPath string
+}
+
+// FailoverInstance : Instance recreated at the target workspace from onboarded volumes
+type FailoverInstance struct {
+	ServerName string `validate:"required"`
+
+	// Auxiliary volume name of the boot volume.
+	BootVolume string `validate:"required"`
+
+	// Auxiliary volume names of the data volumes.
+	DataVolumes []string
+
+	Memory float64 `validate:"required"`
+
+	Processors float64 `validate:"required"`
+
+	ProcType string `validate:"required"`
+
+	SysType string
+
+	// Network attachments using source network IDs, translated with the network mapping.
+	Networks []PvmInstanceAddNetwork
+}
+
+// Failover : Fail over volume groups to the replication site and recreate instances there.
+//
+// The target workspace is first checked to be at a replication site of the source location.
+// The runbook stops replication on each volume group giving access to the auxiliary volumes,
+// onboards the auxiliary volumes at the target workspace and creates the instances from the
+// onboarded boot volumes, waiting for them to become active. Instances already present at the
+// target under the same name, created by an interrupted run, are reused.
+func (powervs *PowervsV1) Failover(ctx context.Context, options *FailoverOptions) (*RunbookLog, error) {
+	if err := core.ValidateNotNil(options, "options cannot be nil"); err != nil {
+		return nil, err
+	}
+	if err := core.ValidateStruct(options, "failoverOptions"); err != nil {
+		return nil, err
+	}
+	for _, instance := range options.Instances {
+		if err := core.ValidateStruct(instance, "failoverInstance"); err != nil {
+			return nil, err
+		}
+		for _, n := range instance.Networks {
+			if _, ok := options.NetworkMapping[core.StringNilMapper(n.NetworkID)]; !ok {
+				return nil, fmt.Errorf("no target network mapped for network %s of instance %s", core.StringNilMapper(n.NetworkID), instance.ServerName)
+			}
+		}
+	}
+	source, target := options.SourceCloudInstanceID, options.TargetCloudInstanceID
+	if err := powervs.checkReplicationSite(ctx, source, target); err != nil {
+		return nil, err
+	}
+	// The log path itself does not change what the runbook does
+	fingerprint := *options
+	fingerprint.LogPath = ""
+	r, err := newRunbook("failover", options.LogPath, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	var auxVolumes []string
+	for _, groupID := range options.VolumeGroupIDs {
+		groupID := groupID
+		_, err = r.run("stop-replication:"+groupID, func(map[string]string) (string, error) {
+			return "replication stopped with access to auxiliary volumes", powervs.stopVolumeGroupReplication(ctx, source, groupID)
+		})
+		if err != nil {
+			return r.log, err
+		}
+		outputs, err := r.run("collect-auxiliary-volumes:"+groupID, func(outputs map[string]string) (string, error) {
+			relationships, _, err := powervs.PcloudVolumegroupsRemoteCopyRelationshipsGetWithContext(ctx, powervs.NewPcloudVolumegroupsRemoteCopyRelationshipsGetOptions(source, groupID))
+			if err != nil {
+				return "", err
+			}
+			var names []string
+			for _, rel := range relationships.RemoteCopyRelationships {
+				if rel.AuxVolumeName != nil {
+					names = append(names, *rel.AuxVolumeName)
+				}
+			}
+			outputs["auxVolumes"] = strings.Join(names, ",")
+			return fmt.Sprintf("%d auxiliary volumes", len(names)), nil
+		})
+		if err != nil {
+			return r.log, err
+		}
+		if outputs["auxVolumes"] != "" {
+			auxVolumes = append(auxVolumes, strings.Split(outputs["auxVolumes"], ",")...)
+		}
+	}
+
+	outputs, err := r.run("onboard-volumes", func(outputs map[string]string) (string, error) {
+		var volumes []AuxiliaryVolumeForOnboarding
+		for _, name := range auxVolumes {
+			volumes = append(volumes, AuxiliaryVolumeForOnboarding{AuxVolumeName: core.StringPtr(name)})
+		}
+		onboardingOptions := powervs.NewPcloudVolumeOnboardingPostOptions(target, []AuxiliaryVolumesForOnboarding{{
+			AuxiliaryVolumes: volumes,
+			SourceCRN:        core.StringPtr(options.SourceCRN),
+		}}).SetDescription("failover from " + source)
+		onboarding, _, err := powervs.PcloudVolumeOnboardingPostWithContext(ctx, onboardingOptions)
+		if err != nil {
+			return "", err
+		}
+		outputs["onboardingID"] = core.StringNilMapper(onboarding.ID)
+		return "onboarding " + outputs["onboardingID"] + " started", nil
+	})
+	if err != nil {
+		return r.log, err
+	}
+	onboardingID := outputs["onboardingID"]
+
+	_, err = r.run("wait-onboarding", func(map[string]string) (string, error) {
+		return "auxiliary volumes onboarded", powervs.waitForVolumeOnboarding(ctx, target, onboardingID)
+	})
+	if err != nil {
+		return r.log, err
+	}
+
+	if len(options.Instances) == 0 {
+		return r.log, nil
+	}
+	volumeIDs, err := powervs.auxiliaryVolumeIDs(ctx, target)
+	if err != nil {
+		return r.log, err
+	}
+	for _, instance := range options.Instances {
+		instance := instance
+		outputs, err := r.run("create-instance:"+instance.ServerName, func(outputs map[string]string) (string, error) {
+			// The log may not record an instance created right before an interruption
+			existing, err := powervs.findPvmInstanceByName(ctx, target, instance.ServerName)
+			if err != nil {
+				return "", err
+			}
+			if existing != "" {
+				outputs["pvmInstanceID"] = existing
+				return "instance " + existing + " already created", nil
+			}
+			createOptions, err := instance.createOptions(powervs, target, volumeIDs, options.NetworkMapping)
+			if err != nil {
+				return "", err
+			}
+			created, _, err := powervs.PcloudPvminstancesPostWithContext(ctx, createOptions)
+			if err != nil {
+				return "", err
+			}
+			if len(created) == 0 {
+				return "", fmt.Errorf("no instance returned")
+			}
+			outputs["pvmInstanceID"] = core.StringNilMapper(created[0].PvmInstanceID)
+			return "instance " + outputs["pvmInstanceID"] + " created", nil
+		})
+		if err != nil {
+			return r.log, err
+		}
+		pvmInstanceID := outputs["pvmInstanceID"]
+		_, err = r.run("wait-instance:"+instance.ServerName, func(map[string]string) (string, error) {
+			return "instance active", powervs.waitForPvmInstanceStatus(ctx, target, pvmInstanceID, "ACTIVE")
+		})
+		if err != nil {
+			return r.log, err
+		}
+	}
+	return r.log, nil
+}
+
+// FailbackOptions : The Failback options.
+type FailbackOptions struct {
+	// Workspace that originally replicated its volumes.
+	SourceCloudInstanceID string `validate:"required"`
+
+	// Workspace at the replication site currently running the workload.
+	TargetCloudInstanceID string `validate:"required"`
+
+	// Volume groups (consistency groups) of the source workspace to fail back.
+	VolumeGroupIDs []string `validate:"required,min=1"`
+
+	// Instances of the target workspace to shut down before copying data back.
+	TargetPvmInstanceIDs []string
+
+	// File recording the runbook progress. An existing log resumes the failback, it must have
+	// been written for the same options.
+	LogPath string
+}
+
+// Failback : Return the workload to the source workspace after a failover.
+//
+// The runbook shuts down the instances running at the target, replicates the auxiliary
+// volumes back to the master volumes, then restores replication in its original direction.
+// Instances and onboarded volumes of the target workspace are left in place.
+func (powervs *PowervsV1) Failback(ctx context.Context, options *FailbackOptions) (*RunbookLog, error) {
+	if err := core.ValidateNotNil(options, "options cannot be nil"); err != nil {
+		return nil, err
+	}
+	if err := core.ValidateStruct(options, "failbackOptions"); err != nil {
+		return nil, err
+	}
+	fingerprint := *options
+	fingerprint.LogPath = ""
+	r, err := newRunbook("failback", options.LogPath, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	source, target := options.SourceCloudInstanceID, options.TargetCloudInstanceID
+
+	for _, pvmInstanceID := range options.TargetPvmInstanceIDs {
+		pvmInstanceID := pvmInstanceID
+		_, err = r.run("stop-instance:"+pvmInstanceID, func(map[string]string) (string, error) {
+			_, _, err := powervs.PcloudPvminstancesActionPostWithContext(ctx, powervs.NewPcloudPvminstancesActionPostOptions(target, pvmInstanceID, PcloudPvminstancesActionPostOptionsActionStopConst))
+			if err != nil {
+				return "", err
+			}
+			return "instance stopped", powervs.waitForPvmInstanceStatus(ctx, target, pvmInstanceID, "SHUTOFF")
+		})
+		if err != nil {
+			return r.log, err
+		}
+	}
+
+	for _, groupID := range options.VolumeGroupIDs {
+		groupID := groupID
+		steps := []struct {
+			name string
+			fn   func() error
+		}{
+			{"reverse-replication", func() error {
+				return powervs.startVolumeGroupReplication(ctx, source, groupID, VolumeGroupActionStartSourceAuxConst)
+			}},
+			{"wait-synchronized", func() error {
+				return powervs.waitForRemoteCopyState(ctx, source, groupID, "consistent_synchronized")
+			}},
+			{"stop-replication", func() error {
+				return powervs.stopVolumeGroupReplication(ctx, source, groupID)
+			}},
+			{"restore-replication", func() error {
+				return powervs.startVolumeGroupReplication(ctx, source, groupID, VolumeGroupActionStartSourceMasterConst)
+			}},
+		}
+		for _, step := range steps {
+			step := step
+			_, err = r.run(step.name+":"+groupID, func(map[string]string) (string, error) {
+				return "", step.fn()
+			})
+			if err != nil {
+				return r.log, err
+			}
+		}
+	}
+	return r.log, nil
+}
+
+// checkReplicationSite Check that the target workspace is at a replication site of the location
+// of the source workspace
+func (powervs *PowervsV1) checkReplicationSite(ctx context.Context, source, target string) error {
+	location, _, err := powervs.PcloudLocationsDisasterrecoveryGetWithContext(ctx, powervs.NewPcloudLocationsDisasterrecoveryGetOptions(source))
+	if err != nil {
+		return fmt.Errorf("failed to get replication sites of workspace %s: %w", source, err)
+	}
+	workspace, _, err := powervs.PcloudCloudinstancesGetWithContext(ctx, powervs.NewPcloudCloudinstancesGetOptions(target))
+	if err != nil {
+		return fmt.Errorf("failed to get workspace %s: %w", target, err)
+	}
+	region := core.StringNilMapper(workspace.Region)
+	var sites []string
+	for _, site := range location.ReplicationSites {
+		if strings.EqualFold(core.StringNilMapper(site.Location), region) {
+			return nil
+		}
+		sites = append(sites, core.StringNilMapper(site.Location))
+	}
+	return fmt.Errorf("workspace %s in %s is not a replication site of %s, use one of [%s]", target, region, core.StringNilMapper(location.Location), strings.Join(sites, ", "))
+}
+
+// findPvmInstanceByName ID of the instance of a workspace with the given name, empty if none
+func (powervs *PowervsV1) findPvmInstanceByName(ctx context.Context, cloudInstanceID, serverName string) (string, error) {
+	instances, _, err := powervs.PcloudPvminstancesGetallWithContext(ctx, powervs.NewPcloudPvminstancesGetallOptions(cloudInstanceID))
+	if err != nil {
+		return "", fmt.Errorf("failed to list instances of workspace %s: %w", cloudInstanceID, err)
+	}
+	for _, instance := range instances.PvmInstances {
+		if core.StringNilMapper(instance.ServerName) == serverName {
+			return core.StringNilMapper(instance.PvmInstanceID), nil
+		}
+	}
+	return "", nil
+}
+
+// stopVolumeGroupReplication Stop replication of a volume group, giving access to the auxiliary volumes
+func (powervs *PowervsV1) stopVolumeGroupReplication(ctx context.Context, cloudInstanceID, volumeGroupID string) error {
+	action := &VolumeGroupAction{Stop: &VolumeGroupActionStop{Access: core.BoolPtr(true)}}
+	_, _, err := powervs.PcloudVolumegroupsActionPostWithContext(ctx, powervs.NewPcloudVolumegroupsActionPostOptions(cloudInstanceID, volumeGroupID, action))
+	if err != nil {
+		return err
+	}
+	return powervs.waitForRemoteCopyState(ctx, cloudInstanceID, volumeGroupID, "idling")
+}
+
+// startVolumeGroupReplication Start replication of a volume group from the given source copy
+func (powervs *PowervsV1) startVolumeGroupReplication(ctx context.Context, cloudInstanceID, volumeGroupID, source string) error {
+	action := &VolumeGroupAction{Start: &VolumeGroupActionStart{Source: core.StringPtr(source)}}
+	_, _, err := powervs.PcloudVolumegroupsActionPostWithContext(ctx, powervs.NewPcloudVolumegroupsActionPostOptions(cloudInstanceID, volumeGroupID, action))
+	return err
+}
+
+// waitForRemoteCopyState Wait until every remote copy relationship of a volume group reaches state
+func (powervs *PowervsV1) waitForRemoteCopyState(ctx context.Context, cloudInstanceID, volumeGroupID, state string) error {
+	return waitFor(ctx, func() (bool, error) {
+		relationships, _, err := powervs.PcloudVolumegroupsRemoteCopyRelationshipsGetWithContext(ctx, powervs.NewPcloudVolumegroupsRemoteCopyRelationshipsGetOptions(cloudInstanceID, volumeGroupID))
+		if err != nil {
+			return false, err
+		}
+		for _, rel := range relationships.RemoteCopyRelationships {
+			if !strings.HasPrefix(core.StringNilMapper(rel.State), state) {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
+
+// waitForVolumeOnboarding Wait until a volume onboarding operation succeeds
+func (powervs *PowervsV1) waitForVolumeOnboarding(ctx context.Context, cloudInstanceID, volumeOnboardingID string) error {
+	return waitFor(ctx, func() (bool, error) {
+		onboarding, _, err := powervs.PcloudVolumeOnboardingGetWithContext(ctx, powervs.NewPcloudVolumeOnboardingGetOptions(cloudInstanceID, volumeOnboardingID))
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToUpper(core.StringNilMapper(onboarding.Status)) {
+		case "SUCCESS":
+			return true, nil
+		case "FAILED", "PARTIAL_SUCCESS":
+			var failures []string
+			if onboarding.Results != nil {
+				for _, f := range onboarding.Results.VolumeOnboardingFailures {
+					failures = append(failures, fmt.Sprintf("%s (%s)", core.StringNilMapper(f.FailureMessage), strings.Join(f.Volumes, ",")))
+				}
+			}
+			return false, fmt.Errorf("volume onboarding %s ended with status %s: %s", volumeOnboardingID, *onboarding.Status, strings.Join(failures, "; "))
+		}
+		return false, nil
+	})
+}
+
+// waitForPvmInstanceStatus Wait until an instance reaches the given status, failing if it errors
+func (powervs *PowervsV1) waitForPvmInstanceStatus(ctx context.Context, cloudInstanceID, pvmInstanceID, status string) error {
+	return waitFor(ctx, func() (bool, error) {
+		instance, _, err := powervs.PcloudPvminstancesGetWithContext(ctx, powervs.NewPcloudPvminstancesGetOptions(cloudInstanceID, pvmInstanceID))
+		if err != nil {
+			return false, err
+		}
+		current := core.StringNilMapper(instance.Status)
+		if strings.EqualFold(current, "ERROR") {
+			return false, fmt.Errorf("instance %s is in error state", pvmInstanceID)
+		}
+		return strings.EqualFold(current, status), nil
+	})
+}
+
+// auxiliaryVolumeIDs Map auxiliary volume names to the IDs of the onboarded volumes of a workspace
+func (powervs *PowervsV1) auxiliaryVolumeIDs(ctx context.Context, cloudInstanceID string) (map[string]string, error) {
+	volumes, _, err := powervs.PcloudCloudinstancesVolumesGetallWithContext(ctx, powervs.NewPcloudCloudinstancesVolumesGetallOptions(cloudInstanceID).SetAuxiliary(true))
+	if err != nil {
+		return nil, err
+	}
+	ids := map[string]string{}
+	for _, v := range volumes.Volumes {
+		if v.AuxVolumeName != nil {
+			ids[*v.AuxVolumeName] = core.StringNilMapper(v.VolumeID)
+		}
+	}
+	return ids, nil
+}
+
+// createOptions Build the creation options of the instance at the target workspace.
+// The onboarded boot volume is passed as the image of the new instance.
+func (instance FailoverInstance) createOptions(powervs *PowervsV1, cloudInstanceID string, volumeIDs, networkMapping map[string]string) (*PcloudPvminstancesPostOptions, error) {
+	bootVolumeID, ok := volumeIDs[instance.BootVolume]
+	if !ok {
+		return nil, fmt.Errorf("boot volume %s was not onboarded", instance.BootVolume)
+	}
+	options := powervs.NewPcloudPvminstancesPostOptions(cloudInstanceID, bootVolumeID, instance.Memory, instance.ProcType, instance.Processors, instance.ServerName)
+	for _, name := range instance.DataVolumes {
+		volumeID, ok := volumeIDs[name]
+		if !ok {
+			return nil, fmt.Errorf("data volume %s was not onboarded", name)
+		}
+		options.VolumeIDs = append(options.VolumeIDs, volumeID)
+	}
+	for _, n := range instance.Networks {
+		options.Networks = append(options.Networks, PvmInstanceAddNetwork{
+			NetworkID: core.StringPtr(networkMapping[core.StringNilMapper(n.NetworkID)]),
+			IPAddress: n.IPAddress,
+		})
+	}
+	if instance.SysType != "" {
+		options.SetSysType(instance.SysType)
+	}
+	return options, nil
+}
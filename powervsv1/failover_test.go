@@ -0,0 +1,196 @@
+package powervsv1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+func TestFailover(t *testing.T) {
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = time.Millisecond
+
+	onboardingFails := true
+	targetRegion := "wdc06"
+	created := ""
+	var requests []string
+	var instanceBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "GET /pcloud/v1/cloud-instances/primary/locations/disaster-recovery":
+			_, _ = w.Write([]byte(`{"location": "dal12", "replicationSites": [{"location": "dal12", "isActive": true}, {"location": "wdc06", "isActive": true}]}`))
+		case "GET /pcloud/v1/cloud-instances/secondary":
+			fmt.Fprintf(w, `{"cloudInstanceID": "secondary", "name": "dr", "region": "%s"}`, targetRegion)
+		case "GET /pcloud/v1/cloud-instances/secondary/pvm-instances":
+			if created == "" {
+				_, _ = w.Write([]byte(`{"pvmInstances": []}`))
+				return
+			}
+			fmt.Fprintf(w, `{"pvmInstances": [{"pvmInstanceID": "%s", "serverName": "app-1"}]}`, created)
+		case "POST /pcloud/v1/cloud-instances/primary/volume-groups/vg-1/action":
+			w.WriteHeader(202)
+			_, _ = w.Write([]byte(`{}`))
+		case "GET /pcloud/v1/cloud-instances/primary/volume-groups/vg-1/remote-copy-relationships":
+			_, _ = w.Write([]byte(`{"remoteCopyRelationships": [
+				{"name": "rel-1", "remoteCopyID": "1", "auxVolumeName": "aux_boot", "state": "idling"},
+				{"name": "rel-2", "remoteCopyID": "2", "auxVolumeName": "aux_data", "state": "idling"}]}`))
+		case "POST /pcloud/v1/cloud-instances/secondary/volumes/onboarding":
+			if onboardingFails {
+				w.WriteHeader(500)
+				_, _ = w.Write([]byte(`{"description": "internal error"}`))
+				return
+			}
+			w.WriteHeader(202)
+			_, _ = w.Write([]byte(`{"id": "onb-1"}`))
+		case "GET /pcloud/v1/cloud-instances/secondary/volumes/onboarding/onb-1":
+			_, _ = w.Write([]byte(`{"id": "onb-1", "status": "SUCCESS"}`))
+		case "GET /pcloud/v1/cloud-instances/secondary/volumes":
+			_, _ = w.Write([]byte(`{"volumes": [
+				{"volumeID": "vol-boot", "auxVolumeName": "aux_boot"},
+				{"volumeID": "vol-data", "auxVolumeName": "aux_data"}]}`))
+		case "POST /pcloud/v1/cloud-instances/secondary/pvm-instances":
+			_ = json.NewDecoder(r.Body).Decode(&instanceBody)
+			created = "pvm-1"
+			w.WriteHeader(201)
+			_, _ = w.Write([]byte(`[{"pvmInstanceID": "pvm-1"}]`))
+		case "GET /pcloud/v1/cloud-instances/secondary/pvm-instances/pvm-1":
+			_, _ = w.Write([]byte(`{"pvmInstanceID": "pvm-1", "status": "ACTIVE"}`))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := &FailoverOptions{
+		SourceCloudInstanceID: "primary",
+		SourceCRN:             "crn:v1:bluemix:public:power-iaas:dal12:a/1:primary::",
+		TargetCloudInstanceID: "secondary",
+		VolumeGroupIDs:        []string{"vg-1"},
+		Instances: []FailoverInstance{{
+			ServerName:  "app-1",
+			BootVolume:  "aux_boot",
+			DataVolumes: []string{"aux_data"},
+			Memory:      4,
+			Processors:  1,
+			ProcType:    "shared",
+			Networks:    []PvmInstanceAddNetwork{{NetworkID: core.StringPtr("net-a"), IPAddress: core.StringPtr("10.0.0.5")}},
+		}},
+		NetworkMapping: map[string]string{"net-a": "net-b"},
+		LogPath:        filepath.Join(t.TempDir(), "failover.json"),
+	}
+
+	log, err := powervs.Failover(context.Background(), options)
+	if err == nil {
+		t.Fatal("Failover() expected onboarding error")
+	}
+	if step := log.Step("onboard-volumes"); step == nil || step.Status != RunbookStepFailed {
+		t.Fatalf("Failover() onboard-volumes step = %+v, error = %v", step, err)
+	}
+
+	onboardingFails = false
+	requests = nil
+	log, err = powervs.Failover(context.Background(), options)
+	if err != nil {
+		t.Fatalf("Failover() resume error = %v\n%s", err, log)
+	}
+	for _, r := range requests {
+		if r == "POST /pcloud/v1/cloud-instances/primary/volume-groups/vg-1/action" {
+			t.Error("Failover() resume stopped replication again")
+		}
+	}
+	wantSteps := []string{
+		"stop-replication:vg-1",
+		"collect-auxiliary-volumes:vg-1",
+		"onboard-volumes",
+		"wait-onboarding",
+		"create-instance:app-1",
+		"wait-instance:app-1",
+	}
+	var gotSteps []string
+	for _, s := range log.Steps {
+		gotSteps = append(gotSteps, s.Name)
+		if s.Status != RunbookStepCompleted {
+			t.Errorf("step %s status = %s", s.Name, s.Status)
+		}
+	}
+	if !reflect.DeepEqual(gotSteps, wantSteps) {
+		t.Errorf("Failover() steps = %v, want %v", gotSteps, wantSteps)
+	}
+	if instanceBody["imageID"] != "vol-boot" || !reflect.DeepEqual(instanceBody["volumeIDs"], []interface{}{"vol-data"}) {
+		t.Errorf("instance body = %v", instanceBody)
+	}
+	wantNetworks := []interface{}{map[string]interface{}{"networkID": "net-b", "ipAddress": "10.0.0.5"}}
+	if !reflect.DeepEqual(instanceBody["networks"], wantNetworks) {
+		t.Errorf("instance networks = %v, want %v", instanceBody["networks"], wantNetworks)
+	}
+
+	// The log of a run interrupted right after the instance creation does not record it
+	log.Step("create-instance:app-1").Status = RunbookStepFailed
+	log.Steps = log.Steps[:len(log.Steps)-1]
+	if err := log.WriteFile(options.LogPath); err != nil {
+		t.Fatal(err)
+	}
+	requests = nil
+	if log, err = powervs.Failover(context.Background(), options); err != nil {
+		t.Fatalf("Failover() resume error = %v\n%s", err, log)
+	}
+	for _, r := range requests {
+		if r == "POST /pcloud/v1/cloud-instances/secondary/pvm-instances" {
+			t.Error("Failover() resume created the instance again")
+		}
+	}
+	if step := log.Step("create-instance:app-1"); step.Status != RunbookStepCompleted || step.Outputs["pvmInstanceID"] != "pvm-1" {
+		t.Errorf("Failover() resumed create-instance step = %+v", step)
+	}
+
+	// A log is not resumed with other options
+	other := *options
+	other.VolumeGroupIDs = []string{"vg-2"}
+	requests = nil
+	if _, err := powervs.Failover(context.Background(), &other); err == nil || !strings.Contains(err.Error(), "was written for other failover options") || len(requests) > 2 {
+		t.Errorf("Failover() with other options error = %v, requests = %v", err, requests)
+	}
+
+	targetRegion = "lon06"
+	if _, err := powervs.Failover(context.Background(), options); err == nil || err.Error() != "workspace secondary in lon06 is not a replication site of dal12, use one of [dal12, wdc06]" {
+		t.Errorf("Failover() to a site that is not a replication partner error = %v", err)
+	}
+}
+
+func TestFailoverOptionsValidation(t *testing.T) {
+	powervs := &PowervsV1{}
+	tests := []struct {
+		name    string
+		options *FailoverOptions
+	}{
+		{"Nil options", nil},
+		{"Missing target", &FailoverOptions{SourceCloudInstanceID: "a", SourceCRN: "crn", VolumeGroupIDs: []string{"vg"}}},
+		{"Unmapped network", &FailoverOptions{
+			SourceCloudInstanceID: "a", SourceCRN: "crn", TargetCloudInstanceID: "b", VolumeGroupIDs: []string{"vg"},
+			Instances: []FailoverInstance{{ServerName: "s", BootVolume: "b", Memory: 2, Processors: 1, ProcType: "shared",
+				Networks: []PvmInstanceAddNetwork{{NetworkID: core.StringPtr("net")}}}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := powervs.Failover(context.Background(), tt.options); err == nil {
+				t.Error("Failover() expected validation error")
+			}
+		})
+	}
+}
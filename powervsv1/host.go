@@ -0,0 +1,427 @@
+package powervsv1
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// States of a dedicated host
+const (
+	HostStateUp          = "up"
+	HostStateDown        = "down"
+	HostStateMaintenance = "maintenance"
+)
+
+// Statuses of a dedicated host
+const (
+	HostStatusEnabled  = "enabled"
+	HostStatusDisabled = "disabled"
+)
+
+// DefaultHostReadyTimeout Time given to a host to come up before it is reported as failed
+const DefaultHostReadyTimeout = 30 * time.Minute
+
+// HostReservationOptions : The ReserveHosts options.
+type HostReservationOptions struct {
+	// System type of the hosts.
+	SysType string `validate:"required"`
+
+	// Number of hosts to reserve.
+	Count int `validate:"required,min=1"`
+
+	// Host group receiving the hosts, a new group named HostgroupName is created when empty.
+	HostgroupID string
+
+	// Name of the new host group.
+	HostgroupName string `validate:"required_without=HostgroupID"`
+
+	// Prefix of the host display names, followed by the host number. Defaults to the system type.
+	DisplayNamePrefix string
+
+	// Workspaces the new host group is shared with.
+	Secondaries []Secondary
+
+	// Wait until the hosts are up.
+	Wait bool
+
+	// Time given to each host to come up when waiting, DefaultHostReadyTimeout when 0.
+	WaitTimeout time.Duration
+}
+
+// HostReservation : Hosts reserved by ReserveHosts
+type HostReservation struct {
+	Hostgroup *Hostgroup
+
+	Hosts []Host
+}
+
+// HostInstance : Instance placed on a dedicated host
+type HostInstance struct {
+	PvmInstanceID string `json:"pvmInstanceID"`
+
+	ServerName string `json:"serverName"`
+
+	Processors float64 `json:"processors"`
+
+	Memory float64 `json:"memory"`
+}
+
+// HostCapacityView : Capacity of a dedicated host and the instances placed on it
+type HostCapacityView struct {
+	ID string `json:"id"`
+
+	DisplayName string `json:"displayName"`
+
+	SysType string `json:"sysType"`
+
+	Hostgroup string `json:"hostgroup"`
+
+	State string `json:"state"`
+
+	Status string `json:"status"`
+
+	TotalCore float64 `json:"totalCore"`
+
+	UsedCore float64 `json:"usedCore"`
+
+	ReservedCore float64 `json:"reservedCore"`
+
+	AvailableCore float64 `json:"availableCore"`
+
+	TotalMemory float64 `json:"totalMemory"`
+
+	UsedMemory float64 `json:"usedMemory"`
+
+	ReservedMemory float64 `json:"reservedMemory"`
+
+	AvailableMemory float64 `json:"availableMemory"`
+
+	Instances []HostInstance `json:"instances"`
+}
+
+// HostCapacityReport : Capacity view of the dedicated hosts of a workspace
+type HostCapacityReport struct {
+	Hosts []HostCapacityView `json:"hosts"`
+}
+
+// String : Table of the hosts followed by the instances placed on each
+func (report *HostCapacityReport) String() string {
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOST\tSYS TYPE\tSTATE\tCORES USED\tCORES RESERVED\tCORES AVAILABLE\tMEMORY USED\tMEMORY RESERVED\tMEMORY AVAILABLE\tINSTANCES")
+	for _, host := range report.Hosts {
+		fmt.Fprintf(tw, "%s (%s)\t%s\t%s\t%s/%s\t%s\t%s\t%s/%s\t%s\t%s\t%d\n", host.DisplayName, host.ID, host.SysType, host.State,
+			formatFloat64(&host.UsedCore), formatFloat64(&host.TotalCore), formatFloat64(&host.ReservedCore), formatFloat64(&host.AvailableCore),
+			formatFloat64(&host.UsedMemory), formatFloat64(&host.TotalMemory), formatFloat64(&host.ReservedMemory), formatFloat64(&host.AvailableMemory),
+			len(host.Instances))
+	}
+	tw.Flush()
+	for _, host := range report.Hosts {
+		for _, instance := range host.Instances {
+			fmt.Fprintf(&b, "  %s: %s (%s) %s cores, %sGB\n", host.DisplayName, instance.ServerName, instance.PvmInstanceID,
+				formatFloat64(&instance.Processors), formatFloat64(&instance.Memory))
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// ReserveHosts : Reserve dedicated hosts of a system type, in a new host group shared with the
+// secondary workspaces or in an existing host group. The available hosts are checked first so
+// that no host is reserved when the request cannot be satisfied.
+func (powervs *PowervsV1) ReserveHosts(ctx context.Context, options *HostReservationOptions) (*HostReservation, error) {
+	if err := core.ValidateNotNil(options, "options cannot be nil"); err != nil {
+		return nil, err
+	}
+	if err := core.ValidateStruct(options, "hostReservationOptions"); err != nil {
+		return nil, err
+	}
+
+	available, _, err := powervs.AvailableHostsWithContext(ctx, powervs.NewV1AvailableHostsOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list available hosts: %w", err)
+	}
+	if count := int64Value(available[options.SysType].Count); count < int64(options.Count) {
+		return nil, fmt.Errorf("%d %s hosts requested, %d available", options.Count, options.SysType, count)
+	}
+
+	prefix := options.DisplayNamePrefix
+	if prefix == "" {
+		prefix = options.SysType
+	}
+	hosts := make([]AddHost, options.Count)
+	for i := range hosts {
+		hosts[i] = AddHost{DisplayName: core.StringPtr(fmt.Sprintf("%s-%d", prefix, i+1)), SysType: core.StringPtr(options.SysType)}
+	}
+
+	reservation := &HostReservation{}
+	var hostIDs []string
+	if options.HostgroupID == "" {
+		group, _, err := powervs.HostgroupsPostWithContext(ctx, powervs.NewV1HostgroupsPostOptions(hosts, options.HostgroupName).SetSecondaries(options.Secondaries))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create host group %s: %w", options.HostgroupName, err)
+		}
+		reservation.Hostgroup = group
+		hostIDs = group.Hosts
+	} else {
+		for i := range hosts {
+			host, _, err := powervs.HostsPostWithContext(ctx, powervs.NewV1HostsPostOptions(&hosts[i], options.HostgroupID))
+			if err != nil {
+				return reservation, fmt.Errorf("failed to reserve host %s: %w", *hosts[i].DisplayName, err)
+			}
+			hostIDs = append(hostIDs, core.StringNilMapper(host.ID))
+		}
+		if reservation.Hostgroup, _, err = powervs.HostgroupsIDGetWithContext(ctx, powervs.NewV1HostgroupsIDGetOptions(options.HostgroupID)); err != nil {
+			return reservation, fmt.Errorf("failed to get host group %s: %w", options.HostgroupID, err)
+		}
+	}
+
+	for _, hostID := range hostIDs {
+		if options.Wait {
+			if err := powervs.waitForHostUsable(ctx, hostID, options.WaitTimeout); err != nil {
+				return reservation, err
+			}
+		}
+		host, _, err := powervs.HostsIDGetWithContext(ctx, powervs.NewV1HostsIDGetOptions(hostID))
+		if err != nil {
+			return reservation, fmt.Errorf("failed to get host %s: %w", hostID, err)
+		}
+		reservation.Hosts = append(reservation.Hosts, *host)
+	}
+	return reservation, nil
+}
+
+// ReleaseHost : Release a dedicated host that no longer runs instances. Host groups may be shared
+// with other workspaces, whose instances are not listed, so a host with used cores or memory is
+// kept too.
+func (powervs *PowervsV1) ReleaseHost(ctx context.Context, cloudInstanceID string, hostID string) error {
+	report, err := powervs.GetHostCapacityReport(ctx, cloudInstanceID)
+	if err != nil {
+		return err
+	}
+	for _, host := range report.Hosts {
+		if host.ID != hostID {
+			continue
+		}
+		if len(host.Instances) > 0 {
+			return fmt.Errorf("host %s still runs %d instances", hostID, len(host.Instances))
+		}
+		if host.UsedCore > 0 || host.UsedMemory > 0 {
+			return fmt.Errorf("host %s still uses %s cores and %sGB of memory, possibly for instances of other workspaces", hostID, formatFloat64(&host.UsedCore), formatFloat64(&host.UsedMemory))
+		}
+	}
+	if _, _, err := powervs.HostsIDDeleteWithContext(ctx, powervs.NewV1HostsIDDeleteOptions(hostID)); err != nil {
+		return fmt.Errorf("failed to release host %s: %w", hostID, err)
+	}
+	return nil
+}
+
+// ShareHostgroup : Share a host group with secondary workspaces, skipping those already sharing it
+func (powervs *PowervsV1) ShareHostgroup(ctx context.Context, hostgroupID string, secondaries ...Secondary) (*Hostgroup, error) {
+	group, _, err := powervs.HostgroupsIDGetWithContext(ctx, powervs.NewV1HostgroupsIDGetOptions(hostgroupID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get host group %s: %w", hostgroupID, err)
+	}
+	shared := map[string]bool{core.StringNilMapper(group.Primary): true}
+	for _, workspace := range group.Secondaries {
+		shared[workspace] = true
+	}
+	var add []Secondary
+	for _, secondary := range secondaries {
+		if !shared[core.StringNilMapper(secondary.Workspace)] {
+			add = append(add, secondary)
+		}
+	}
+	if len(add) == 0 {
+		return group, nil
+	}
+	group, _, err = powervs.HostgroupsIDPutWithContext(ctx, powervs.NewV1HostgroupsIDPutOptions(hostgroupID).SetAdd(add))
+	if err != nil {
+		return nil, fmt.Errorf("failed to share host group %s: %w", hostgroupID, err)
+	}
+	return group, nil
+}
+
+// UnshareHostgroup : Stop sharing a host group with a secondary workspace
+func (powervs *PowervsV1) UnshareHostgroup(ctx context.Context, hostgroupID string, workspace string) (*Hostgroup, error) {
+	group, _, err := powervs.HostgroupsIDPutWithContext(ctx, powervs.NewV1HostgroupsIDPutOptions(hostgroupID).SetRemove(workspace))
+	if err != nil {
+		return nil, fmt.Errorf("failed to stop sharing host group %s with %s: %w", hostgroupID, workspace, err)
+	}
+	return group, nil
+}
+
+// GetHostCapacityReport : Capacity of the dedicated hosts visible to a workspace with the
+// instances of the workspace placed on each, matched on the host ID the instances report
+func (powervs *PowervsV1) GetHostCapacityReport(ctx context.Context, cloudInstanceID string) (*HostCapacityReport, error) {
+	hosts, _, err := powervs.HostsGetWithContext(ctx, powervs.NewV1HostsGetOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hosts: %w", err)
+	}
+	instances, _, err := powervs.PcloudPvminstancesGetallWithContext(ctx, powervs.NewPcloudPvminstancesGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances: %w", err)
+	}
+	placed := map[string][]HostInstance{}
+	for _, instance := range instances.PvmInstances {
+		if instance.HostID == nil {
+			continue
+		}
+		hostID := strconv.FormatInt(*instance.HostID, 10)
+		placed[hostID] = append(placed[hostID], HostInstance{
+			PvmInstanceID: core.StringNilMapper(instance.PvmInstanceID),
+			ServerName:    core.StringNilMapper(instance.ServerName),
+			Processors:    floatValue(instance.Processors),
+			Memory:        floatValue(instance.Memory),
+		})
+	}
+
+	report := &HostCapacityReport{}
+	for _, host := range hosts {
+		view := HostCapacityView{
+			ID:          core.StringNilMapper(host.ID),
+			DisplayName: core.StringNilMapper(host.DisplayName),
+			SysType:     core.StringNilMapper(host.SysType),
+			Hostgroup:   core.StringNilMapper(host.Hostgroup),
+			State:       core.StringNilMapper(host.State),
+			Status:      core.StringNilMapper(host.Status),
+		}
+		if c := host.Capacity; c != nil {
+			view.TotalCore, view.UsedCore, view.ReservedCore, view.AvailableCore = floatValue(c.TotalCore), floatValue(c.UsedCore), floatValue(c.ReservedCore), floatValue(c.AvailableCore)
+			view.TotalMemory, view.UsedMemory, view.ReservedMemory, view.AvailableMemory = floatValue(c.TotalMemory), floatValue(c.UsedMemory), floatValue(c.ReservedMemory), floatValue(c.AvailableMemory)
+		}
+		view.Instances = placed[view.ID]
+		report.Hosts = append(report.Hosts, view)
+	}
+	sort.SliceStable(report.Hosts, func(i, j int) bool {
+		return report.Hosts[i].DisplayName < report.Hosts[j].DisplayName
+	})
+	return report, nil
+}
+
+// TargetHost : Deploy an instance on a dedicated host. Waits up to DefaultHostReadyTimeout until
+// the host is up, checks that it has the cores and memory of the instance and sets the deploy
+// target and system type.
+func (powervs *PowervsV1) TargetHost(ctx context.Context, options *PcloudPvminstancesPostOptions, hostID string) error {
+	if err := powervs.waitForHostUsable(ctx, hostID, DefaultHostReadyTimeout); err != nil {
+		return err
+	}
+	host, _, err := powervs.HostsIDGetWithContext(ctx, powervs.NewV1HostsIDGetOptions(hostID))
+	if err != nil {
+		return fmt.Errorf("failed to get host %s: %w", hostID, err)
+	}
+	if err := hostFits(host, options); err != nil {
+		return err
+	}
+	options.SetDeployTarget(hostID)
+	options.SetSysType(core.StringNilMapper(host.SysType))
+	return nil
+}
+
+// TargetHostgroup : Deploy an instance in a host group. Waits up to DefaultHostReadyTimeout until
+// one of its hosts is up with the cores and memory of the instance and sets the deploy target
+// and system type.
+func (powervs *PowervsV1) TargetHostgroup(ctx context.Context, options *PcloudPvminstancesPostOptions, hostgroupID string) error {
+	group, _, err := powervs.HostgroupsIDGetWithContext(ctx, powervs.NewV1HostgroupsIDGetOptions(hostgroupID))
+	if err != nil {
+		return fmt.Errorf("failed to get host group %s: %w", hostgroupID, err)
+	}
+	if len(group.Hosts) == 0 {
+		return fmt.Errorf("host group %s has no hosts", hostgroupID)
+	}
+	var sysType string
+	waitCtx, cancel := context.WithTimeout(ctx, DefaultHostReadyTimeout)
+	defer cancel()
+	err = waitFor(waitCtx, func() (bool, error) {
+		var reasons []string
+		pending := false
+		for _, hostID := range group.Hosts {
+			host, _, err := powervs.HostsIDGetWithContext(ctx, powervs.NewV1HostsIDGetOptions(hostID))
+			if err != nil {
+				return false, fmt.Errorf("failed to get host %s: %w", hostID, err)
+			}
+			usable, err := hostUsable(host)
+			if err == nil && !usable {
+				// The host may still come up
+				pending = true
+				continue
+			}
+			if err == nil {
+				err = hostFits(host, options)
+			}
+			if err == nil {
+				sysType = core.StringNilMapper(host.SysType)
+				return true, nil
+			}
+			reasons = append(reasons, err.Error())
+		}
+		if pending {
+			return false, nil
+		}
+		return false, fmt.Errorf("no host of host group %s can run the instance: %s", hostgroupID, strings.Join(reasons, "; "))
+	})
+	if err != nil && waitCtx.Err() != nil && ctx.Err() == nil {
+		return fmt.Errorf("no host of host group %s came up within %s", hostgroupID, DefaultHostReadyTimeout)
+	}
+	if err != nil {
+		return err
+	}
+	options.SetDeployTarget(hostgroupID)
+	options.SetSysType(sysType)
+	return nil
+}
+
+// hostFits Check that a host has the cores and memory of an instance
+func hostFits(host *Host, options *PcloudPvminstancesPostOptions) error {
+	var availableCore, availableMemory float64
+	if host.Capacity != nil {
+		availableCore, availableMemory = floatValue(host.Capacity.AvailableCore), floatValue(host.Capacity.AvailableMemory)
+	}
+	if processors := floatValue(options.Processors); processors > availableCore {
+		return fmt.Errorf("host %s has %s cores available, %s needed", core.StringNilMapper(host.ID), formatFloat64(&availableCore), formatFloat64(&processors))
+	}
+	if memory := floatValue(options.Memory); memory > availableMemory {
+		return fmt.Errorf("host %s has %sGB of memory available, %sGB needed", core.StringNilMapper(host.ID), formatFloat64(&availableMemory), formatFloat64(&memory))
+	}
+	return nil
+}
+
+// hostUsable Whether an enabled host is up, failing when it is disabled or in maintenance. Down
+// hosts, like new ones being provisioned, are not usable yet.
+func hostUsable(host *Host) (bool, error) {
+	if status := core.StringNilMapper(host.Status); strings.EqualFold(status, HostStatusDisabled) {
+		return false, fmt.Errorf("host %s is disabled", core.StringNilMapper(host.ID))
+	}
+	state := core.StringNilMapper(host.State)
+	if strings.EqualFold(state, HostStateMaintenance) {
+		return false, fmt.Errorf("host %s is in maintenance", core.StringNilMapper(host.ID))
+	}
+	return strings.EqualFold(state, HostStateUp), nil
+}
+
+// waitForHostUsable Wait until a host is up, failing if it is disabled or still down after the
+// timeout, DefaultHostReadyTimeout when 0
+func (powervs *PowervsV1) waitForHostUsable(ctx context.Context, hostID string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultHostReadyTimeout
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := waitFor(waitCtx, func() (bool, error) {
+		host, _, err := powervs.HostsIDGetWithContext(waitCtx, powervs.NewV1HostsIDGetOptions(hostID))
+		if err != nil {
+			return false, err
+		}
+		return hostUsable(host)
+	})
+	if err != nil && waitCtx.Err() != nil && ctx.Err() == nil {
+		return fmt.Errorf("host %s did not come up within %s", hostID, timeout)
+	}
+	return err
+}
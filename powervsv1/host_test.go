@@ -0,0 +1,201 @@
+package powervsv1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+func newFakeHostServer(t *testing.T) *PowervsV1 {
+	var mu sync.Mutex
+	capacity := func(available float64) *HostCapacity {
+		return &HostCapacity{TotalCore: core.Float64Ptr(20), UsedCore: core.Float64Ptr(20 - available), ReservedCore: core.Float64Ptr(0), AvailableCore: core.Float64Ptr(available),
+			TotalMemory: core.Float64Ptr(1024), UsedMemory: core.Float64Ptr(1024 - available*32), ReservedMemory: core.Float64Ptr(0), AvailableMemory: core.Float64Ptr(available * 32)}
+	}
+	hosts := map[string]*Host{
+		"7":  {ID: core.StringPtr("7"), DisplayName: core.StringPtr("s922-a"), SysType: core.StringPtr("s922"), Hostgroup: core.StringPtr("hg-1"), State: core.StringPtr("up"), Status: core.StringPtr("enabled"), Capacity: capacity(4)},
+		"8":  {ID: core.StringPtr("8"), DisplayName: core.StringPtr("s922-b"), SysType: core.StringPtr("s922"), Hostgroup: core.StringPtr("hg-1"), State: core.StringPtr("up"), Status: core.StringPtr("enabled"), Capacity: capacity(16)},
+		"9":  {ID: core.StringPtr("9"), DisplayName: core.StringPtr("s922-c"), SysType: core.StringPtr("s922"), Hostgroup: core.StringPtr("hg-3"), State: core.StringPtr("up"), Status: core.StringPtr("disabled"), Capacity: capacity(20)},
+		"10": {ID: core.StringPtr("10"), DisplayName: core.StringPtr("s922-d"), SysType: core.StringPtr("s922"), Hostgroup: core.StringPtr("hg-3"), State: core.StringPtr("down"), Status: core.StringPtr("enabled"), Capacity: capacity(20)},
+	}
+	groups := map[string]*Hostgroup{
+		"hg-1": {ID: core.StringPtr("hg-1"), Name: core.StringPtr("prod"), Hosts: []string{"7", "8"}, Primary: core.StringPtr("ws"), Secondaries: []string{"ws-2"}},
+		"hg-3": {ID: core.StringPtr("hg-3"), Name: core.StringPtr("broken"), Hosts: []string{"9"}, Primary: core.StringPtr("ws")},
+	}
+	// New hosts come up once they have been polled
+	polls := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		switch {
+		case r.URL.Path == "/v1/available-hosts":
+			_, _ = w.Write([]byte(`{"s922": {"count": 2, "sysType": "s922"}, "e980": {"count": 0, "sysType": "e980"}}`))
+		case r.URL.Path == "/pcloud/v1/cloud-instances/ws/pvm-instances":
+			_, _ = w.Write([]byte(`{"pvmInstances": [
+				{"pvmInstanceID": "pvm-1", "serverName": "db", "processors": 8, "memory": 256, "hostID": 7},
+				{"pvmInstanceID": "pvm-2", "serverName": "app", "processors": 8, "memory": 256, "hostID": 7},
+				{"pvmInstanceID": "pvm-3", "serverName": "web", "processors": 1, "memory": 8, "hostID": 100}]}`))
+		case parts[1] == "hosts" && len(parts) == 2:
+			all := []Host{}
+			for _, id := range []string{"8", "7"} {
+				all = append(all, *hosts[id])
+			}
+			_ = json.NewEncoder(w).Encode(all)
+		case parts[1] == "hosts" && r.Method == http.MethodGet:
+			host := hosts[parts[2]]
+			if polls[parts[2]]++; polls[parts[2]] > 1 && *host.State == "down" && strings.HasPrefix(parts[2], "new-") {
+				host.State = core.StringPtr("up")
+			}
+			_ = json.NewEncoder(w).Encode(host)
+		case parts[1] == "hostgroups" && r.Method == http.MethodPost:
+			body := V1HostgroupsPostOptions{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			group := &Hostgroup{ID: core.StringPtr("hg-2"), Name: body.Name, Primary: core.StringPtr("ws")}
+			for i, add := range body.Hosts {
+				id := fmt.Sprintf("new-%d", i+1)
+				hosts[id] = &Host{ID: core.StringPtr(id), DisplayName: add.DisplayName, SysType: add.SysType, Hostgroup: group.ID, State: core.StringPtr("down"), Status: core.StringPtr("enabled"), Capacity: capacity(20)}
+				group.Hosts = append(group.Hosts, id)
+			}
+			for _, secondary := range body.Secondaries {
+				group.Secondaries = append(group.Secondaries, *secondary.Workspace)
+			}
+			groups["hg-2"] = group
+			_ = json.NewEncoder(w).Encode(group)
+		case parts[1] == "hostgroups" && r.Method == http.MethodPut:
+			body := V1HostgroupsIDPutOptions{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			group := groups[parts[2]]
+			for _, secondary := range body.Add {
+				group.Secondaries = append(group.Secondaries, *secondary.Workspace)
+			}
+			_ = json.NewEncoder(w).Encode(group)
+		case parts[1] == "hostgroups":
+			_ = json.NewEncoder(w).Encode(groups[parts[2]])
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return powervs
+}
+
+func TestReserveHosts(t *testing.T) {
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = time.Millisecond
+	powervs := newFakeHostServer(t)
+
+	if _, err := powervs.ReserveHosts(context.Background(), &HostReservationOptions{SysType: "e980", Count: 1, HostgroupName: "big"}); err == nil || err.Error() != "1 e980 hosts requested, 0 available" {
+		t.Errorf("ReserveHosts() error = %v", err)
+	}
+	if _, err := powervs.ReserveHosts(context.Background(), &HostReservationOptions{SysType: "s922", Count: 1}); err == nil {
+		t.Error("ReserveHosts() expected error without host group")
+	}
+
+	reservation, err := powervs.ReserveHosts(context.Background(), &HostReservationOptions{
+		SysType: "s922", Count: 2, HostgroupName: "sap", Secondaries: []Secondary{{Workspace: core.StringPtr("ws-3")}}, Wait: true,
+	})
+	if err != nil {
+		t.Fatalf("ReserveHosts() error = %v", err)
+	}
+	if *reservation.Hostgroup.ID != "hg-2" || !reflect.DeepEqual(reservation.Hostgroup.Secondaries, []string{"ws-3"}) || len(reservation.Hosts) != 2 {
+		t.Fatalf("ReserveHosts() = %+v", reservation)
+	}
+	for _, host := range reservation.Hosts {
+		if *host.State != HostStateUp || !strings.HasPrefix(*host.DisplayName, "s922-") {
+			t.Errorf("ReserveHosts() host %s is %s", *host.DisplayName, *host.State)
+		}
+	}
+}
+
+func TestShareHostgroup(t *testing.T) {
+	powervs := newFakeHostServer(t)
+	group, err := powervs.ShareHostgroup(context.Background(), "hg-1", Secondary{Workspace: core.StringPtr("ws")}, Secondary{Workspace: core.StringPtr("ws-2")}, Secondary{Workspace: core.StringPtr("ws-4")})
+	if err != nil {
+		t.Fatalf("ShareHostgroup() error = %v", err)
+	}
+	if want := []string{"ws-2", "ws-4"}; !reflect.DeepEqual(group.Secondaries, want) {
+		t.Errorf("ShareHostgroup() secondaries = %v, want %v", group.Secondaries, want)
+	}
+}
+
+func TestGetHostCapacityReport(t *testing.T) {
+	powervs := newFakeHostServer(t)
+	report, err := powervs.GetHostCapacityReport(context.Background(), "ws")
+	if err != nil {
+		t.Fatalf("GetHostCapacityReport() error = %v", err)
+	}
+	want := `HOST        SYS TYPE  STATE  CORES USED  CORES RESERVED  CORES AVAILABLE  MEMORY USED  MEMORY RESERVED  MEMORY AVAILABLE  INSTANCES
+s922-a (7)  s922      up     16/20       0               4                896/1024     0                128               2
+s922-b (8)  s922      up     4/20        0               16               512/1024     0                512               0
+  s922-a: db (pvm-1) 8 cores, 256GB
+  s922-a: app (pvm-2) 8 cores, 256GB`
+	if got := report.String(); got != want {
+		t.Errorf("GetHostCapacityReport() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTargetHost(t *testing.T) {
+	powervs := newFakeHostServer(t)
+	options := powervs.NewPcloudPvminstancesPostOptions("ws", "img", 64, "dedicated", 2, "vm")
+	if err := powervs.TargetHost(context.Background(), options, "7"); err != nil || *options.DeployTarget != "7" || *options.SysType != "s922" {
+		t.Errorf("TargetHost() error = %v, options = %+v", err, options)
+	}
+	options = powervs.NewPcloudPvminstancesPostOptions("ws", "img", 64, "dedicated", 8, "vm")
+	if err := powervs.TargetHost(context.Background(), options, "7"); err == nil || err.Error() != "host 7 has 4 cores available, 8 needed" {
+		t.Errorf("TargetHost() error = %v", err)
+	}
+	if err := powervs.TargetHostgroup(context.Background(), options, "hg-1"); err != nil || *options.DeployTarget != "hg-1" {
+		t.Errorf("TargetHostgroup() error = %v", err)
+	}
+	options = powervs.NewPcloudPvminstancesPostOptions("ws", "img", 64, "dedicated", 18, "vm")
+	if err := powervs.TargetHostgroup(context.Background(), options, "hg-1"); err == nil {
+		t.Error("TargetHostgroup() expected error")
+	}
+	if err := powervs.TargetHost(context.Background(), options, "9"); err == nil || err.Error() != "host 9 is disabled" {
+		t.Errorf("TargetHost() of disabled host error = %v", err)
+	}
+	if err := powervs.TargetHostgroup(context.Background(), options, "hg-3"); err == nil || err.Error() != "no host of host group hg-3 can run the instance: host 9 is disabled" {
+		t.Errorf("TargetHostgroup() of disabled hosts error = %v", err)
+	}
+}
+
+func TestWaitForHostUsable(t *testing.T) {
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = time.Millisecond
+	powervs := newFakeHostServer(t)
+	if err := powervs.waitForHostUsable(context.Background(), "10", 20*time.Millisecond); err == nil || err.Error() != "host 10 did not come up within 20ms" {
+		t.Errorf("waitForHostUsable() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := powervs.waitForHostUsable(ctx, "10", time.Minute); err == nil || strings.Contains(err.Error(), "did not come up") {
+		t.Errorf("waitForHostUsable() of cancelled context error = %v", err)
+	}
+}
+
+func TestReleaseHost(t *testing.T) {
+	powervs := newFakeHostServer(t)
+	if err := powervs.ReleaseHost(context.Background(), "ws", "7"); err == nil || err.Error() != "host 7 still runs 2 instances" {
+		t.Errorf("ReleaseHost() error = %v", err)
+	}
+	// Host 8 runs no instance of the workspace but its cores are used by another one
+	if err := powervs.ReleaseHost(context.Background(), "ws", "8"); err == nil || err.Error() != "host 8 still uses 4 cores and 512GB of memory, possibly for instances of other workspaces" {
+		t.Errorf("ReleaseHost() error = %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package powervsv1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// Defaults of HydrateOptions
+const (
+	DefaultHydrateConcurrency = 8
+	DefaultHydrateMaxRetries  = 3
+)
+
+// HydrateOptions : Tuning of the bulk Get*Detailed helpers
+type HydrateOptions struct {
+	// Maximum number of requests in flight, defaults to DefaultHydrateConcurrency.
+	Concurrency int
+
+	// Minimum delay between the start of two requests, no limit when zero.
+	RequestInterval time.Duration
+
+	// Retries of a request rejected with 429 Too Many Requests, defaults to DefaultHydrateMaxRetries.
+	MaxRetries int
+}
+
+// HydrateError : Failure to fetch one item of a bulk request
+type HydrateError struct {
+	// Position of the item in the input references.
+	Index int
+
+	ID string
+
+	Err error
+}
+
+// HydrateErrors : Failures of a bulk request, ordered by index
+type HydrateErrors []HydrateError
+
+// Error : Summary of the failed items
+func (errs HydrateErrors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, e := range errs {
+		messages = append(messages, fmt.Sprintf("%s: %v", e.ID, e.Err))
+	}
+	return fmt.Sprintf("failed to fetch %d items: %s", len(errs), strings.Join(messages, "; "))
+}
+
+// GetPvmInstancesDetailed : Fetch the full PVM instances behind a list of references.
+// Results keep the order of refs; failed items are nil and reported in a HydrateErrors error.
+func (powervs *PowervsV1) GetPvmInstancesDetailed(ctx context.Context, cloudInstanceID string, refs []PvmInstanceReference, options *HydrateOptions) ([]*PvmInstance, error) {
+	results := make([]*PvmInstance, len(refs))
+	err := hydrate(ctx, len(refs), options, func(i int) (string, *core.DetailedResponse, error) {
+		id := core.StringNilMapper(refs[i].PvmInstanceID)
+		result, response, err := powervs.PcloudPvminstancesGetWithContext(ctx, powervs.NewPcloudPvminstancesGetOptions(cloudInstanceID, id))
+		results[i] = result
+		return id, response, err
+	})
+	return results, err
+}
+
+// GetVolumesDetailed : Fetch the full volumes behind a list of references.
+// Results keep the order of refs; failed items are nil and reported in a HydrateErrors error.
+func (powervs *PowervsV1) GetVolumesDetailed(ctx context.Context, cloudInstanceID string, refs []VolumeReference, options *HydrateOptions) ([]*Volume, error) {
+	results := make([]*Volume, len(refs))
+	err := hydrate(ctx, len(refs), options, func(i int) (string, *core.DetailedResponse, error) {
+		id := core.StringNilMapper(refs[i].VolumeID)
+		result, response, err := powervs.PcloudCloudinstancesVolumesGetWithContext(ctx, powervs.NewPcloudCloudinstancesVolumesGetOptions(cloudInstanceID, id))
+		results[i] = result
+		return id, response, err
+	})
+	return results, err
+}
+
+// GetNetworksDetailed : Fetch the full networks behind a list of references.
+// Results keep the order of refs; failed items are nil and reported in a HydrateErrors error.
+func (powervs *PowervsV1) GetNetworksDetailed(ctx context.Context, cloudInstanceID string, refs []NetworkReference, options *HydrateOptions) ([]*Network, error) {
+	results := make([]*Network, len(refs))
+	err := hydrate(ctx, len(refs), options, func(i int) (string, *core.DetailedResponse, error) {
+		id := core.StringNilMapper(refs[i].NetworkID)
+		result, response, err := powervs.PcloudNetworksGetWithContext(ctx, powervs.NewPcloudNetworksGetOptions(cloudInstanceID, id))
+		results[i] = result
+		return id, response, err
+	})
+	return results, err
+}
+
+// GetImagesDetailed : Fetch the full images behind a list of references.
+// Results keep the order of refs; failed items are nil and reported in a HydrateErrors error.
+func (powervs *PowervsV1) GetImagesDetailed(ctx context.Context, cloudInstanceID string, refs []ImageReference, options *HydrateOptions) ([]*Image, error) {
+	results := make([]*Image, len(refs))
+	err := hydrate(ctx, len(refs), options, func(i int) (string, *core.DetailedResponse, error) {
+		id := core.StringNilMapper(refs[i].ImageID)
+		result, response, err := powervs.PcloudCloudinstancesImagesGetWithContext(ctx, powervs.NewPcloudCloudinstancesImagesGetOptions(cloudInstanceID, id))
+		results[i] = result
+		return id, response, err
+	})
+	return results, err
+}
+
+// hydrate Call fetch for indexes 0 to n-1 with bounded concurrency and request rate.
+// Requests rejected with 429 pause every worker for the Retry-After delay before being retried.
+func hydrate(ctx context.Context, n int, options *HydrateOptions, fetch func(i int) (string, *core.DetailedResponse, error)) error {
+	if options == nil {
+		options = &HydrateOptions{}
+	}
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultHydrateConcurrency
+	}
+	maxRetries := options.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = DefaultHydrateMaxRetries
+	}
+
+	var (
+		mu        sync.Mutex
+		nextStart time.Time
+		errs      = make([]*HydrateError, n)
+	)
+	// throttle Wait for the request interval and any rate limit pause
+	throttle := func() error {
+		mu.Lock()
+		now := time.Now()
+		start := nextStart
+		if start.Before(now) {
+			start = now
+		}
+		nextStart = start.Add(options.RequestInterval)
+		mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Until(start)):
+			return nil
+		}
+	}
+	pause := func(d time.Duration) {
+		mu.Lock()
+		if until := time.Now().Add(d); until.After(nextStart) {
+			nextStart = until
+		}
+		mu.Unlock()
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				for attempt := 0; ; attempt++ {
+					if err := throttle(); err != nil {
+						errs[i] = &HydrateError{Index: i, Err: err}
+						break
+					}
+					id, response, err := fetch(i)
+					if err == nil {
+						break
+					}
+					if response != nil && response.StatusCode == http.StatusTooManyRequests && attempt < maxRetries {
+						pause(retryAfter(response, attempt))
+						continue
+					}
+					errs[i] = &HydrateError{Index: i, ID: id, Err: err}
+					break
+				}
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var failed HydrateErrors
+	for _, e := range errs {
+		if e != nil {
+			failed = append(failed, *e)
+		}
+	}
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
+}
+
+// retryAfter Delay requested by a 429 response, exponential from one second when absent
+func retryAfter(response *core.DetailedResponse, attempt int) time.Duration {
+	if value := response.GetHeaders().Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+		if t, err := http.ParseTime(value); err == nil {
+			return time.Until(t)
+		}
+	}
+	return time.Second << attempt
+}
@@ -0,0 +1,109 @@
+package powervsv1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+func TestGetPvmInstancesDetailed(t *testing.T) {
+	var (
+		inFlight, maxInFlight int32
+		mu                    sync.Mutex
+		throttled             = map[string]bool{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		w.Header().Set("Content-Type", "application/json")
+		mu.Lock()
+		first := !throttled[id]
+		throttled[id] = true
+		mu.Unlock()
+		switch {
+		case id == "pvm-3" && first:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"description": "slow down"}`)
+		case id == "pvm-5":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"description": "not found"}`)
+		default:
+			fmt.Fprintf(w, `{"pvmInstanceID": %q, "serverName": "srv-%s"}`, id, id)
+		}
+	}))
+	defer server.Close()
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var refs []PvmInstanceReference
+	for i := 0; i < 10; i++ {
+		refs = append(refs, PvmInstanceReference{PvmInstanceID: core.StringPtr(fmt.Sprintf("pvm-%d", i))})
+	}
+	instances, err := powervs.GetPvmInstancesDetailed(context.Background(), "ws", refs, &HydrateOptions{Concurrency: 3})
+
+	var errs HydrateErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Index != 5 || errs[0].ID != "pvm-5" {
+		t.Fatalf("GetPvmInstancesDetailed() error = %v", err)
+	}
+	if len(instances) != len(refs) {
+		t.Fatalf("GetPvmInstancesDetailed() returned %d results, want %d", len(instances), len(refs))
+	}
+	for i, instance := range instances {
+		if i == 5 {
+			if instance != nil {
+				t.Errorf("failed item = %v, want nil", instance)
+			}
+			continue
+		}
+		if instance == nil || *instance.PvmInstanceID != *refs[i].PvmInstanceID {
+			t.Errorf("result %d = %v, want %s", i, instance, *refs[i].PvmInstanceID)
+		}
+	}
+	if maxInFlight > 3 {
+		t.Errorf("max concurrent requests = %d, want <= 3", maxInFlight)
+	}
+}
+
+func TestHydrateRetryAfter(t *testing.T) {
+	header := func(value string) *core.DetailedResponse {
+		return &core.DetailedResponse{Headers: http.Header{"Retry-After": []string{value}}}
+	}
+	tests := []struct {
+		name     string
+		response *core.DetailedResponse
+		attempt  int
+		want     time.Duration
+	}{
+		{"seconds", header("7"), 0, 7 * time.Second},
+		{"missing", &core.DetailedResponse{}, 2, 4 * time.Second},
+		{"invalid", header("soon"), 0, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryAfter(tt.response, tt.attempt); got != tt.want {
+				t.Errorf("retryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
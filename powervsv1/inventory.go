@@ -0,0 +1,426 @@
+package powervsv1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"sigs.k8s.io/yaml"
+)
+
+// InventorySchemaVersion : Version of the document written by ExportInventory.
+// Bump it whenever a field is renamed or removed.
+const InventorySchemaVersion = "powervs.inventory/v1"
+
+// Formats supported by ExportInventory
+const (
+	InventoryFormatJSON = "json"
+	InventoryFormatYAML = "yaml"
+)
+
+// inventoryRedacted Placeholder written instead of secret values
+const inventoryRedacted = "REDACTED"
+
+// inventorySecretFields JSON fields whose values are never exported
+var inventorySecretFields = map[string]bool{
+	"accessToken":           true,
+	"cloudStorageSecretKey": true,
+	"credentials":           true,
+	"password":              true,
+	"presharedKey":          true,
+	"refreshToken":          true,
+	"secret":                true,
+	"secretKey":             true,
+	"userData":              true,
+}
+
+// WorkspaceInventory : Point-in-time snapshot of the resources of a workspace
+type WorkspaceInventory struct {
+	SchemaVersion string `json:"schemaVersion"`
+
+	CloudInstanceID string `json:"cloudInstanceID"`
+
+	TenantID string `json:"tenantID,omitempty"`
+
+	GeneratedAt time.Time `json:"generatedAt"`
+
+	Instances []InventoryInstance `json:"instances"`
+
+	Volumes []VolumeReference `json:"volumes"`
+
+	Networks []InventoryNetwork `json:"networks"`
+
+	Images []ImageReference `json:"images"`
+
+	Snapshots []Snapshot `json:"snapshots"`
+
+	SshKeys []SshKey `json:"sshKeys"`
+
+	PlacementGroups []PlacementGroup `json:"placementGroups"`
+
+	SharedProcessorPools []SharedProcessorPool `json:"sharedProcessorPools"`
+
+	VPNConnections []VPNConnection `json:"vpnConnections"`
+
+	IkePolicies []IkePolicy `json:"ikePolicies"`
+
+	IPSecPolicies []IPSecPolicy `json:"ipSecPolicies"`
+
+	CloudConnections []CloudConnection `json:"cloudConnections"`
+
+	DhcpServers []DhcpServer `json:"dhcpServers"`
+
+	VolumeGroups []VolumeGroup `json:"volumeGroups"`
+
+	StorageCapacity *StoragePoolsCapacity `json:"storageCapacity,omitempty"`
+}
+
+// InventoryInstance : PVM instance with its attached volumes
+type InventoryInstance struct {
+	PvmInstance
+
+	// Volumes attached to the instance, from the workspace volume list.
+	Volumes []VolumeReference `json:"volumes"`
+}
+
+// InventoryNetwork : Network with its ports
+type InventoryNetwork struct {
+	Network
+
+	Ports []NetworkPort `json:"ports"`
+}
+
+// GetWorkspaceInventory : Collect a sorted snapshot of the resources of a workspace
+func (powervs *PowervsV1) GetWorkspaceInventory(ctx context.Context, cloudInstanceID string) (*WorkspaceInventory, error) {
+	inventory := &WorkspaceInventory{
+		SchemaVersion:   InventorySchemaVersion,
+		CloudInstanceID: cloudInstanceID,
+		GeneratedAt:     time.Now().UTC().Truncate(time.Second),
+	}
+
+	cloudInstance, _, err := powervs.PcloudCloudinstancesGetWithContext(ctx, powervs.NewPcloudCloudinstancesGetOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace %s: %w", cloudInstanceID, err)
+	}
+	inventory.TenantID = core.StringNilMapper(cloudInstance.TenantID)
+
+	volumes, _, err := powervs.PcloudCloudinstancesVolumesGetallWithContext(ctx, powervs.NewPcloudCloudinstancesVolumesGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+	inventory.Volumes = volumes.Volumes
+	volumesByID := map[string]VolumeReference{}
+	for _, volume := range volumes.Volumes {
+		volumesByID[core.StringNilMapper(volume.VolumeID)] = volume
+	}
+
+	instanceRefs, _, err := powervs.PcloudPvminstancesGetallWithContext(ctx, powervs.NewPcloudPvminstancesGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pvm instances: %w", err)
+	}
+	instances, err := powervs.GetPvmInstancesDetailed(ctx, cloudInstanceID, instanceRefs.PvmInstances, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pvm instances: %w", err)
+	}
+	for _, instance := range instances {
+		item := InventoryInstance{PvmInstance: *instance, Volumes: []VolumeReference{}}
+		for _, id := range instance.VolumeIDs {
+			if volume, ok := volumesByID[id]; ok {
+				item.Volumes = append(item.Volumes, volume)
+			}
+		}
+		inventory.Instances = append(inventory.Instances, item)
+	}
+
+	networkRefs, _, err := powervs.PcloudNetworksGetallWithContext(ctx, powervs.NewPcloudNetworksGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+	networks, err := powervs.GetNetworksDetailed(ctx, cloudInstanceID, networkRefs.Networks, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get networks: %w", err)
+	}
+	for _, network := range networks {
+		ports, _, err := powervs.PcloudNetworksPortsGetallWithContext(ctx, powervs.NewPcloudNetworksPortsGetallOptions(cloudInstanceID, *network.NetworkID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list ports of network %s: %w", *network.NetworkID, err)
+		}
+		inventory.Networks = append(inventory.Networks, InventoryNetwork{Network: *network, Ports: ports.Ports})
+	}
+
+	images, _, err := powervs.PcloudCloudinstancesImagesGetallWithContext(ctx, powervs.NewPcloudCloudinstancesImagesGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	inventory.Images = images.Images
+
+	snapshots, _, err := powervs.PcloudCloudinstancesSnapshotsGetallWithContext(ctx, powervs.NewPcloudCloudinstancesSnapshotsGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	inventory.Snapshots = snapshots.Snapshots
+
+	if inventory.TenantID != "" {
+		sshKeys, _, err := powervs.PcloudTenantsSshkeysGetallWithContext(ctx, powervs.NewPcloudTenantsSshkeysGetallOptions(inventory.TenantID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list ssh keys: %w", err)
+		}
+		inventory.SshKeys = sshKeys.SshKeys
+	}
+
+	placementGroups, _, err := powervs.PcloudPlacementgroupsGetallWithContext(ctx, powervs.NewPcloudPlacementgroupsGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list placement groups: %w", err)
+	}
+	inventory.PlacementGroups = placementGroups.PlacementGroups
+
+	pools, _, err := powervs.PcloudSharedprocessorpoolsGetallWithContext(ctx, powervs.NewPcloudSharedprocessorpoolsGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shared processor pools: %w", err)
+	}
+	inventory.SharedProcessorPools = pools.SharedProcessorPools
+
+	vpnConnections, _, err := powervs.PcloudVpnconnectionsGetallWithContext(ctx, powervs.NewPcloudVpnconnectionsGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vpn connections: %w", err)
+	}
+	inventory.VPNConnections = vpnConnections.VPNConnections
+
+	ikePolicies, _, err := powervs.PcloudIkepoliciesGetallWithContext(ctx, powervs.NewPcloudIkepoliciesGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ike policies: %w", err)
+	}
+	inventory.IkePolicies = ikePolicies.IkePolicies
+
+	ipsecPolicies, _, err := powervs.PcloudIpsecpoliciesGetallWithContext(ctx, powervs.NewPcloudIpsecpoliciesGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ipsec policies: %w", err)
+	}
+	inventory.IPSecPolicies = ipsecPolicies.IPSecPolicies
+
+	cloudConnections, _, err := powervs.PcloudCloudconnectionsGetallWithContext(ctx, powervs.NewPcloudCloudconnectionsGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cloud connections: %w", err)
+	}
+	inventory.CloudConnections = cloudConnections.CloudConnections
+
+	dhcpServers, _, err := powervs.PcloudDhcpGetallWithContext(ctx, powervs.NewPcloudDhcpGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dhcp servers: %w", err)
+	}
+	inventory.DhcpServers = dhcpServers
+
+	volumeGroups, _, err := powervs.PcloudVolumegroupsGetallWithContext(ctx, powervs.NewPcloudVolumegroupsGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volume groups: %w", err)
+	}
+	inventory.VolumeGroups = volumeGroups.VolumeGroups
+
+	capacity, _, err := powervs.PcloudStoragecapacityPoolsGetallWithContext(ctx, powervs.NewPcloudStoragecapacityPoolsGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage capacity: %w", err)
+	}
+	inventory.StorageCapacity = capacity
+
+	inventory.Sort()
+	return inventory, nil
+}
+
+// ExportInventory : Write a snapshot of a workspace as a JSON or YAML document, with secrets redacted
+func (powervs *PowervsV1) ExportInventory(ctx context.Context, cloudInstanceID string, w io.Writer, format string) error {
+	inventory, err := powervs.GetWorkspaceInventory(ctx, cloudInstanceID)
+	if err != nil {
+		return err
+	}
+	return inventory.Write(w, format)
+}
+
+// Write : Encode the inventory as a JSON or YAML document, with secrets redacted.
+// Keys are sorted so that two snapshots of the same workspace only differ where it changed.
+func (inventory *WorkspaceInventory) Write(w io.Writer, format string) error {
+	data, err := inventory.Marshal(format)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Marshal : Encode the inventory as a JSON or YAML document, with secrets redacted
+func (inventory *WorkspaceInventory) Marshal(format string) ([]byte, error) {
+	document, err := inventory.document()
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(format) {
+	case InventoryFormatJSON, "":
+		data, err := json.MarshalIndent(document, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case InventoryFormatYAML, "yml":
+		return yaml.Marshal(document)
+	default:
+		return nil, fmt.Errorf("unsupported inventory format %q", format)
+	}
+}
+
+// document Generic JSON representation of the inventory, with secrets redacted
+func (inventory *WorkspaceInventory) document() (map[string]interface{}, error) {
+	raw, err := json.Marshal(inventory)
+	if err != nil {
+		return nil, err
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(raw, &document); err != nil {
+		return nil, err
+	}
+	redactInventory(document)
+	return document, nil
+}
+
+// ParseInventory : Decode a JSON or YAML inventory document
+func ParseInventory(data []byte) (*WorkspaceInventory, error) {
+	inventory := &WorkspaceInventory{}
+	if err := yaml.Unmarshal(data, inventory); err != nil {
+		return nil, fmt.Errorf("failed to parse inventory: %w", err)
+	}
+	if inventory.SchemaVersion != InventorySchemaVersion {
+		return nil, fmt.Errorf("unsupported inventory schema version %q, expected %q", inventory.SchemaVersion, InventorySchemaVersion)
+	}
+	return inventory, nil
+}
+
+// LoadInventory : Read a JSON or YAML inventory document from a file
+func LoadInventory(path string) (*WorkspaceInventory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseInventory(data)
+}
+
+// Sort : Order every resource list by ID, then by name, so that exports are stable
+func (inventory *WorkspaceInventory) Sort() {
+	for _, instance := range inventory.Instances {
+		sort.Strings(instance.VolumeIDs)
+		sort.Strings(instance.NetworkIDs)
+		volumes := instance.Volumes
+		sort.SliceStable(volumes, func(i, j int) bool {
+			return inventoryLess(volumes[i].VolumeID, volumes[i].Name, volumes[j].VolumeID, volumes[j].Name)
+		})
+	}
+	for _, network := range inventory.Networks {
+		ports := network.Ports
+		sort.SliceStable(ports, func(i, j int) bool {
+			return inventoryLess(ports[i].PortID, ports[i].IPAddress, ports[j].PortID, ports[j].IPAddress)
+		})
+	}
+
+	instances := inventory.Instances
+	sort.SliceStable(instances, func(i, j int) bool {
+		return inventoryLess(instances[i].PvmInstanceID, instances[i].ServerName, instances[j].PvmInstanceID, instances[j].ServerName)
+	})
+	volumes := inventory.Volumes
+	sort.SliceStable(volumes, func(i, j int) bool {
+		return inventoryLess(volumes[i].VolumeID, volumes[i].Name, volumes[j].VolumeID, volumes[j].Name)
+	})
+	networks := inventory.Networks
+	sort.SliceStable(networks, func(i, j int) bool {
+		return inventoryLess(networks[i].NetworkID, networks[i].Name, networks[j].NetworkID, networks[j].Name)
+	})
+	images := inventory.Images
+	sort.SliceStable(images, func(i, j int) bool {
+		return inventoryLess(images[i].ImageID, images[i].Name, images[j].ImageID, images[j].Name)
+	})
+	snapshots := inventory.Snapshots
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return inventoryLess(snapshots[i].SnapshotID, snapshots[i].Name, snapshots[j].SnapshotID, snapshots[j].Name)
+	})
+	sshKeys := inventory.SshKeys
+	sort.SliceStable(sshKeys, func(i, j int) bool {
+		return core.StringNilMapper(sshKeys[i].Name) < core.StringNilMapper(sshKeys[j].Name)
+	})
+	groups := inventory.PlacementGroups
+	sort.SliceStable(groups, func(i, j int) bool {
+		return inventoryLess(groups[i].ID, groups[i].Name, groups[j].ID, groups[j].Name)
+	})
+	pools := inventory.SharedProcessorPools
+	sort.SliceStable(pools, func(i, j int) bool {
+		return inventoryLess(pools[i].ID, pools[i].Name, pools[j].ID, pools[j].Name)
+	})
+	vpns := inventory.VPNConnections
+	sort.SliceStable(vpns, func(i, j int) bool {
+		return inventoryLess(vpns[i].ID, vpns[i].Name, vpns[j].ID, vpns[j].Name)
+	})
+	ikes := inventory.IkePolicies
+	sort.SliceStable(ikes, func(i, j int) bool {
+		return inventoryLess(ikes[i].ID, ikes[i].Name, ikes[j].ID, ikes[j].Name)
+	})
+	ipsecs := inventory.IPSecPolicies
+	sort.SliceStable(ipsecs, func(i, j int) bool {
+		return inventoryLess(ipsecs[i].ID, ipsecs[i].Name, ipsecs[j].ID, ipsecs[j].Name)
+	})
+	connections := inventory.CloudConnections
+	sort.SliceStable(connections, func(i, j int) bool {
+		return inventoryLess(connections[i].CloudConnectionID, connections[i].Name, connections[j].CloudConnectionID, connections[j].Name)
+	})
+	dhcps := inventory.DhcpServers
+	sort.SliceStable(dhcps, func(i, j int) bool {
+		return inventoryLess(dhcps[i].ID, dhcpServerNetworkName(dhcps[i]), dhcps[j].ID, dhcpServerNetworkName(dhcps[j]))
+	})
+	volumeGroups := inventory.VolumeGroups
+	sort.SliceStable(volumeGroups, func(i, j int) bool {
+		return inventoryLess(volumeGroups[i].ID, volumeGroups[i].Name, volumeGroups[j].ID, volumeGroups[j].Name)
+	})
+	if inventory.StorageCapacity != nil {
+		capacity := inventory.StorageCapacity.StoragePoolsCapacity
+		sort.SliceStable(capacity, func(i, j int) bool {
+			return core.StringNilMapper(capacity[i].PoolName) < core.StringNilMapper(capacity[j].PoolName)
+		})
+	}
+}
+
+// inventoryLess Order resources by ID, and by name when they share an ID or have none
+func inventoryLess(id1 *string, name1 *string, id2 *string, name2 *string) bool {
+	if core.StringNilMapper(id1) != core.StringNilMapper(id2) {
+		return core.StringNilMapper(id1) < core.StringNilMapper(id2)
+	}
+	return core.StringNilMapper(name1) < core.StringNilMapper(name2)
+}
+
+// dhcpServerNetworkName Name of the network of a DHCP server, nil when it is not reported
+func dhcpServerNetworkName(server DhcpServer) *string {
+	if server.Network == nil {
+		return nil
+	}
+	return server.Network.Name
+}
+
+// redactInventory Replace the values of secret fields in a decoded JSON document
+func redactInventory(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if inventorySecretFields[key] {
+				if item != nil {
+					v[key] = inventoryRedacted
+				}
+				continue
+			}
+			redactInventory(item)
+		}
+	case []interface{}:
+		for _, item := range v {
+			redactInventory(item)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package powervsv1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Actions of an InventoryChange
+const (
+	InventoryChangeAdded    = "added"
+	InventoryChangeRemoved  = "removed"
+	InventoryChangeModified = "modified"
+)
+
+// Formats supported by InventoryDiff.Write
+const (
+	InventoryDiffFormatText     = "text"
+	InventoryDiffFormatJSON     = "json"
+	InventoryDiffFormatMarkdown = "markdown"
+)
+
+// inventoryCollection Resource list of an inventory document compared item by item
+type inventoryCollection struct {
+	// Field of the inventory document holding the list.
+	field string
+	// Resource type reported in changes.
+	resourceType string
+	// Field identifying an item across snapshots.
+	idField string
+	// Field naming an item in reports.
+	nameField string
+}
+
+// inventoryCollections Compared collections, in report order
+var inventoryCollections = []inventoryCollection{
+	{"instances", "pvmInstance", "pvmInstanceID", "serverName"},
+	{"volumes", "volume", "volumeID", "name"},
+	{"networks", "network", "networkID", "name"},
+	{"images", "image", "imageID", "name"},
+	{"snapshots", "snapshot", "snapshotID", "name"},
+	{"sshKeys", "sshKey", "name", "name"},
+	{"placementGroups", "placementGroup", "id", "name"},
+	{"sharedProcessorPools", "sharedProcessorPool", "id", "name"},
+	{"vpnConnections", "vpnConnection", "id", "name"},
+	{"ikePolicies", "ikePolicy", "id", "name"},
+	{"ipSecPolicies", "ipSecPolicy", "id", "name"},
+	{"cloudConnections", "cloudConnection", "cloudConnectionID", "name"},
+	{"dhcpServers", "dhcpServer", "id", "id"},
+	{"volumeGroups", "volumeGroup", "id", "name"},
+	{"storageCapacity.storagePoolsCapacity", "storagePool", "poolName", "poolName"},
+}
+
+// inventoryIgnoredFields Fields that change without any action on the workspace, or
+// duplicate another collection, by resource type
+var inventoryIgnoredFields = map[string][]string{
+	"pvmInstance": {"updatedDate", "progress", "volumes"},
+	"volume":      {"lastUpdateDate"},
+	"image":       {"lastUpdateDate"},
+	"snapshot":    {"lastUpdateDate", "percentComplete"},
+	"network":     {"ipAddressMetrics"},
+}
+
+// InventoryFieldChange : Value of a field in both snapshots
+type InventoryFieldChange struct {
+	// Dotted path of the field, list items are indexed as in "networks[0].ipAddress".
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// InventoryChange : Difference on one resource between two snapshots
+type InventoryChange struct {
+	ResourceType string `json:"resourceType"`
+
+	ID string `json:"id"`
+
+	Name string `json:"name"`
+
+	Action string `json:"action"`
+
+	// Changed fields, only set for modified resources.
+	Fields []InventoryFieldChange `json:"fields,omitempty"`
+}
+
+// String : Human readable rendering of the change
+func (change InventoryChange) String() string {
+	symbol := map[string]string{
+		InventoryChangeAdded:    "+",
+		InventoryChangeRemoved:  "-",
+		InventoryChangeModified: "~",
+	}[change.Action]
+	s := fmt.Sprintf("%s %s %q (%s)", symbol, change.ResourceType, change.Name, change.ID)
+	for _, f := range change.Fields {
+		s += fmt.Sprintf("\n    %s: %q => %q", f.Field, f.Old, f.New)
+	}
+	return s
+}
+
+// InventoryDiff : Changes between two inventory snapshots of a workspace
+type InventoryDiff struct {
+	CloudInstanceID string `json:"cloudInstanceID"`
+
+	// Generation times of the compared snapshots.
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	Changes []InventoryChange `json:"changes"`
+}
+
+// DiffInventories : Compare two inventory snapshots resource by resource and field by field
+func DiffInventories(from *WorkspaceInventory, to *WorkspaceInventory) (*InventoryDiff, error) {
+	fromDocument, err := from.document()
+	if err != nil {
+		return nil, err
+	}
+	toDocument, err := to.document()
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &InventoryDiff{
+		CloudInstanceID: to.CloudInstanceID,
+		From:            from.GeneratedAt.Format(time.RFC3339),
+		To:              to.GeneratedAt.Format(time.RFC3339),
+		Changes:         []InventoryChange{},
+	}
+	for _, collection := range inventoryCollections {
+		diff.Changes = append(diff.Changes, collection.diff(fromDocument, toDocument)...)
+	}
+	return diff, nil
+}
+
+// DiffInventoryWithLive : Compare an inventory snapshot with the current state of its workspace
+func (powervs *PowervsV1) DiffInventoryWithLive(ctx context.Context, from *WorkspaceInventory) (*InventoryDiff, error) {
+	to, err := powervs.GetWorkspaceInventory(ctx, from.CloudInstanceID)
+	if err != nil {
+		return nil, err
+	}
+	return DiffInventories(from, to)
+}
+
+// HasChanges : Whether the snapshots differ
+func (diff *InventoryDiff) HasChanges() bool {
+	return len(diff.Changes) > 0
+}
+
+// String : Human readable rendering of the diff
+func (diff *InventoryDiff) String() string {
+	if len(diff.Changes) == 0 {
+		return "No changes."
+	}
+	lines := make([]string, 0, len(diff.Changes))
+	for _, c := range diff.Changes {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Markdown : Rendering of the diff as a Markdown change report
+func (diff *InventoryDiff) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Changes in workspace %s\n\n", diff.CloudInstanceID)
+	fmt.Fprintf(&b, "From %s to %s.\n\n", diff.From, diff.To)
+	if len(diff.Changes) == 0 {
+		b.WriteString("No changes.\n")
+		return b.String()
+	}
+	b.WriteString("| Resource | Name | ID | Change | Field | Old | New |\n")
+	b.WriteString("|---|---|---|---|---|---|---|\n")
+	for _, c := range diff.Changes {
+		if len(c.Fields) == 0 {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | | | |\n", c.ResourceType, markdownCell(c.Name), markdownCell(c.ID), c.Action)
+			continue
+		}
+		for _, f := range c.Fields {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s |\n", c.ResourceType, markdownCell(c.Name), markdownCell(c.ID), c.Action,
+				markdownCell(f.Field), markdownCell(f.Old), markdownCell(f.New))
+		}
+	}
+	return b.String()
+}
+
+// Write : Render the diff as text, JSON or Markdown
+func (diff *InventoryDiff) Write(w io.Writer, format string) error {
+	var data []byte
+	switch strings.ToLower(format) {
+	case InventoryDiffFormatText, "":
+		data = []byte(diff.String() + "\n")
+	case InventoryDiffFormatJSON:
+		raw, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(raw, '\n')
+	case InventoryDiffFormatMarkdown, "md":
+		data = []byte(diff.Markdown())
+	default:
+		return fmt.Errorf("unsupported diff format %q", format)
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// diff Changes of the collection between two inventory documents
+func (collection inventoryCollection) diff(from map[string]interface{}, to map[string]interface{}) (changes []InventoryChange) {
+	fromItems, order := collection.items(from, nil)
+	toItems, order := collection.items(to, order)
+	sort.Strings(order)
+
+	for _, key := range order {
+		before, inFrom := fromItems[key]
+		after, inTo := toItems[key]
+		change := InventoryChange{ResourceType: collection.resourceType}
+		switch {
+		case !inFrom:
+			change.Action = InventoryChangeAdded
+			change.ID = inventoryValue(after[collection.idField])
+			change.Name = inventoryValue(after[collection.nameField])
+		case !inTo:
+			change.Action = InventoryChangeRemoved
+			change.ID = inventoryValue(before[collection.idField])
+			change.Name = inventoryValue(before[collection.nameField])
+		default:
+			change.Action = InventoryChangeModified
+			change.ID = inventoryValue(after[collection.idField])
+			change.Name = inventoryValue(after[collection.nameField])
+			change.Fields = collection.diffFields(before, after)
+			if len(change.Fields) == 0 {
+				continue
+			}
+		}
+		changes = append(changes, change)
+	}
+	return
+}
+
+// items Index the collection of a document by ID, appending keys not yet seen to order.
+// Items without an ID are keyed by name, and items sharing a key are told apart by their rank among them.
+func (collection inventoryCollection) items(document map[string]interface{}, order []string) (map[string]map[string]interface{}, []string) {
+	var list interface{} = document
+	for _, key := range strings.Split(collection.field, ".") {
+		object, _ := list.(map[string]interface{})
+		list = object[key]
+	}
+	items := map[string]map[string]interface{}{}
+	seen := map[string]bool{}
+	for _, key := range order {
+		seen[key] = true
+	}
+	ranks := map[string]int{}
+	values, _ := list.([]interface{})
+	for _, value := range values {
+		item, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key := "id:" + inventoryValue(item[collection.idField])
+		if key == "id:" {
+			key = "name:" + inventoryValue(item[collection.nameField])
+		}
+		ranks[key]++
+		if ranks[key] > 1 {
+			key = fmt.Sprintf("%s#%d", key, ranks[key])
+		}
+		items[key] = item
+		if !seen[key] {
+			seen[key] = true
+			order = append(order, key)
+		}
+	}
+	return items, order
+}
+
+// diffFields Changed leaf fields between two versions of an item
+func (collection inventoryCollection) diffFields(before map[string]interface{}, after map[string]interface{}) []InventoryFieldChange {
+	ignored := map[string]bool{}
+	for _, field := range inventoryIgnoredFields[collection.resourceType] {
+		ignored[field] = true
+	}
+	oldLeaves := map[string]string{}
+	flattenInventoryValue("", before, ignored, oldLeaves)
+	newLeaves := map[string]string{}
+	flattenInventoryValue("", after, ignored, newLeaves)
+
+	fields := map[string]bool{}
+	for field := range oldLeaves {
+		fields[field] = true
+	}
+	for field := range newLeaves {
+		fields[field] = true
+	}
+	var changes []InventoryFieldChange
+	for field := range fields {
+		if oldLeaves[field] != newLeaves[field] {
+			changes = append(changes, InventoryFieldChange{Field: field, Old: oldLeaves[field], New: newLeaves[field]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// flattenInventoryValue Collect the leaf values of a decoded JSON value by dotted path.
+// Lists of scalars are kept whole so that reordering them stays a single change.
+func flattenInventoryValue(path string, value interface{}, ignored map[string]bool, leaves map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if path == "" && ignored[key] {
+				continue
+			}
+			field := key
+			if path != "" {
+				field = path + "." + key
+			}
+			flattenInventoryValue(field, item, ignored, leaves)
+		}
+	case []interface{}:
+		scalars := make([]string, 0, len(v))
+		for i, item := range v {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				flattenInventoryValue(fmt.Sprintf("%s[%d]", path, i), item, ignored, leaves)
+			default:
+				scalars = append(scalars, inventoryValue(item))
+			}
+		}
+		if len(scalars) > 0 {
+			leaves[path] = "[" + strings.Join(scalars, ", ") + "]"
+		}
+	case nil:
+	default:
+		leaves[path] = inventoryValue(v)
+	}
+}
+
+// inventoryValue String form of a decoded JSON scalar
+func inventoryValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// markdownCell Escape a value for a Markdown table cell
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
@@ -0,0 +1,117 @@
+package powervsv1
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+func TestDiffInventories(t *testing.T) {
+	before, err := ParseInventory([]byte(`{"schemaVersion": "powervs.inventory/v1", "cloudInstanceID": "ws",
+		"instances": [{"pvmInstanceID": "pvm-1", "serverName": "app", "memory": 8, "progress": 0}, {"pvmInstanceID": "pvm-2", "serverName": "db", "memory": 4}],
+		"volumes": [{"volumeID": "vol-1", "name": "boot", "size": 20}],
+		"networks": [{"networkID": "net-1", "name": "private", "dnsServers": ["9.9.9.9"], "ipAddressMetrics": {"used": 3}}],
+		"sshKeys": [{"name": "ops", "sshKey": "ssh-rsa AAAA"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := ParseInventory([]byte(`{"schemaVersion": "powervs.inventory/v1", "cloudInstanceID": "ws",
+		"instances": [{"pvmInstanceID": "pvm-1", "serverName": "app", "memory": 8, "progress": 50}, {"pvmInstanceID": "pvm-2", "serverName": "db", "memory": 16}],
+		"volumes": [{"volumeID": "vol-1", "name": "boot", "size": 20}, {"volumeID": "vol-4", "name": "logs"}],
+		"networks": [{"networkID": "net-1", "name": "private", "dnsServers": ["1.1.1.1", "9.9.9.9"], "ipAddressMetrics": {"used": 53}}],
+		"sshKeys": []}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := DiffInventories(before, after)
+	if err != nil {
+		t.Fatalf("DiffInventories() error = %v", err)
+	}
+	want := []InventoryChange{
+		{ResourceType: "pvmInstance", ID: "pvm-2", Name: "db", Action: InventoryChangeModified, Fields: []InventoryFieldChange{{Field: "memory", Old: "4", New: "16"}}},
+		{ResourceType: "volume", ID: "vol-4", Name: "logs", Action: InventoryChangeAdded},
+		{ResourceType: "network", ID: "net-1", Name: "private", Action: InventoryChangeModified, Fields: []InventoryFieldChange{{Field: "dnsServers", Old: "[9.9.9.9]", New: "[1.1.1.1, 9.9.9.9]"}}},
+		{ResourceType: "sshKey", ID: "ops", Name: "ops", Action: InventoryChangeRemoved},
+	}
+	if !reflect.DeepEqual(diff.Changes, want) {
+		t.Errorf("DiffInventories() =\n%s", diff)
+	}
+
+	same, _ := DiffInventories(before, before)
+	if same.HasChanges() || same.String() != "No changes." {
+		t.Errorf("DiffInventories() of identical snapshots = %s", same)
+	}
+}
+
+func TestInventoryDiffWrite(t *testing.T) {
+	diff := &InventoryDiff{
+		CloudInstanceID: "ws",
+		From:            "2024-03-01T00:00:00Z",
+		To:              "2024-03-02T00:00:00Z",
+		Changes: []InventoryChange{
+			{ResourceType: "pvmInstance", ID: "pvm-1", Name: "app", Action: InventoryChangeModified, Fields: []InventoryFieldChange{{Field: "memory", Old: "8", New: "16"}}},
+			{ResourceType: "volume", ID: "vol-4", Name: "a|b", Action: InventoryChangeAdded},
+		},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{InventoryDiffFormatText, "~ pvmInstance \"app\" (pvm-1)\n    memory: \"8\" => \"16\"\n+ volume \"a|b\" (vol-4)\n"},
+		{InventoryDiffFormatMarkdown, "| pvmInstance | app | pvm-1 | modified | memory | 8 | 16 |\n| volume | a\\|b | vol-4 | added | | | |\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := diff.Write(&buf, tt.format); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasSuffix(buf.String(), tt.want) {
+				t.Errorf("Write() =\n%s\nwant suffix\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := diff.Write(&buf, InventoryDiffFormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded InventoryDiff
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || !reflect.DeepEqual(&decoded, diff) {
+		t.Errorf("Write() JSON = %s", buf.String())
+	}
+	if err := diff.Write(&buf, "html"); err == nil {
+		t.Error("Write() expected error for unsupported format")
+	}
+}
+
+func TestDiffInventoriesWithoutIDs(t *testing.T) {
+	before := &WorkspaceInventory{Volumes: []VolumeReference{
+		{Name: core.StringPtr("data"), Size: core.Float64Ptr(10)},
+		{Name: core.StringPtr("logs"), Size: core.Float64Ptr(20)},
+		{Size: core.Float64Ptr(30)},
+		{Size: core.Float64Ptr(40)},
+	}}
+	after := &WorkspaceInventory{Volumes: []VolumeReference{
+		{Name: core.StringPtr("data"), Size: core.Float64Ptr(15)},
+		{Size: core.Float64Ptr(30)},
+		{Size: core.Float64Ptr(40)},
+	}}
+
+	diff, err := DiffInventories(before, after)
+	if err != nil {
+		t.Fatalf("DiffInventories() error = %v", err)
+	}
+	want := []InventoryChange{
+		{ResourceType: "volume", ID: "", Name: "data", Action: InventoryChangeModified, Fields: []InventoryFieldChange{{Field: "size", Old: "10", New: "15"}}},
+		{ResourceType: "volume", ID: "", Name: "logs", Action: InventoryChangeRemoved},
+	}
+	if !reflect.DeepEqual(diff.Changes, want) {
+		t.Errorf("DiffInventories() =\n%s", diff)
+	}
+}
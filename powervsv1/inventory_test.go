@@ -0,0 +1,149 @@
+package powervsv1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// fakeInventoryRoutes Responses of the endpoints read by GetWorkspaceInventory, by path below /pcloud/v1
+var fakeInventoryRoutes = map[string]string{
+	"/cloud-instances/ws": `{"cloudInstanceID": "ws", "tenantID": "tenant", "enabled": true, "initialized": true, "name": "ws", "openstackID": "o", "region": "dal", "capabilities": [],
+		"limits": {}, "usage": {}, "pvmInstances": []}`,
+	"/cloud-instances/ws/volumes": `{"volumes": [
+		{"volumeID": "vol-2", "name": "data", "size": 100, "state": "in-use", "diskType": "tier1", "bootable": false, "shareable": false, "href": "h", "wwn": "w", "creationDate": "2024-01-01T00:00:00.000Z", "lastUpdateDate": "2024-01-01T00:00:00.000Z", "pvmInstanceIDs": ["pvm-1"]},
+		{"volumeID": "vol-1", "name": "boot", "size": 20, "state": "in-use", "diskType": "tier1", "bootable": true, "bootVolume": true, "shareable": false, "href": "h", "wwn": "w", "creationDate": "2024-01-01T00:00:00.000Z", "lastUpdateDate": "2024-01-01T00:00:00.000Z", "pvmInstanceIDs": ["pvm-1"]},
+		{"volumeID": "vol-3", "name": "spare", "size": 10, "state": "available", "diskType": "tier3", "bootable": false, "shareable": true, "href": "h", "wwn": "w", "creationDate": "2024-01-01T00:00:00.000Z", "lastUpdateDate": "2024-01-01T00:00:00.000Z"}]}`,
+	"/cloud-instances/ws/pvm-instances": `{"pvmInstances": [
+		{"pvmInstanceID": "pvm-2", "serverName": "db", "href": "h", "status": "SHUTOFF", "sysType": "s922", "memory": 4, "processors": 1, "procType": "dedicated", "diskSize": 0, "imageID": "img-1", "networks": []},
+		{"pvmInstanceID": "pvm-1", "serverName": "app", "href": "h", "status": "ACTIVE", "sysType": "s922", "memory": 8, "processors": 0.5, "procType": "shared", "diskSize": 120, "imageID": "img-1", "networks": []}]}`,
+	"/cloud-instances/ws/pvm-instances/pvm-1": `{"pvmInstanceID": "pvm-1", "serverName": "app", "status": "ACTIVE", "sysType": "s922", "memory": 8, "processors": 0.5, "procType": "shared", "diskSize": 120,
+		"imageID": "img-1", "osType": "aix", "storageType": "tier1", "volumeIDs": ["vol-2", "vol-1"], "networkIDs": ["net-1"], "placementGroup": "pg-1",
+		"networks": [{"networkID": "net-1", "networkName": "private", "ipAddress": "10.0.0.10", "macAddress": "fa:16:3e:00:00:01", "type": "fixed"}]}`,
+	"/cloud-instances/ws/pvm-instances/pvm-2": `{"pvmInstanceID": "pvm-2", "serverName": "db", "status": "SHUTOFF", "sysType": "s922", "memory": 4, "processors": 1, "procType": "dedicated", "diskSize": 0,
+		"imageID": "img-1", "osType": "aix", "storageType": "tier1", "volumeIDs": [], "networkIDs": [], "networks": []}`,
+	"/cloud-instances/ws/networks": `{"networks": [{"networkID": "net-1", "name": "private", "type": "vlan", "vlanID": 10, "href": "h"}]}`,
+	"/cloud-instances/ws/networks/net-1": `{"networkID": "net-1", "name": "private", "type": "vlan", "vlanID": 10, "cidr": "10.0.0.0/24", "gateway": "10.0.0.1",
+		"dnsServers": ["9.9.9.9"], "ipAddressMetrics": {"available": 250, "used": 3, "total": 253, "utilization": 1}, "ipAddressRanges": [{"startingIPAddress": "10.0.0.2", "endingIPAddress": "10.0.0.254"}]}`,
+	"/cloud-instances/ws/networks/net-1/ports": `{"ports": [
+		{"portID": "port-2", "ipAddress": "10.0.0.11", "macAddress": "fa:16:3e:00:00:02", "status": "DOWN", "description": ""},
+		{"portID": "port-1", "ipAddress": "10.0.0.10", "macAddress": "fa:16:3e:00:00:01", "status": "ACTIVE", "description": "", "pvmInstance": {"pvmInstanceID": "pvm-1", "href": "h"}}]}`,
+	"/cloud-instances/ws/images":                         `{"images": [{"imageID": "img-1", "name": "aix-7.3", "href": "h", "state": "active", "storageType": "tier1", "storagePool": "p1", "creationDate": "2024-01-01T00:00:00.000Z", "lastUpdateDate": "2024-01-01T00:00:00.000Z", "specifications": {"operatingSystem": "aix"}}]}`,
+	"/cloud-instances/ws/snapshots":                      `{"snapshots": [{"snapshotID": "snap-1", "name": "before-patch", "pvmInstanceID": "pvm-1", "volumeSnapshots": {"vol-1": "vs-1"}}]}`,
+	"/tenants/tenant/sshkeys":                            `{"sshKeys": [{"name": "ops", "sshKey": "ssh-rsa AAAA"}]}`,
+	"/cloud-instances/ws/placement-groups":               `{"placementGroups": [{"id": "pg-1", "name": "ha", "policy": "anti-affinity", "members": ["pvm-1"]}]}`,
+	"/cloud-instances/ws/shared-processor-pools":         `{"sharedProcessorPools": [{"id": "spp-1", "name": "pool", "allocatedCores": 2, "availableCores": 1, "hostID": 3, "reservedCores": 2}]}`,
+	"/cloud-instances/ws/vpn/vpn-connections":            `{"vpnConnections": [{"id": "vpn-1", "name": "office", "mode": "policy", "status": "active", "localGatewayAddress": "1.1.1.1", "peerGatewayAddress": "2.2.2.2", "vpnGatewayAddress": "3.3.3.3", "networkIDs": ["net-1"], "peerSubnets": ["192.168.0.0/24"], "deadPeerDetection": {"action": "restart", "interval": 30, "threshold": 3}, "ikePolicy": {"id": "ike-1", "name": "ike", "href": "h"}, "ipSecPolicy": {"id": "ipsec-1", "name": "ipsec", "href": "h"}}]}`,
+	"/cloud-instances/ws/vpn/ike-policies":               `{"ikePolicies": [{"id": "ike-1", "name": "ike", "authentication": "sha-256", "dhGroup": 14, "encryption": "aes-256-cbc", "keyLifetime": 28800, "version": 2}]}`,
+	"/cloud-instances/ws/vpn/ipsec-policies":             `{"ipSecPolicies": [{"id": "ipsec-1", "name": "ipsec", "authentication": "hmac-sha-256-128", "dhGroup": 14, "encryption": "aes-256-cbc", "keyLifetime": 3600, "pfs": true}]}`,
+	"/cloud-instances/ws/cloud-connections":              `{"cloudConnections": [{"cloudConnectionID": "cc-1", "name": "to-vpc", "speed": 1000, "globalRouting": false, "metered": false, "linkStatus": "up", "port": "p", "ibmIPAddress": "169.254.0.1", "userIPAddress": "169.254.0.2", "creationDate": "2024-01-01T00:00:00.000Z", "networks": [{"networkID": "net-1", "name": "private", "href": "h", "type": "vlan", "vlanID": 10}]}]}`,
+	"/cloud-instances/ws/services/dhcp":                  `[{"id": "dhcp-1", "status": "ACTIVE", "network": {"id": "net-1", "name": "private"}}]`,
+	"/cloud-instances/ws/volume-groups":                  `{"volumeGroups": [{"id": "vg-1", "name": "rep", "status": "available", "replicationStatus": "enabled"}]}`,
+	"/cloud-instances/ws/storage-capacity/storage-pools": `{"maximumStorageAllocation": {"maxAllocationSize": 1000, "storagePool": "p1", "storageType": "tier1"}, "storagePoolsCapacity": [{"poolName": "p2", "maxAllocationSize": 500}, {"poolName": "p1", "maxAllocationSize": 1000}]}`,
+}
+
+// newFakeInventoryServer Serve the fake inventory routes
+func newFakeInventoryServer(t *testing.T) *PowervsV1 {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/pcloud/v1")
+		body, ok := fakeInventoryRoutes[path]
+		w.Header().Set("Content-Type", "application/json")
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"description": "not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return powervs
+}
+
+func TestGetWorkspaceInventory(t *testing.T) {
+	powervs := newFakeInventoryServer(t)
+	inventory, err := powervs.GetWorkspaceInventory(context.Background(), "ws")
+	if err != nil {
+		t.Fatalf("GetWorkspaceInventory() error = %v", err)
+	}
+	if inventory.SchemaVersion != InventorySchemaVersion || inventory.TenantID != "tenant" {
+		t.Errorf("GetWorkspaceInventory() header = %q, %q", inventory.SchemaVersion, inventory.TenantID)
+	}
+	if len(inventory.Instances) != 2 || *inventory.Instances[0].PvmInstanceID != "pvm-1" {
+		t.Fatalf("GetWorkspaceInventory() instances = %+v", inventory.Instances)
+	}
+	app := inventory.Instances[0]
+	if len(app.Volumes) != 2 || *app.Volumes[0].VolumeID != "vol-1" || app.VolumeIDs[0] != "vol-1" {
+		t.Errorf("instance volumes = %+v", app.Volumes)
+	}
+	if len(inventory.Networks) != 1 || len(inventory.Networks[0].Ports) != 2 || *inventory.Networks[0].Ports[0].PortID != "port-1" {
+		t.Errorf("GetWorkspaceInventory() networks = %+v", inventory.Networks)
+	}
+	if *inventory.Volumes[0].VolumeID != "vol-1" || *inventory.StorageCapacity.StoragePoolsCapacity[0].PoolName != "p1" {
+		t.Error("GetWorkspaceInventory() lists are not sorted")
+	}
+	if len(inventory.SshKeys) != 1 || len(inventory.DhcpServers) != 1 || len(inventory.VPNConnections) != 1 {
+		t.Errorf("GetWorkspaceInventory() missing resources: %+v", inventory)
+	}
+}
+
+func TestWorkspaceInventoryMarshal(t *testing.T) {
+	powervs := newFakeInventoryServer(t)
+	var buf bytes.Buffer
+	if err := powervs.ExportInventory(context.Background(), "ws", &buf, InventoryFormatYAML); err != nil {
+		t.Fatalf("ExportInventory() error = %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "cloudConnections:\n") || !strings.Contains(buf.String(), "\nschemaVersion: powervs.inventory/v1\n") {
+		t.Errorf("ExportInventory() keys are not sorted:\n%s", buf.String())
+	}
+
+	parsed, err := ParseInventory(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ParseInventory() error = %v", err)
+	}
+	again, err := parsed.Marshal(InventoryFormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, buf.Bytes()) {
+		t.Error("ParseInventory() does not round-trip")
+	}
+
+	if _, err := parsed.Marshal("xml"); err == nil {
+		t.Error("Marshal() expected error for unsupported format")
+	}
+	if _, err := ParseInventory([]byte(`{"schemaVersion": "powervs.inventory/v0"}`)); err == nil {
+		t.Error("ParseInventory() expected error for unknown schema version")
+	}
+
+	// Hand written documents may leave out IDs
+	partial, err := ParseInventory([]byte(`{"schemaVersion": "powervs.inventory/v1", "volumes": [{"name": "b"}, {"volumeID": "vol-1"}, {"name": "a"}], "sshKeys": [{}, {"name": "ops"}],
+		"placementGroups": [{"name": "ha"}, {}], "cloudConnections": [{}, {"cloudConnectionID": "cc-1"}]}`))
+	if err != nil {
+		t.Fatalf("ParseInventory() error = %v", err)
+	}
+	partial.Sort()
+	if *partial.Volumes[0].Name != "a" || *partial.Volumes[1].Name != "b" || *partial.Volumes[2].VolumeID != "vol-1" {
+		t.Errorf("Sort() volumes = %+v", partial.Volumes)
+	}
+}
+
+func TestRedactInventory(t *testing.T) {
+	var document interface{}
+	_ = json.Unmarshal([]byte(`{"vpn": [{"name": "office", "presharedKey": "hunter2"}], "userData": "c2VjcmV0", "empty": {"secretKey": null}}`), &document)
+	redactInventory(document)
+	got, _ := json.Marshal(document)
+	want := `{"empty":{"secretKey":null},"userData":"REDACTED","vpn":[{"name":"office","presharedKey":"REDACTED"}]}`
+	if string(got) != want {
+		t.Errorf("redactInventory() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,278 @@
+package powervsv1
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// Fields matched by LocateAddress
+const (
+	AddressFieldIPAddress  = "ipAddress"
+	AddressFieldExternalIP = "externalIP"
+	AddressFieldMacAddress = "macAddress"
+)
+
+// AddressLocation : Resource holding an IP or MAC address
+type AddressLocation struct {
+	CloudInstanceID string `json:"cloudInstanceID"`
+
+	NetworkID string `json:"networkID,omitempty"`
+
+	NetworkName string `json:"networkName,omitempty"`
+
+	// Empty when the address was only found on an instance network.
+	PortID string `json:"portID,omitempty"`
+
+	PvmInstanceID string `json:"pvmInstanceID,omitempty"`
+
+	ServerName string `json:"serverName,omitempty"`
+
+	IPAddress string `json:"ipAddress,omitempty"`
+
+	MacAddress string `json:"macAddress,omitempty"`
+
+	ExternalIP string `json:"externalIP,omitempty"`
+
+	// Field that matched the address, one of the AddressField constants.
+	MatchedField string `json:"matchedField,omitempty"`
+
+	// The address belongs to the IP address ranges of the network but is not allocated to any port.
+	// The gateway, network and broadcast addresses are never reported as unallocated.
+	Unallocated bool `json:"unallocated,omitempty"`
+}
+
+// String : Human readable rendering of the location
+func (location AddressLocation) String() string {
+	if location.Unallocated {
+		return fmt.Sprintf("workspace %s network %s (%s): not allocated", location.CloudInstanceID, location.NetworkName, location.NetworkID)
+	}
+	s := fmt.Sprintf("workspace %s network %s (%s)", location.CloudInstanceID, location.NetworkName, location.NetworkID)
+	if location.PortID != "" {
+		s += " port " + location.PortID
+	}
+	if location.PvmInstanceID != "" {
+		s += fmt.Sprintf(" instance %s (%s)", location.ServerName, location.PvmInstanceID)
+	}
+	return s + fmt.Sprintf(": %s %s", location.MatchedField, location.matchedValue())
+}
+
+func (location AddressLocation) matchedValue() string {
+	switch location.MatchedField {
+	case AddressFieldExternalIP:
+		return location.ExternalIP
+	case AddressFieldMacAddress:
+		return location.MacAddress
+	default:
+		return location.IPAddress
+	}
+}
+
+// LocateAddress : Find the ports, instances and networks holding an IP or MAC address.
+// Without cloudInstanceIDs every workspace of the account is searched. Workspaces that
+// cannot be searched are reported in a HydrateErrors error next to the other results.
+func (powervs *PowervsV1) LocateAddress(ctx context.Context, address string, cloudInstanceIDs ...string) ([]AddressLocation, error) {
+	query, err := parseAddressQuery(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cloudInstanceIDs) == 0 {
+		workspaces, _, err := powervs.WorkspacesGetallWithContext(ctx, powervs.NewV1WorkspacesGetallOptions())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list workspaces: %w", err)
+		}
+		for _, workspace := range workspaces.Workspaces {
+			cloudInstanceIDs = append(cloudInstanceIDs, *workspace.ID)
+		}
+	}
+
+	results := make([][]AddressLocation, len(cloudInstanceIDs))
+	err = hydrate(ctx, len(cloudInstanceIDs), nil, func(i int) (string, *core.DetailedResponse, error) {
+		locations, response, err := powervs.locateAddressInWorkspace(ctx, cloudInstanceIDs[i], query)
+		results[i] = locations
+		return cloudInstanceIDs[i], response, err
+	})
+
+	var locations []AddressLocation
+	for _, workspaceLocations := range results {
+		locations = append(locations, workspaceLocations...)
+	}
+	return locations, err
+}
+
+// addressQuery Normalized address searched by LocateAddress
+type addressQuery struct {
+	ip  net.IP
+	mac string
+}
+
+// parseAddressQuery Parse an IP address or a MAC address written with colons or dashes
+func parseAddressQuery(address string) (addressQuery, error) {
+	address = strings.TrimSpace(address)
+	if ip := net.ParseIP(address); ip != nil {
+		return addressQuery{ip: ip}, nil
+	}
+	if mac, err := net.ParseMAC(address); err == nil {
+		return addressQuery{mac: mac.String()}, nil
+	}
+	return addressQuery{}, fmt.Errorf("%q is neither an IP nor a MAC address", address)
+}
+
+// matches Whether an address of a resource is the searched one
+func (query addressQuery) matches(value *string) bool {
+	if value == nil || *value == "" {
+		return false
+	}
+	if query.ip != nil {
+		return query.ip.Equal(net.ParseIP(*value))
+	}
+	mac, err := net.ParseMAC(*value)
+	return err == nil && mac.String() == query.mac
+}
+
+// locateAddressInWorkspace Search the networks, ports and instances of one workspace
+func (powervs *PowervsV1) locateAddressInWorkspace(ctx context.Context, cloudInstanceID string, query addressQuery) ([]AddressLocation, *core.DetailedResponse, error) {
+	networkRefs, response, err := powervs.PcloudNetworksGetallWithContext(ctx, powervs.NewPcloudNetworksGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, response, fmt.Errorf("failed to list networks: %w", err)
+	}
+	networks, err := powervs.GetNetworksDetailed(ctx, cloudInstanceID, networkRefs.Networks, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get networks: %w", err)
+	}
+	instances, response, err := powervs.PcloudPvminstancesGetallWithContext(ctx, powervs.NewPcloudPvminstancesGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, response, fmt.Errorf("failed to list pvm instances: %w", err)
+	}
+	serverNames := map[string]string{}
+	for _, instance := range instances.PvmInstances {
+		serverNames[*instance.PvmInstanceID] = core.StringNilMapper(instance.ServerName)
+	}
+
+	var locations []AddressLocation
+	// Instances already reported through one of their ports, by network
+	found := map[string]bool{}
+	for _, network := range networks {
+		ports, response, err := powervs.PcloudNetworksPortsGetallWithContext(ctx, powervs.NewPcloudNetworksPortsGetallOptions(cloudInstanceID, *network.NetworkID))
+		if err != nil {
+			return nil, response, fmt.Errorf("failed to list ports of network %s: %w", *network.NetworkID, err)
+		}
+		matched := false
+		for _, port := range ports.Ports {
+			field := query.matchedField(port.IPAddress, port.ExternalIP, port.MacAddress)
+			if field == "" {
+				continue
+			}
+			location := AddressLocation{
+				CloudInstanceID: cloudInstanceID,
+				NetworkID:       *network.NetworkID,
+				NetworkName:     core.StringNilMapper(network.Name),
+				PortID:          core.StringNilMapper(port.PortID),
+				IPAddress:       core.StringNilMapper(port.IPAddress),
+				MacAddress:      core.StringNilMapper(port.MacAddress),
+				ExternalIP:      core.StringNilMapper(port.ExternalIP),
+				MatchedField:    field,
+			}
+			if port.PvmInstance != nil && port.PvmInstance.PvmInstanceID != nil {
+				location.PvmInstanceID = *port.PvmInstance.PvmInstanceID
+				location.ServerName = serverNames[location.PvmInstanceID]
+				found[*network.NetworkID+"/"+location.PvmInstanceID] = true
+			}
+			locations = append(locations, location)
+			matched = true
+		}
+		if !matched && query.ip != nil && assignableAddress(network, query.ip) {
+			locations = append(locations, AddressLocation{
+				CloudInstanceID: cloudInstanceID,
+				NetworkID:       *network.NetworkID,
+				NetworkName:     core.StringNilMapper(network.Name),
+				IPAddress:       query.ip.String(),
+				Unallocated:     true,
+			})
+		}
+	}
+
+	for _, instance := range instances.PvmInstances {
+		for _, network := range instance.Networks {
+			ip := network.IPAddress
+			if ip == nil {
+				ip = network.IP
+			}
+			field := query.matchedField(ip, network.ExternalIP, network.MacAddress)
+			networkID := core.StringNilMapper(network.NetworkID)
+			if field == "" || found[networkID+"/"+*instance.PvmInstanceID] {
+				continue
+			}
+			locations = append(locations, AddressLocation{
+				CloudInstanceID: cloudInstanceID,
+				NetworkID:       networkID,
+				NetworkName:     core.StringNilMapper(network.NetworkName),
+				PvmInstanceID:   *instance.PvmInstanceID,
+				ServerName:      core.StringNilMapper(instance.ServerName),
+				IPAddress:       core.StringNilMapper(ip),
+				MacAddress:      core.StringNilMapper(network.MacAddress),
+				ExternalIP:      core.StringNilMapper(network.ExternalIP),
+				MatchedField:    field,
+			})
+		}
+	}
+
+	// An instance network may reveal that an "unallocated" address is in use
+	allocated := map[string]bool{}
+	for _, location := range locations {
+		if !location.Unallocated {
+			allocated[location.NetworkID] = true
+		}
+	}
+	kept := locations[:0]
+	for _, location := range locations {
+		if !location.Unallocated || !allocated[location.NetworkID] {
+			kept = append(kept, location)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].NetworkID < kept[j].NetworkID })
+	return kept, nil, nil
+}
+
+// matchedField Field of a resource holding the searched address, empty if none
+func (query addressQuery) matchedField(ipAddress *string, externalIP *string, macAddress *string) string {
+	switch {
+	case query.matches(ipAddress):
+		return AddressFieldIPAddress
+	case query.matches(externalIP):
+		return AddressFieldExternalIP
+	case query.matches(macAddress):
+		return AddressFieldMacAddress
+	}
+	return ""
+}
+
+// assignableAddress Whether an address can be allocated to a port of the network, that is it
+// belongs to one of its IP address ranges, or to its CIDR when it has none, and is neither the
+// gateway nor the network or broadcast address
+func assignableAddress(network *Network, ip net.IP) bool {
+	if network.CIDR == nil {
+		return false
+	}
+	_, cidr, err := net.ParseCIDR(*network.CIDR)
+	if err != nil || !cidr.Contains(ip) {
+		return false
+	}
+	if gateway := net.ParseIP(core.StringNilMapper(network.Gateway)); gateway != nil && gateway.Equal(ip) {
+		return false
+	}
+	if len(network.IPAddressRanges) > 0 {
+		return inIPAddressRanges(network.IPAddressRanges, ip.String())
+	}
+	if ip.To4() == nil {
+		return true
+	}
+	start, end := ipv4Span(cidr)
+	value, _ := ipv4Value(ip.String())
+	return end-start < 2 || (value != start && value != end)
+}
@@ -0,0 +1,90 @@
+package powervsv1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+func TestLocateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch strings.TrimPrefix(r.URL.Path, "/pcloud/v1") {
+		case "/v1/workspaces":
+			_, _ = w.Write([]byte(`{"workspaces": [{"id": "ws", "name": "ws", "status": "active", "type": "off-premises", "capabilities": {}, "details": {"crn": "c", "creationDate": "2024-01-01T00:00:00.000Z"}, "location": {"region": "dal10", "url": "u"}}]}`))
+		case "/cloud-instances/ws/pvm-instances":
+			_, _ = w.Write([]byte(`{"pvmInstances": [
+				{"pvmInstanceID": "pvm-1", "serverName": "app", "href": "h", "status": "ACTIVE", "memory": 8, "processors": 0.5, "procType": "shared", "diskSize": 120, "imageID": "img-1", "osType": "aix",
+				 "networks": [{"networkID": "net-1", "networkName": "private", "ipAddress": "10.0.0.10", "macAddress": "fa:16:3e:00:00:01"}]},
+				{"pvmInstanceID": "pvm-2", "serverName": "db", "href": "h", "status": "ACTIVE", "memory": 4, "processors": 1, "procType": "dedicated", "diskSize": 0, "imageID": "img-1", "osType": "aix",
+				 "networks": [{"networkID": "net-1", "networkName": "private", "ipAddress": "10.0.0.50", "macAddress": "fa:16:3e:00:00:50", "externalIP": "52.1.1.1"}]}]}`))
+		case "/cloud-instances/ws/networks":
+			_, _ = w.Write([]byte(`{"networks": [{"networkID": "net-1", "name": "private", "type": "vlan", "vlanID": 10, "href": "h"}]}`))
+		case "/cloud-instances/ws/networks/net-1":
+			_, _ = w.Write([]byte(`{"networkID": "net-1", "name": "private", "type": "vlan", "vlanID": 10, "cidr": "10.0.0.0/24", "gateway": "10.0.0.1",
+				"dnsServers": ["9.9.9.9"], "ipAddressMetrics": {"available": 250, "used": 3, "total": 253, "utilization": 1}, "ipAddressRanges": [{"startingIPAddress": "10.0.0.2", "endingIPAddress": "10.0.0.254"}]}`))
+		case "/cloud-instances/ws/networks/net-1/ports":
+			_, _ = w.Write([]byte(`{"ports": [
+				{"portID": "port-2", "ipAddress": "10.0.0.11", "macAddress": "fa:16:3e:00:00:02", "status": "DOWN", "description": ""},
+				{"portID": "port-1", "ipAddress": "10.0.0.10", "macAddress": "fa:16:3e:00:00:01", "status": "ACTIVE", "description": "", "pvmInstance": {"pvmInstanceID": "pvm-1", "href": "h"}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"description": "not found"}`))
+		}
+	}))
+	defer server.Close()
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		address string
+		want    []AddressLocation
+	}{
+		{"10.0.0.10", []AddressLocation{{
+			CloudInstanceID: "ws", NetworkID: "net-1", NetworkName: "private", PortID: "port-1", PvmInstanceID: "pvm-1", ServerName: "app",
+			IPAddress: "10.0.0.10", MacAddress: "fa:16:3e:00:00:01", MatchedField: AddressFieldIPAddress,
+		}}},
+		{"FA-16-3E-00-00-02", []AddressLocation{{
+			CloudInstanceID: "ws", NetworkID: "net-1", NetworkName: "private", PortID: "port-2",
+			IPAddress: "10.0.0.11", MacAddress: "fa:16:3e:00:00:02", MatchedField: AddressFieldMacAddress,
+		}}},
+		{"52.1.1.1", []AddressLocation{{
+			CloudInstanceID: "ws", NetworkID: "net-1", NetworkName: "private", PvmInstanceID: "pvm-2", ServerName: "db",
+			IPAddress: "10.0.0.50", MacAddress: "fa:16:3e:00:00:50", ExternalIP: "52.1.1.1", MatchedField: AddressFieldExternalIP,
+		}}},
+		{"10.0.0.99", []AddressLocation{{CloudInstanceID: "ws", NetworkID: "net-1", NetworkName: "private", IPAddress: "10.0.0.99", Unallocated: true}}},
+		{"10.0.0.1", nil},
+		{"10.0.0.0", nil},
+		{"10.0.0.255", nil},
+		{"172.16.0.1", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			got, err := powervs.LocateAddress(context.Background(), tt.address)
+			if err != nil {
+				t.Fatalf("LocateAddress() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("LocateAddress() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("LocateAddress()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := powervs.LocateAddress(context.Background(), "not-an-address", "ws"); err == nil {
+		t.Error("LocateAddress() expected error for invalid address")
+	}
+	if _, err := powervs.LocateAddress(context.Background(), "10.0.0.10", "missing"); err == nil {
+		t.Error("LocateAddress() expected error for unknown workspace")
+	}
+}
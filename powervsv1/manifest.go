@@ -0,0 +1,1198 @@
+package powervsv1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"sigs.k8s.io/yaml"
+)
+
+// Resource types used by manifest plans
+const (
+	ManifestResourceNetwork             = "network"
+	ManifestResourceSshKey              = "sshKey"
+	ManifestResourcePlacementGroup      = "placementGroup"
+	ManifestResourceSharedProcessorPool = "sharedProcessorPool"
+	ManifestResourceVolume              = "volume"
+	ManifestResourcePvmInstance         = "pvmInstance"
+)
+
+// Actions used by manifest plans
+const (
+	// ManifestActionCreate The resource is missing from the workspace
+	ManifestActionCreate = "create"
+	// ManifestActionUpdate The resource differs on fields that can be updated in place
+	ManifestActionUpdate = "update"
+	// ManifestActionDelete The resource is not described by a pruning manifest
+	ManifestActionDelete = "delete"
+	// ManifestActionDrift The resource differs on fields that cannot be updated, apply leaves it untouched
+	ManifestActionDrift = "drift"
+)
+
+// WorkspaceManifest : Declarative description of the resources of a workspace.
+// Resources reference each other by name.
+type WorkspaceManifest struct {
+	// Delete workspace resources that are not described in the manifest.
+	// SSH keys are never pruned since they are shared by every workspace of the tenant, and
+	// volumes, pools, placement groups and networks used by instances the manifest keeps are
+	// reported as drift instead.
+	Prune bool `json:"prune,omitempty"`
+
+	Networks []ManifestNetwork `json:"networks,omitempty"`
+
+	SshKeys []ManifestSshKey `json:"sshKeys,omitempty"`
+
+	PlacementGroups []ManifestPlacementGroup `json:"placementGroups,omitempty"`
+
+	SharedProcessorPools []ManifestSharedProcessorPool `json:"sharedProcessorPools,omitempty"`
+
+	Volumes []ManifestVolume `json:"volumes,omitempty"`
+
+	Instances []ManifestInstance `json:"instances,omitempty"`
+}
+
+// ManifestNetwork : Network described by a workspace manifest
+type ManifestNetwork struct {
+	Name string `json:"name"`
+
+	// Type of network, defaults to "vlan".
+	Type string `json:"type,omitempty"`
+
+	CIDR string `json:"cidr,omitempty"`
+
+	Gateway string `json:"gateway,omitempty"`
+
+	DnsServers []string `json:"dnsServers,omitempty"`
+
+	IPAddressRanges []IPAddressRange `json:"ipAddressRanges,omitempty"`
+
+	Mtu *int64 `json:"mtu,omitempty"`
+}
+
+// ManifestSshKey : SSH key described by a workspace manifest
+type ManifestSshKey struct {
+	Name string `json:"name"`
+
+	SshKey string `json:"sshKey"`
+}
+
+// ManifestPlacementGroup : Placement group described by a workspace manifest
+type ManifestPlacementGroup struct {
+	Name string `json:"name"`
+
+	// Policy of the placement group, "affinity" or "anti-affinity".
+	Policy string `json:"policy"`
+}
+
+// ManifestSharedProcessorPool : Shared processor pool described by a workspace manifest
+type ManifestSharedProcessorPool struct {
+	Name string `json:"name"`
+
+	HostGroup string `json:"hostGroup"`
+
+	ReservedCores int64 `json:"reservedCores"`
+}
+
+// ManifestVolume : Volume described by a workspace manifest
+type ManifestVolume struct {
+	Name string `json:"name"`
+
+	// Size of the volume in GB.
+	Size float64 `json:"size"`
+
+	DiskType string `json:"diskType,omitempty"`
+
+	VolumePool string `json:"volumePool,omitempty"`
+
+	Shareable *bool `json:"shareable,omitempty"`
+
+	ReplicationEnabled *bool `json:"replicationEnabled,omitempty"`
+}
+
+// ManifestInstance : PVM instance described by a workspace manifest
+type ManifestInstance struct {
+	Name string `json:"name"`
+
+	ImageID string `json:"imageID"`
+
+	Memory float64 `json:"memory"`
+
+	Processors float64 `json:"processors"`
+
+	ProcType string `json:"procType"`
+
+	SysType string `json:"sysType,omitempty"`
+
+	StorageType string `json:"storageType,omitempty"`
+
+	// Name of the SSH key to inject.
+	KeyPairName string `json:"keyPairName,omitempty"`
+
+	Networks []ManifestInstanceNetwork `json:"networks,omitempty"`
+
+	// Names of the data volumes to attach.
+	Volumes []string `json:"volumes,omitempty"`
+
+	// Name of the placement group.
+	PlacementGroup string `json:"placementGroup,omitempty"`
+
+	// Name of the shared processor pool.
+	SharedProcessorPool string `json:"sharedProcessorPool,omitempty"`
+
+	UserData string `json:"userData,omitempty"`
+}
+
+// ManifestInstanceNetwork : Network attachment of a manifest instance
+type ManifestInstanceNetwork struct {
+	// Name of the network.
+	Name string `json:"name"`
+
+	// Fixed IP address, allocated by the network when empty.
+	IPAddress string `json:"ipAddress,omitempty"`
+}
+
+// ParseWorkspaceManifest : Parse a YAML or JSON workspace manifest
+func ParseWorkspaceManifest(data []byte) (*WorkspaceManifest, error) {
+	manifest := &WorkspaceManifest{}
+	if err := yaml.UnmarshalStrict(data, manifest); err != nil {
+		return nil, fmt.Errorf("invalid workspace manifest: %w", err)
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// LoadWorkspaceManifest : Read and parse a YAML or JSON workspace manifest file
+func LoadWorkspaceManifest(path string) (*WorkspaceManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseWorkspaceManifest(data)
+}
+
+// Validate : Check that names are set and unique per resource type
+func (manifest *WorkspaceManifest) Validate() error {
+	check := func(resourceType string) func(name string) error {
+		seen := map[string]bool{}
+		return func(name string) error {
+			if name == "" {
+				return fmt.Errorf("%s without a name in workspace manifest", resourceType)
+			}
+			if seen[name] {
+				return fmt.Errorf("duplicate %s %q in workspace manifest", resourceType, name)
+			}
+			seen[name] = true
+			return nil
+		}
+	}
+
+	checkNetwork := check(ManifestResourceNetwork)
+	for _, n := range manifest.Networks {
+		if err := checkNetwork(n.Name); err != nil {
+			return err
+		}
+	}
+	checkSshKey := check(ManifestResourceSshKey)
+	for _, k := range manifest.SshKeys {
+		if err := checkSshKey(k.Name); err != nil {
+			return err
+		}
+	}
+	checkPlacementGroup := check(ManifestResourcePlacementGroup)
+	for _, pg := range manifest.PlacementGroups {
+		if err := checkPlacementGroup(pg.Name); err != nil {
+			return err
+		}
+	}
+	checkPool := check(ManifestResourceSharedProcessorPool)
+	for _, spp := range manifest.SharedProcessorPools {
+		if err := checkPool(spp.Name); err != nil {
+			return err
+		}
+	}
+	checkVolume := check(ManifestResourceVolume)
+	for _, v := range manifest.Volumes {
+		if err := checkVolume(v.Name); err != nil {
+			return err
+		}
+	}
+	checkInstance := check(ManifestResourcePvmInstance)
+	for _, i := range manifest.Instances {
+		if err := checkInstance(i.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WorkspaceState : Live resources of a workspace, as returned by the Getall endpoints
+type WorkspaceState struct {
+	CloudInstanceID string
+
+	TenantID string
+
+	Networks []Network
+
+	SshKeys []SshKey
+
+	PlacementGroups []PlacementGroup
+
+	SharedProcessorPools []SharedProcessorPool
+
+	Volumes []VolumeReference
+
+	PvmInstances []PvmInstanceReference
+}
+
+// GetWorkspaceState : Fetch the live resources of a workspace
+func (powervs *PowervsV1) GetWorkspaceState(ctx context.Context, cloudInstanceID string) (*WorkspaceState, error) {
+	state := &WorkspaceState{CloudInstanceID: cloudInstanceID}
+
+	cloudInstance, _, err := powervs.PcloudCloudinstancesGetWithContext(ctx, powervs.NewPcloudCloudinstancesGetOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace %s: %w", cloudInstanceID, err)
+	}
+	state.TenantID = core.StringNilMapper(cloudInstance.TenantID)
+
+	networks, _, err := powervs.PcloudNetworksGetallWithContext(ctx, powervs.NewPcloudNetworksGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+	for _, ref := range networks.Networks {
+		network, _, err := powervs.PcloudNetworksGetWithContext(ctx, powervs.NewPcloudNetworksGetOptions(cloudInstanceID, *ref.NetworkID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get network %s: %w", *ref.NetworkID, err)
+		}
+		state.Networks = append(state.Networks, *network)
+	}
+
+	if state.TenantID != "" {
+		sshKeys, _, err := powervs.PcloudTenantsSshkeysGetallWithContext(ctx, powervs.NewPcloudTenantsSshkeysGetallOptions(state.TenantID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list ssh keys: %w", err)
+		}
+		state.SshKeys = sshKeys.SshKeys
+	}
+
+	placementGroups, _, err := powervs.PcloudPlacementgroupsGetallWithContext(ctx, powervs.NewPcloudPlacementgroupsGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list placement groups: %w", err)
+	}
+	state.PlacementGroups = placementGroups.PlacementGroups
+
+	pools, _, err := powervs.PcloudSharedprocessorpoolsGetallWithContext(ctx, powervs.NewPcloudSharedprocessorpoolsGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shared processor pools: %w", err)
+	}
+	state.SharedProcessorPools = pools.SharedProcessorPools
+
+	volumes, _, err := powervs.PcloudCloudinstancesVolumesGetallWithContext(ctx, powervs.NewPcloudCloudinstancesVolumesGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+	state.Volumes = volumes.Volumes
+
+	instances, _, err := powervs.PcloudPvminstancesGetallWithContext(ctx, powervs.NewPcloudPvminstancesGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pvm instances: %w", err)
+	}
+	state.PvmInstances = instances.PvmInstances
+
+	return state, nil
+}
+
+// ManifestFieldChange : Difference between the live and the desired value of a field
+type ManifestFieldChange struct {
+	Field   string `json:"field"`
+	Current string `json:"current"`
+	Desired string `json:"desired"`
+}
+
+// ManifestChange : Change required to converge one resource towards the manifest
+type ManifestChange struct {
+	Action string `json:"action"`
+
+	ResourceType string `json:"resourceType"`
+
+	Name string `json:"name"`
+
+	// ID of the live resource, empty for creations.
+	ID string `json:"id,omitempty"`
+
+	Fields []ManifestFieldChange `json:"fields,omitempty"`
+}
+
+// String : One line summary of the change
+func (change ManifestChange) String() string {
+	symbol := map[string]string{
+		ManifestActionCreate: "+",
+		ManifestActionUpdate: "~",
+		ManifestActionDelete: "-",
+		ManifestActionDrift:  "!",
+	}[change.Action]
+	s := fmt.Sprintf("%s %s %q", symbol, change.ResourceType, change.Name)
+	for _, f := range change.Fields {
+		s += fmt.Sprintf("\n    %s: %q => %q", f.Field, f.Current, f.Desired)
+	}
+	return s
+}
+
+// ManifestPlan : Ordered changes converging a workspace towards a manifest
+type ManifestPlan struct {
+	CloudInstanceID string `json:"cloudInstanceID"`
+
+	TenantID string `json:"tenantID,omitempty"`
+
+	Changes []ManifestChange `json:"changes"`
+
+	manifest *WorkspaceManifest
+	state    *WorkspaceState
+}
+
+// HasChanges : Whether applying the plan would modify the workspace
+func (plan *ManifestPlan) HasChanges() bool {
+	for _, c := range plan.Changes {
+		if c.Action != ManifestActionDrift {
+			return true
+		}
+	}
+	return false
+}
+
+// Drift : Changes on existing resources that no longer match the manifest
+func (plan *ManifestPlan) Drift() (drift []ManifestChange) {
+	for _, c := range plan.Changes {
+		if c.Action == ManifestActionUpdate || c.Action == ManifestActionDrift {
+			drift = append(drift, c)
+		}
+	}
+	return
+}
+
+// String : Human readable rendering of the plan
+func (plan *ManifestPlan) String() string {
+	if len(plan.Changes) == 0 {
+		return "No changes. Workspace matches the manifest."
+	}
+	lines := make([]string, 0, len(plan.Changes))
+	for _, c := range plan.Changes {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// PlanWorkspaceManifest : Compute the changes needed to converge a workspace towards a manifest
+func (powervs *PowervsV1) PlanWorkspaceManifest(ctx context.Context, cloudInstanceID string, manifest *WorkspaceManifest) (*ManifestPlan, error) {
+	state, err := powervs.GetWorkspaceState(ctx, cloudInstanceID)
+	if err != nil {
+		return nil, err
+	}
+	return manifest.Plan(state)
+}
+
+// Plan : Compute the changes needed to converge the given state towards the manifest
+func (manifest *WorkspaceManifest) Plan(state *WorkspaceState) (*ManifestPlan, error) {
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	if err := manifest.checkReferences(state); err != nil {
+		return nil, err
+	}
+
+	plan := &ManifestPlan{
+		CloudInstanceID: state.CloudInstanceID,
+		TenantID:        state.TenantID,
+		manifest:        manifest,
+		state:           state,
+	}
+	add := func(action, resourceType, name, id string, fields []ManifestFieldChange) {
+		plan.Changes = append(plan.Changes, ManifestChange{
+			Action:       action,
+			ResourceType: resourceType,
+			Name:         name,
+			ID:           id,
+			Fields:       fields,
+		})
+	}
+	converge := func(resourceType, name, id string, found bool, updates, immutable []ManifestFieldChange) {
+		switch {
+		case !found:
+			add(ManifestActionCreate, resourceType, name, "", nil)
+		case len(updates) > 0:
+			add(ManifestActionUpdate, resourceType, name, id, updates)
+		}
+		if found && len(immutable) > 0 {
+			add(ManifestActionDrift, resourceType, name, id, immutable)
+		}
+	}
+
+	networks := map[string]*Network{}
+	for i := range state.Networks {
+		networks[core.StringNilMapper(state.Networks[i].Name)] = &state.Networks[i]
+	}
+	for _, desired := range manifest.Networks {
+		live, found := networks[desired.Name]
+		var updates, immutable []ManifestFieldChange
+		if found {
+			immutable = diffString(immutable, "type", core.StringNilMapper(live.Type), desired.networkType())
+			immutable = diffString(immutable, "cidr", core.StringNilMapper(live.CIDR), desired.CIDR)
+			if desired.Mtu != nil && (live.Mtu == nil || *live.Mtu != *desired.Mtu) {
+				immutable = append(immutable, ManifestFieldChange{"mtu", formatInt64(live.Mtu), formatInt64(desired.Mtu)})
+			}
+			updates = diffString(updates, "gateway", core.StringNilMapper(live.Gateway), desired.Gateway)
+			if desired.DnsServers != nil {
+				updates = diffString(updates, "dnsServers", strings.Join(live.DnsServers, ","), strings.Join(desired.DnsServers, ","))
+			}
+			if desired.IPAddressRanges != nil {
+				updates = diffString(updates, "ipAddressRanges", formatIPAddressRanges(live.IPAddressRanges), formatIPAddressRanges(desired.IPAddressRanges))
+			}
+			converge(ManifestResourceNetwork, desired.Name, *live.NetworkID, true, updates, immutable)
+		} else {
+			converge(ManifestResourceNetwork, desired.Name, "", false, nil, nil)
+		}
+	}
+
+	sshKeys := map[string]*SshKey{}
+	for i := range state.SshKeys {
+		sshKeys[core.StringNilMapper(state.SshKeys[i].Name)] = &state.SshKeys[i]
+	}
+	for _, desired := range manifest.SshKeys {
+		live, found := sshKeys[desired.Name]
+		var updates []ManifestFieldChange
+		if found {
+			updates = diffString(updates, "sshKey", strings.TrimSpace(core.StringNilMapper(live.SshKey)), strings.TrimSpace(desired.SshKey))
+		}
+		converge(ManifestResourceSshKey, desired.Name, desired.Name, found, updates, nil)
+	}
+
+	placementGroups := map[string]*PlacementGroup{}
+	for i := range state.PlacementGroups {
+		placementGroups[core.StringNilMapper(state.PlacementGroups[i].Name)] = &state.PlacementGroups[i]
+	}
+	for _, desired := range manifest.PlacementGroups {
+		live, found := placementGroups[desired.Name]
+		var immutable []ManifestFieldChange
+		id := ""
+		if found {
+			id = core.StringNilMapper(live.ID)
+			immutable = diffString(immutable, "policy", core.StringNilMapper(live.Policy), desired.Policy)
+		}
+		converge(ManifestResourcePlacementGroup, desired.Name, id, found, nil, immutable)
+	}
+
+	pools := map[string]*SharedProcessorPool{}
+	for i := range state.SharedProcessorPools {
+		pools[core.StringNilMapper(state.SharedProcessorPools[i].Name)] = &state.SharedProcessorPools[i]
+	}
+	for _, desired := range manifest.SharedProcessorPools {
+		live, found := pools[desired.Name]
+		var updates, immutable []ManifestFieldChange
+		id := ""
+		if found {
+			id = core.StringNilMapper(live.ID)
+			immutable = diffString(immutable, "hostGroup", core.StringNilMapper(live.HostGroup), desired.HostGroup)
+			if live.ReservedCores == nil || *live.ReservedCores != desired.ReservedCores {
+				updates = append(updates, ManifestFieldChange{"reservedCores", formatInt64(live.ReservedCores), formatInt64(&desired.ReservedCores)})
+			}
+		}
+		converge(ManifestResourceSharedProcessorPool, desired.Name, id, found, updates, immutable)
+	}
+
+	volumes := map[string]*VolumeReference{}
+	for i := range state.Volumes {
+		volumes[core.StringNilMapper(state.Volumes[i].Name)] = &state.Volumes[i]
+	}
+	for _, desired := range manifest.Volumes {
+		live, found := volumes[desired.Name]
+		var updates, immutable []ManifestFieldChange
+		id := ""
+		if found {
+			id = core.StringNilMapper(live.VolumeID)
+			immutable = diffString(immutable, "diskType", core.StringNilMapper(live.DiskType), desired.DiskType)
+			immutable = diffString(immutable, "volumePool", core.StringNilMapper(live.VolumePool), desired.VolumePool)
+			if desired.ReplicationEnabled != nil {
+				immutable = diffString(immutable, "replicationEnabled", formatBool(live.ReplicationEnabled), formatBool(desired.ReplicationEnabled))
+			}
+			current := formatFloat64(live.Size)
+			if live.Size == nil || *live.Size < desired.Size {
+				updates = append(updates, ManifestFieldChange{"size", current, formatFloat64(&desired.Size)})
+			} else if *live.Size > desired.Size {
+				// Volumes can only grow
+				immutable = append(immutable, ManifestFieldChange{"size", current, formatFloat64(&desired.Size)})
+			}
+			if desired.Shareable != nil {
+				updates = diffString(updates, "shareable", formatBool(live.Shareable), formatBool(desired.Shareable))
+			}
+		}
+		converge(ManifestResourceVolume, desired.Name, id, found, updates, immutable)
+	}
+
+	instances := map[string]*PvmInstanceReference{}
+	for i := range state.PvmInstances {
+		instances[core.StringNilMapper(state.PvmInstances[i].ServerName)] = &state.PvmInstances[i]
+	}
+	for _, desired := range manifest.Instances {
+		live, found := instances[desired.Name]
+		var updates, immutable []ManifestFieldChange
+		id := ""
+		if found {
+			id = core.StringNilMapper(live.PvmInstanceID)
+			immutable = diffString(immutable, "imageID", core.StringNilMapper(live.ImageID), desired.ImageID)
+			immutable = diffString(immutable, "sysType", core.StringNilMapper(live.SysType), desired.SysType)
+			immutable = diffString(immutable, "storageType", core.StringNilMapper(live.StorageType), desired.StorageType)
+			if live.Memory == nil || *live.Memory != desired.Memory {
+				updates = append(updates, ManifestFieldChange{"memory", formatFloat64(live.Memory), formatFloat64(&desired.Memory)})
+			}
+			if live.Processors == nil || *live.Processors != desired.Processors {
+				updates = append(updates, ManifestFieldChange{"processors", formatFloat64(live.Processors), formatFloat64(&desired.Processors)})
+			}
+			updates = diffString(updates, "procType", core.StringNilMapper(live.ProcType), desired.ProcType)
+			// Apply does not change attachments of existing instances. The service does not
+			// report the SSH key of an instance, so it cannot be compared.
+			if desired.Networks != nil {
+				names := make([]string, 0, len(desired.Networks))
+				for _, n := range desired.Networks {
+					names = append(names, n.Name)
+				}
+				sort.Strings(names)
+				immutable = diffStrings(immutable, "networks", instanceNetworks(*live, state), names)
+			}
+			if desired.Volumes != nil {
+				names := append([]string{}, desired.Volumes...)
+				sort.Strings(names)
+				immutable = diffStrings(immutable, "volumes", instanceVolumes(*live, state), names)
+			}
+			immutable = diffString(immutable, "placementGroup", instancePlacementGroup(*live, state), desired.PlacementGroup)
+			immutable = diffString(immutable, "sharedProcessorPool", instanceSharedProcessorPool(*live, state), desired.SharedProcessorPool)
+		}
+		converge(ManifestResourcePvmInstance, desired.Name, id, found, updates, immutable)
+	}
+
+	if manifest.Prune {
+		plan.addPruneChanges(add)
+	}
+	return plan, nil
+}
+
+// addPruneChanges Add deletions for live resources missing from the manifest,
+// dependents first so that resources in use are released before being deleted.
+// Resources still used by kept instances are left in place and reported as drift.
+func (plan *ManifestPlan) addPruneChanges(add func(action, resourceType, name, id string, fields []ManifestFieldChange)) {
+	manifest, state := plan.manifest, plan.state
+	type orphan struct{ name, id string }
+	// Names of the kept instances using each resource, by resource ID
+	var usedBy map[string][]string
+	prune := func(resourceType string, orphans []orphan) {
+		sort.Slice(orphans, func(i, j int) bool { return orphans[i].name < orphans[j].name })
+		for _, o := range orphans {
+			if users := usedBy[o.id]; len(users) > 0 {
+				sort.Strings(users)
+				add(ManifestActionDrift, resourceType, o.name, o.id, []ManifestFieldChange{{"pvmInstances", strings.Join(users, ","), ""}})
+			}
+		}
+		for _, o := range orphans {
+			if len(usedBy[o.id]) == 0 {
+				add(ManifestActionDelete, resourceType, o.name, o.id, nil)
+			}
+		}
+	}
+
+	wanted := map[string]bool{}
+	for _, i := range manifest.Instances {
+		wanted[i.Name] = true
+	}
+	var orphans []orphan
+	var kept []PvmInstanceReference
+	for _, i := range state.PvmInstances {
+		if !wanted[core.StringNilMapper(i.ServerName)] {
+			orphans = append(orphans, orphan{core.StringNilMapper(i.ServerName), core.StringNilMapper(i.PvmInstanceID)})
+		} else {
+			kept = append(kept, i)
+		}
+	}
+	prune(ManifestResourcePvmInstance, orphans)
+
+	wanted, orphans, usedBy = map[string]bool{}, nil, map[string][]string{}
+	for _, v := range manifest.Volumes {
+		wanted[v.Name] = true
+	}
+	for _, v := range state.Volumes {
+		// Boot volumes go away with their instance
+		if !wanted[core.StringNilMapper(v.Name)] && !boolValue(v.BootVolume) {
+			orphans = append(orphans, orphan{core.StringNilMapper(v.Name), core.StringNilMapper(v.VolumeID)})
+			for _, i := range kept {
+				if containsString(v.PvmInstanceIDs, core.StringNilMapper(i.PvmInstanceID)) {
+					usedBy[*v.VolumeID] = append(usedBy[*v.VolumeID], core.StringNilMapper(i.ServerName))
+				}
+			}
+		}
+	}
+	prune(ManifestResourceVolume, orphans)
+
+	wanted, orphans, usedBy = map[string]bool{}, nil, map[string][]string{}
+	for _, spp := range manifest.SharedProcessorPools {
+		wanted[spp.Name] = true
+	}
+	for _, spp := range state.SharedProcessorPools {
+		if !wanted[core.StringNilMapper(spp.Name)] {
+			orphans = append(orphans, orphan{core.StringNilMapper(spp.Name), core.StringNilMapper(spp.ID)})
+			for _, i := range kept {
+				if instanceSharedProcessorPool(i, state) == core.StringNilMapper(spp.Name) {
+					usedBy[core.StringNilMapper(spp.ID)] = append(usedBy[core.StringNilMapper(spp.ID)], core.StringNilMapper(i.ServerName))
+				}
+			}
+		}
+	}
+	prune(ManifestResourceSharedProcessorPool, orphans)
+
+	wanted, orphans, usedBy = map[string]bool{}, nil, map[string][]string{}
+	for _, pg := range manifest.PlacementGroups {
+		wanted[pg.Name] = true
+	}
+	for _, pg := range state.PlacementGroups {
+		if !wanted[core.StringNilMapper(pg.Name)] {
+			orphans = append(orphans, orphan{core.StringNilMapper(pg.Name), core.StringNilMapper(pg.ID)})
+			for _, i := range kept {
+				if instancePlacementGroup(i, state) == core.StringNilMapper(pg.Name) {
+					usedBy[core.StringNilMapper(pg.ID)] = append(usedBy[core.StringNilMapper(pg.ID)], core.StringNilMapper(i.ServerName))
+				}
+			}
+		}
+	}
+	prune(ManifestResourcePlacementGroup, orphans)
+
+	wanted, orphans, usedBy = map[string]bool{}, nil, map[string][]string{}
+	for _, n := range manifest.Networks {
+		wanted[n.Name] = true
+	}
+	for _, n := range state.Networks {
+		if !wanted[core.StringNilMapper(n.Name)] {
+			orphans = append(orphans, orphan{core.StringNilMapper(n.Name), core.StringNilMapper(n.NetworkID)})
+			for _, i := range kept {
+				if containsString(instanceNetworks(i, state), core.StringNilMapper(n.Name)) {
+					usedBy[core.StringNilMapper(n.NetworkID)] = append(usedBy[core.StringNilMapper(n.NetworkID)], core.StringNilMapper(i.ServerName))
+				}
+			}
+		}
+	}
+	prune(ManifestResourceNetwork, orphans)
+}
+
+// instanceNetworks Sorted names of the networks a live instance is attached to
+func instanceNetworks(instance PvmInstanceReference, state *WorkspaceState) []string {
+	names := make([]string, 0, len(instance.Networks))
+	for _, network := range instance.Networks {
+		name := core.StringNilMapper(network.NetworkName)
+		for _, n := range state.Networks {
+			if name == "" && network.NetworkID != nil && core.StringNilMapper(n.NetworkID) == *network.NetworkID {
+				name = core.StringNilMapper(n.Name)
+			}
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// instanceVolumes Sorted names of the data volumes attached to a live instance
+func instanceVolumes(instance PvmInstanceReference, state *WorkspaceState) []string {
+	names := []string{}
+	for _, v := range state.Volumes {
+		if !boolValue(v.BootVolume) && containsString(v.PvmInstanceIDs, core.StringNilMapper(instance.PvmInstanceID)) {
+			names = append(names, core.StringNilMapper(v.Name))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// instancePlacementGroup Name of the placement group of a live instance, empty when it has none
+func instancePlacementGroup(instance PvmInstanceReference, state *WorkspaceState) string {
+	id := core.StringNilMapper(instance.PvmInstanceID)
+	for _, pg := range state.PlacementGroups {
+		if containsString(pg.Members, id) || (instance.PlacementGroup != nil && (*instance.PlacementGroup == core.StringNilMapper(pg.ID) || *instance.PlacementGroup == core.StringNilMapper(pg.Name))) {
+			return core.StringNilMapper(pg.Name)
+		}
+	}
+	return ""
+}
+
+// instanceSharedProcessorPool Name of the shared processor pool of a live instance, empty when it has none
+func instanceSharedProcessorPool(instance PvmInstanceReference, state *WorkspaceState) string {
+	for _, spp := range state.SharedProcessorPools {
+		if instance.SharedProcessorPoolID != nil && *instance.SharedProcessorPoolID == core.StringNilMapper(spp.ID) {
+			return core.StringNilMapper(spp.Name)
+		}
+	}
+	return core.StringNilMapper(instance.SharedProcessorPool)
+}
+
+// checkReferences Ensure every name referenced by an instance is described
+// in the manifest or exists in the workspace
+func (manifest *WorkspaceManifest) checkReferences(state *WorkspaceState) error {
+	known := map[string]map[string]bool{}
+	mark := func(resourceType, name string) {
+		if known[resourceType] == nil {
+			known[resourceType] = map[string]bool{}
+		}
+		known[resourceType][name] = true
+	}
+	for _, n := range manifest.Networks {
+		mark(ManifestResourceNetwork, n.Name)
+	}
+	for _, k := range manifest.SshKeys {
+		mark(ManifestResourceSshKey, k.Name)
+	}
+	for _, pg := range manifest.PlacementGroups {
+		mark(ManifestResourcePlacementGroup, pg.Name)
+	}
+	for _, spp := range manifest.SharedProcessorPools {
+		mark(ManifestResourceSharedProcessorPool, spp.Name)
+	}
+	for _, v := range manifest.Volumes {
+		mark(ManifestResourceVolume, v.Name)
+	}
+	// Live resources can only be referenced when they survive the plan
+	if !manifest.Prune {
+		for _, n := range state.Networks {
+			mark(ManifestResourceNetwork, core.StringNilMapper(n.Name))
+		}
+		for _, pg := range state.PlacementGroups {
+			mark(ManifestResourcePlacementGroup, core.StringNilMapper(pg.Name))
+		}
+		for _, spp := range state.SharedProcessorPools {
+			mark(ManifestResourceSharedProcessorPool, core.StringNilMapper(spp.Name))
+		}
+		for _, v := range state.Volumes {
+			mark(ManifestResourceVolume, core.StringNilMapper(v.Name))
+		}
+	}
+	for _, k := range state.SshKeys {
+		mark(ManifestResourceSshKey, core.StringNilMapper(k.Name))
+	}
+
+	for _, i := range manifest.Instances {
+		refs := [][2]string{
+			{ManifestResourceSshKey, i.KeyPairName},
+			{ManifestResourcePlacementGroup, i.PlacementGroup},
+			{ManifestResourceSharedProcessorPool, i.SharedProcessorPool},
+		}
+		for _, n := range i.Networks {
+			refs = append(refs, [2]string{ManifestResourceNetwork, n.Name})
+		}
+		for _, v := range i.Volumes {
+			refs = append(refs, [2]string{ManifestResourceVolume, v})
+		}
+		for _, ref := range refs {
+			if ref[1] != "" && !known[ref[0]][ref[1]] {
+				return fmt.Errorf("instance %q references unknown %s %q", i.Name, ref[0], ref[1])
+			}
+		}
+	}
+	return nil
+}
+
+// ManifestApplyResult : Outcome of applying a manifest plan
+type ManifestApplyResult struct {
+	// Changes applied successfully, in order.
+	Applied []ManifestChange
+
+	// Change that failed and stopped the apply, nil on success.
+	Failed *ManifestChange
+}
+
+// ApplyWorkspacePlan : Apply the changes of a plan in order, stopping on the first failure.
+// Drift changes are reported by the plan but never applied.
+func (powervs *PowervsV1) ApplyWorkspacePlan(ctx context.Context, plan *ManifestPlan) (*ManifestApplyResult, error) {
+	if plan == nil || plan.manifest == nil || plan.state == nil {
+		return nil, fmt.Errorf("plan must be computed with PlanWorkspaceManifest")
+	}
+	a := &manifestApplier{
+		powervs: powervs,
+		plan:    plan,
+		ids:     map[string]map[string]string{},
+	}
+	a.indexState()
+
+	result := &ManifestApplyResult{}
+	for i := range plan.Changes {
+		change := plan.Changes[i]
+		if change.Action == ManifestActionDrift {
+			continue
+		}
+		if err := a.apply(ctx, change); err != nil {
+			result.Failed = &change
+			return result, fmt.Errorf("failed to %s %s %q: %w", change.Action, change.ResourceType, change.Name, err)
+		}
+		result.Applied = append(result.Applied, change)
+	}
+	return result, nil
+}
+
+// manifestApplier Applies plan changes, tracking the IDs of resources by name
+type manifestApplier struct {
+	powervs *PowervsV1
+	plan    *ManifestPlan
+	ids     map[string]map[string]string
+}
+
+func (a *manifestApplier) setID(resourceType, name, id string) {
+	if a.ids[resourceType] == nil {
+		a.ids[resourceType] = map[string]string{}
+	}
+	a.ids[resourceType][name] = id
+}
+
+func (a *manifestApplier) indexState() {
+	state := a.plan.state
+	for _, n := range state.Networks {
+		a.setID(ManifestResourceNetwork, core.StringNilMapper(n.Name), core.StringNilMapper(n.NetworkID))
+	}
+	for _, pg := range state.PlacementGroups {
+		a.setID(ManifestResourcePlacementGroup, core.StringNilMapper(pg.Name), core.StringNilMapper(pg.ID))
+	}
+	for _, spp := range state.SharedProcessorPools {
+		a.setID(ManifestResourceSharedProcessorPool, core.StringNilMapper(spp.Name), core.StringNilMapper(spp.ID))
+	}
+	for _, v := range state.Volumes {
+		a.setID(ManifestResourceVolume, core.StringNilMapper(v.Name), core.StringNilMapper(v.VolumeID))
+	}
+}
+
+func (a *manifestApplier) apply(ctx context.Context, change ManifestChange) error {
+	if change.Action == ManifestActionDelete {
+		return a.applyDelete(ctx, change)
+	}
+	manifest := a.plan.manifest
+	switch change.ResourceType {
+	case ManifestResourceNetwork:
+		for _, n := range manifest.Networks {
+			if n.Name == change.Name {
+				return a.applyNetwork(ctx, change, n)
+			}
+		}
+	case ManifestResourceSshKey:
+		for _, k := range manifest.SshKeys {
+			if k.Name == change.Name {
+				return a.applySshKey(ctx, change, k)
+			}
+		}
+	case ManifestResourcePlacementGroup:
+		for _, pg := range manifest.PlacementGroups {
+			if pg.Name == change.Name {
+				return a.applyPlacementGroup(ctx, change, pg)
+			}
+		}
+	case ManifestResourceSharedProcessorPool:
+		for _, spp := range manifest.SharedProcessorPools {
+			if spp.Name == change.Name {
+				return a.applySharedProcessorPool(ctx, change, spp)
+			}
+		}
+	case ManifestResourceVolume:
+		for _, v := range manifest.Volumes {
+			if v.Name == change.Name {
+				return a.applyVolume(ctx, change, v)
+			}
+		}
+	case ManifestResourcePvmInstance:
+		for _, i := range manifest.Instances {
+			if i.Name == change.Name {
+				return a.applyInstance(ctx, change, i)
+			}
+		}
+	}
+	return fmt.Errorf("%s %q is not described in the manifest", change.ResourceType, change.Name)
+}
+
+func (a *manifestApplier) applyNetwork(ctx context.Context, change ManifestChange, desired ManifestNetwork) error {
+	powervs, cloudInstanceID := a.powervs, a.plan.CloudInstanceID
+	switch change.Action {
+	case ManifestActionCreate:
+		options := powervs.NewPcloudNetworksPostOptions(cloudInstanceID, desired.networkType()).
+			SetName(desired.Name).
+			SetDnsServers(desired.DnsServers).
+			SetIPAddressRanges(desired.IPAddressRanges)
+		if desired.CIDR != "" {
+			options.SetCIDR(desired.CIDR)
+		}
+		if desired.Gateway != "" {
+			options.SetGateway(desired.Gateway)
+		}
+		if desired.Mtu != nil {
+			options.SetMtu(*desired.Mtu)
+		}
+		network, _, err := powervs.PcloudNetworksPostWithContext(ctx, options)
+		if err != nil {
+			return err
+		}
+		a.setID(ManifestResourceNetwork, desired.Name, core.StringNilMapper(network.NetworkID))
+	case ManifestActionUpdate:
+		options := powervs.NewPcloudNetworksPutOptions(cloudInstanceID, change.ID)
+		for _, f := range change.Fields {
+			switch f.Field {
+			case "gateway":
+				options.SetGateway(desired.Gateway)
+			case "dnsServers":
+				options.SetDnsServers(desired.DnsServers)
+			case "ipAddressRanges":
+				options.SetIPAddressRanges(desired.IPAddressRanges)
+			}
+		}
+		_, _, err := powervs.PcloudNetworksPutWithContext(ctx, options)
+		return err
+	}
+	return nil
+}
+
+func (a *manifestApplier) applySshKey(ctx context.Context, change ManifestChange, desired ManifestSshKey) error {
+	powervs, tenantID := a.powervs, a.plan.TenantID
+	if tenantID == "" {
+		return fmt.Errorf("tenant of workspace %s is unknown", a.plan.CloudInstanceID)
+	}
+	switch change.Action {
+	case ManifestActionCreate:
+		_, _, err := powervs.PcloudTenantsSshkeysPostWithContext(ctx, powervs.NewPcloudTenantsSshkeysPostOptions(tenantID, desired.Name, desired.SshKey))
+		return err
+	case ManifestActionUpdate:
+		_, _, err := powervs.PcloudTenantsSshkeysPutWithContext(ctx, powervs.NewPcloudTenantsSshkeysPutOptions(tenantID, desired.Name, desired.Name, desired.SshKey))
+		return err
+	}
+	return nil
+}
+
+func (a *manifestApplier) applyPlacementGroup(ctx context.Context, change ManifestChange, desired ManifestPlacementGroup) error {
+	powervs, cloudInstanceID := a.powervs, a.plan.CloudInstanceID
+	switch change.Action {
+	case ManifestActionCreate:
+		pg, _, err := powervs.PcloudPlacementgroupsPostWithContext(ctx, powervs.NewPcloudPlacementgroupsPostOptions(cloudInstanceID, desired.Name, desired.Policy))
+		if err != nil {
+			return err
+		}
+		a.setID(ManifestResourcePlacementGroup, desired.Name, core.StringNilMapper(pg.ID))
+	}
+	return nil
+}
+
+func (a *manifestApplier) applySharedProcessorPool(ctx context.Context, change ManifestChange, desired ManifestSharedProcessorPool) error {
+	powervs, cloudInstanceID := a.powervs, a.plan.CloudInstanceID
+	switch change.Action {
+	case ManifestActionCreate:
+		spp, _, err := powervs.PcloudSharedprocessorpoolsPostWithContext(ctx, powervs.NewPcloudSharedprocessorpoolsPostOptions(cloudInstanceID, desired.HostGroup, desired.Name, desired.ReservedCores))
+		if err != nil {
+			return err
+		}
+		a.setID(ManifestResourceSharedProcessorPool, desired.Name, core.StringNilMapper(spp.ID))
+	case ManifestActionUpdate:
+		options := powervs.NewPcloudSharedprocessorpoolsPutOptions(cloudInstanceID, change.ID).SetReservedCores(desired.ReservedCores)
+		_, _, err := powervs.PcloudSharedprocessorpoolsPutWithContext(ctx, options)
+		return err
+	}
+	return nil
+}
+
+func (a *manifestApplier) applyVolume(ctx context.Context, change ManifestChange, desired ManifestVolume) error {
+	powervs, cloudInstanceID := a.powervs, a.plan.CloudInstanceID
+	switch change.Action {
+	case ManifestActionCreate:
+		options := powervs.NewPcloudCloudinstancesVolumesPostOptions(cloudInstanceID, desired.Name, desired.Size)
+		if desired.DiskType != "" {
+			options.SetDiskType(desired.DiskType)
+		}
+		if desired.VolumePool != "" {
+			options.SetVolumePool(desired.VolumePool)
+		}
+		if desired.Shareable != nil {
+			options.SetShareable(*desired.Shareable)
+		}
+		if desired.ReplicationEnabled != nil {
+			options.SetReplicationEnabled(*desired.ReplicationEnabled)
+		}
+		volume, _, err := powervs.PcloudCloudinstancesVolumesPostWithContext(ctx, options)
+		if err != nil {
+			return err
+		}
+		volumeID := core.StringNilMapper(volume.VolumeID)
+		a.setID(ManifestResourceVolume, desired.Name, volumeID)
+		return powervs.waitForVolumeState(ctx, cloudInstanceID, volumeID, "available")
+	case ManifestActionUpdate:
+		options := powervs.NewPcloudCloudinstancesVolumesPutOptions(cloudInstanceID, change.ID)
+		for _, f := range change.Fields {
+			switch f.Field {
+			case "size":
+				options.SetSize(desired.Size)
+			case "shareable":
+				options.SetShareable(boolValue(desired.Shareable))
+			}
+		}
+		_, _, err := powervs.PcloudCloudinstancesVolumesPutWithContext(ctx, options)
+		return err
+	}
+	return nil
+}
+
+func (a *manifestApplier) applyInstance(ctx context.Context, change ManifestChange, desired ManifestInstance) error {
+	powervs, cloudInstanceID := a.powervs, a.plan.CloudInstanceID
+	switch change.Action {
+	case ManifestActionCreate:
+		options := powervs.NewPcloudPvminstancesPostOptions(cloudInstanceID, desired.ImageID, desired.Memory, desired.ProcType, desired.Processors, desired.Name)
+		for _, n := range desired.Networks {
+			network := PvmInstanceAddNetwork{NetworkID: core.StringPtr(a.ids[ManifestResourceNetwork][n.Name])}
+			if n.IPAddress != "" {
+				network.IPAddress = core.StringPtr(n.IPAddress)
+			}
+			options.Networks = append(options.Networks, network)
+		}
+		for _, v := range desired.Volumes {
+			options.VolumeIDs = append(options.VolumeIDs, a.ids[ManifestResourceVolume][v])
+		}
+		if desired.KeyPairName != "" {
+			options.SetKeyPairName(desired.KeyPairName)
+		}
+		if desired.PlacementGroup != "" {
+			options.SetPlacementGroup(a.ids[ManifestResourcePlacementGroup][desired.PlacementGroup])
+		}
+		if desired.SharedProcessorPool != "" {
+			options.SetSharedProcessorPool(a.ids[ManifestResourceSharedProcessorPool][desired.SharedProcessorPool])
+		}
+		if desired.SysType != "" {
+			options.SetSysType(desired.SysType)
+		}
+		if desired.StorageType != "" {
+			options.SetStorageType(desired.StorageType)
+		}
+		if desired.UserData != "" {
+			options.SetUserData(desired.UserData)
+		}
+		_, _, err := powervs.PcloudPvminstancesPostWithContext(ctx, options)
+		return err
+	case ManifestActionUpdate:
+		options := powervs.NewPcloudPvminstancesPutOptions(cloudInstanceID, change.ID)
+		for _, f := range change.Fields {
+			switch f.Field {
+			case "memory":
+				options.SetMemory(desired.Memory)
+			case "processors":
+				options.SetProcessors(desired.Processors)
+			case "procType":
+				options.SetProcType(desired.ProcType)
+			}
+		}
+		_, _, err := powervs.PcloudPvminstancesPutWithContext(ctx, options)
+		return err
+	}
+	return nil
+}
+
+// applyDelete Delete a live resource and wait for asynchronous deletions to complete
+func (a *manifestApplier) applyDelete(ctx context.Context, change ManifestChange) error {
+	powervs, cloudInstanceID := a.powervs, a.plan.CloudInstanceID
+	var err error
+	switch change.ResourceType {
+	case ManifestResourcePvmInstance:
+		_, _, err = powervs.PcloudPvminstancesDeleteWithContext(ctx, powervs.NewPcloudPvminstancesDeleteOptions(cloudInstanceID, change.ID))
+		if err == nil {
+			err = powervs.waitForPvmInstanceDeleted(ctx, cloudInstanceID, change.ID)
+		}
+	case ManifestResourceVolume:
+		_, _, err = powervs.PcloudCloudinstancesVolumesDeleteWithContext(ctx, powervs.NewPcloudCloudinstancesVolumesDeleteOptions(cloudInstanceID, change.ID))
+	case ManifestResourceSharedProcessorPool:
+		_, _, err = powervs.PcloudSharedprocessorpoolsDeleteWithContext(ctx, powervs.NewPcloudSharedprocessorpoolsDeleteOptions(cloudInstanceID, change.ID))
+	case ManifestResourcePlacementGroup:
+		_, _, err = powervs.PcloudPlacementgroupsDeleteWithContext(ctx, powervs.NewPcloudPlacementgroupsDeleteOptions(cloudInstanceID, change.ID))
+	case ManifestResourceNetwork:
+		_, _, err = powervs.PcloudNetworksDeleteWithContext(ctx, powervs.NewPcloudNetworksDeleteOptions(cloudInstanceID, change.ID))
+	default:
+		err = fmt.Errorf("deleting %s is not supported", change.ResourceType)
+	}
+	return err
+}
+
+// waitForVolumeState Wait until a volume reaches the given state, failing if it errors
+func (powervs *PowervsV1) waitForVolumeState(ctx context.Context, cloudInstanceID, volumeID, state string) error {
+	return waitFor(ctx, func() (bool, error) {
+		volume, _, err := powervs.PcloudCloudinstancesVolumesGetWithContext(ctx, powervs.NewPcloudCloudinstancesVolumesGetOptions(cloudInstanceID, volumeID))
+		if err != nil {
+			return false, err
+		}
+		current := core.StringNilMapper(volume.State)
+		if strings.EqualFold(current, "error") {
+			return false, fmt.Errorf("volume %s is in error state", volumeID)
+		}
+		return strings.EqualFold(current, state), nil
+	})
+}
+
+// waitForPvmInstanceDeleted Wait until an instance no longer exists
+func (powervs *PowervsV1) waitForPvmInstanceDeleted(ctx context.Context, cloudInstanceID, pvmInstanceID string) error {
+	return waitFor(ctx, func() (bool, error) {
+		_, response, err := powervs.PcloudPvminstancesGetWithContext(ctx, powervs.NewPcloudPvminstancesGetOptions(cloudInstanceID, pvmInstanceID))
+		return isNotFound(response), ignoreNotFound(response, err)
+	})
+}
+
+// isNotFound Whether the response reports a missing resource
+func isNotFound(response *core.DetailedResponse) bool {
+	return response != nil && response.StatusCode == http.StatusNotFound
+}
+
+// ignoreNotFound Drop the error of a not found response
+func ignoreNotFound(response *core.DetailedResponse, err error) error {
+	if isNotFound(response) {
+		return nil
+	}
+	return err
+}
+
+func (n ManifestNetwork) networkType() string {
+	if n.Type == "" {
+		return PcloudNetworksPostOptionsTypeVlanConst
+	}
+	return n.Type
+}
+
+// diffString Record a change when desired is set and differs from current
+func diffString(changes []ManifestFieldChange, field, current, desired string) []ManifestFieldChange {
+	if desired != "" && current != desired {
+		changes = append(changes, ManifestFieldChange{field, current, desired})
+	}
+	return changes
+}
+
+// diffStrings Record a change when two lists differ, unlike diffString an empty desired list is compared
+func diffStrings(changes []ManifestFieldChange, field string, current, desired []string) []ManifestFieldChange {
+	if strings.Join(current, ",") != strings.Join(desired, ",") || len(current) != len(desired) {
+		changes = append(changes, ManifestFieldChange{field, strings.Join(current, ","), strings.Join(desired, ",")})
+	}
+	return changes
+}
+
+func formatInt64(i *int64) string {
+	if i == nil {
+		return ""
+	}
+	return strconv.FormatInt(*i, 10)
+}
+
+func formatBool(b *bool) string {
+	if b == nil {
+		return ""
+	}
+	return strconv.FormatBool(*b)
+}
+
+func formatIPAddressRanges(ranges []IPAddressRange) string {
+	parts := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		parts = append(parts, core.StringNilMapper(r.StartingIPAddress)+"-"+core.StringNilMapper(r.EndingIPAddress))
+	}
+	return strings.Join(parts, ",")
+}
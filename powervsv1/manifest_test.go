@@ -0,0 +1,278 @@
+package powervsv1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+const testManifest = `
+prune: true
+networks:
+  - name: app-net
+    cidr: 192.168.10.0/24
+    gateway: 192.168.10.1
+    dnsServers: [9.9.9.9]
+sshKeys:
+  - name: ci-key
+    sshKey: ssh-rsa AAAA
+volumes:
+  - name: data
+    size: 100
+    diskType: tier1
+instances:
+  - name: app-1
+    imageID: image-1
+    memory: 4
+    processors: 0.5
+    procType: shared
+    keyPairName: ci-key
+    networks:
+      - name: app-net
+    volumes: [data]
+`
+
+func TestParseWorkspaceManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: "Valid manifest",
+			data: testManifest,
+		},
+		{
+			name:    "Unknown field",
+			data:    "networks:\n  - name: a\n    cdir: 10.0.0.0/24\n",
+			wantErr: "unknown field",
+		},
+		{
+			name:    "Duplicate names",
+			data:    "volumes:\n  - name: a\n    size: 1\n  - name: a\n    size: 2\n",
+			wantErr: `duplicate volume "a"`,
+		},
+		{
+			name:    "Missing name",
+			data:    "placementGroups:\n  - policy: affinity\n",
+			wantErr: "placementGroup without a name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseWorkspaceManifest([]byte(tt.data))
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("ParseWorkspaceManifest() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("ParseWorkspaceManifest() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWorkspaceManifest_Plan(t *testing.T) {
+	manifest, err := ParseWorkspaceManifest([]byte(testManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		state *WorkspaceState
+		want  []string
+	}{
+		{
+			name:  "Empty workspace",
+			state: &WorkspaceState{CloudInstanceID: "ws"},
+			want: []string{
+				"create network app-net",
+				"create sshKey ci-key",
+				"create volume data",
+				"create pvmInstance app-1",
+			},
+		},
+		{
+			name: "Drifted workspace",
+			state: &WorkspaceState{
+				CloudInstanceID: "ws",
+				Networks: []Network{{
+					Name:       core.StringPtr("app-net"),
+					NetworkID:  core.StringPtr("net-1"),
+					Type:       core.StringPtr("vlan"),
+					CIDR:       core.StringPtr("192.168.20.0/24"),
+					Gateway:    core.StringPtr("192.168.10.1"),
+					DnsServers: []string{"127.0.0.1"},
+				}},
+				SshKeys: []SshKey{{Name: core.StringPtr("ci-key"), SshKey: core.StringPtr("ssh-rsa AAAA\n")}},
+				Volumes: []VolumeReference{
+					{Name: core.StringPtr("data"), VolumeID: core.StringPtr("vol-1"), Size: core.Float64Ptr(50), DiskType: core.StringPtr("tier1"), PvmInstanceIDs: []string{"pvm-1"}},
+					{Name: core.StringPtr("app-1-boot"), VolumeID: core.StringPtr("vol-2"), BootVolume: core.BoolPtr(true)},
+					{Name: core.StringPtr("scratch"), VolumeID: core.StringPtr("vol-3")},
+					{Name: core.StringPtr("logs"), VolumeID: core.StringPtr("vol-4"), PvmInstanceIDs: []string{"pvm-1"}},
+				},
+				PvmInstances: []PvmInstanceReference{{
+					ServerName:    core.StringPtr("app-1"),
+					PvmInstanceID: core.StringPtr("pvm-1"),
+					ImageID:       core.StringPtr("image-1"),
+					Memory:        core.Float64Ptr(8),
+					Processors:    core.Float64Ptr(0.5),
+					ProcType:      core.StringPtr("shared"),
+					Networks:      []PvmInstanceNetwork{{NetworkID: core.StringPtr("net-1")}},
+				}},
+			},
+			want: []string{
+				"update network app-net",
+				"drift network app-net",
+				"update volume data",
+				"update pvmInstance app-1",
+				"drift pvmInstance app-1",
+				"drift volume logs",
+				"delete volume scratch",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := manifest.Plan(tt.state)
+			if err != nil {
+				t.Fatalf("Plan() error = %v", err)
+			}
+			var got []string
+			for _, c := range plan.Changes {
+				got = append(got, c.Action+" "+c.ResourceType+" "+c.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Plan() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkspaceManifest_PlanResourcesInUse(t *testing.T) {
+	manifest, err := ParseWorkspaceManifest([]byte(testManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := &WorkspaceState{
+		CloudInstanceID: "ws",
+		Networks: []Network{
+			{Name: core.StringPtr("app-net"), NetworkID: core.StringPtr("net-1"), Type: core.StringPtr("vlan"), CIDR: core.StringPtr("192.168.10.0/24"), Gateway: core.StringPtr("192.168.10.1"), DnsServers: []string{"9.9.9.9"}},
+			{Name: core.StringPtr("legacy"), NetworkID: core.StringPtr("net-2")},
+		},
+		SshKeys:              []SshKey{{Name: core.StringPtr("ci-key"), SshKey: core.StringPtr("ssh-rsa AAAA")}},
+		PlacementGroups:      []PlacementGroup{{Name: core.StringPtr("spread"), ID: core.StringPtr("pg-1"), Members: []string{"pvm-1", "pvm-2"}}, {Name: core.StringPtr("unused"), ID: core.StringPtr("pg-2")}},
+		SharedProcessorPools: []SharedProcessorPool{{Name: core.StringPtr("pool"), ID: core.StringPtr("spp-1")}},
+		Volumes:              []VolumeReference{{Name: core.StringPtr("data"), VolumeID: core.StringPtr("vol-1"), Size: core.Float64Ptr(100), DiskType: core.StringPtr("tier1"), PvmInstanceIDs: []string{"pvm-1"}}},
+		PvmInstances: []PvmInstanceReference{
+			{ServerName: core.StringPtr("app-1"), PvmInstanceID: core.StringPtr("pvm-1"), ImageID: core.StringPtr("image-1"), Memory: core.Float64Ptr(4), Processors: core.Float64Ptr(0.5), ProcType: core.StringPtr("shared"),
+				SharedProcessorPoolID: core.StringPtr("spp-1"), Networks: []PvmInstanceNetwork{{NetworkID: core.StringPtr("net-1")}, {NetworkID: core.StringPtr("net-2"), NetworkName: core.StringPtr("legacy")}}},
+			{ServerName: core.StringPtr("old"), PvmInstanceID: core.StringPtr("pvm-2"), Networks: []PvmInstanceNetwork{{NetworkID: core.StringPtr("net-2")}}},
+		},
+	}
+	plan, err := manifest.Plan(state)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	var got []string
+	for _, c := range plan.Changes {
+		got = append(got, c.String())
+	}
+	want := []string{
+		"! pvmInstance \"app-1\"\n    networks: \"app-net,legacy\" => \"app-net\"",
+		"- pvmInstance \"old\"",
+		"! sharedProcessorPool \"pool\"\n    pvmInstances: \"app-1\" => \"\"",
+		"! placementGroup \"spread\"\n    pvmInstances: \"app-1\" => \"\"",
+		"- placementGroup \"unused\"",
+		"! network \"legacy\"\n    pvmInstances: \"app-1\" => \"\"",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Plan() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestWorkspaceManifest_PlanUnknownReference(t *testing.T) {
+	manifest := &WorkspaceManifest{
+		Instances: []ManifestInstance{{Name: "app-1", Networks: []ManifestInstanceNetwork{{Name: "missing"}}}},
+	}
+	_, err := manifest.Plan(&WorkspaceState{})
+	if err == nil || !strings.Contains(err.Error(), `unknown network "missing"`) {
+		t.Errorf("Plan() error = %v", err)
+	}
+}
+
+func TestApplyWorkspacePlan(t *testing.T) {
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = time.Millisecond
+
+	var requests []string
+	var instanceBody map[string]interface{}
+	volumeGets := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "POST /pcloud/v1/cloud-instances/ws/networks":
+			w.WriteHeader(201)
+			_, _ = w.Write([]byte(`{"networkID": "net-1", "name": "app-net"}`))
+		case "POST /pcloud/v1/tenants/tenant/sshkeys":
+			w.WriteHeader(201)
+			_, _ = w.Write([]byte(`{"name": "ci-key"}`))
+		case "POST /pcloud/v1/cloud-instances/ws/volumes":
+			w.WriteHeader(202)
+			_, _ = w.Write([]byte(`{"volumeID": "vol-1", "name": "data", "state": "creating"}`))
+		case "GET /pcloud/v1/cloud-instances/ws/volumes/vol-1":
+			volumeGets++
+			state := "creating"
+			if volumeGets > 1 {
+				state = "available"
+			}
+			_, _ = w.Write([]byte(`{"volumeID": "vol-1", "state": "` + state + `"}`))
+		case "POST /pcloud/v1/cloud-instances/ws/pvm-instances":
+			_ = json.NewDecoder(r.Body).Decode(&instanceBody)
+			w.WriteHeader(201)
+			_, _ = w.Write([]byte(`[{"pvmInstanceID": "pvm-1"}]`))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := ParseWorkspaceManifest([]byte(testManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := manifest.Plan(&WorkspaceState{CloudInstanceID: "ws", TenantID: "tenant"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := powervs.ApplyWorkspacePlan(context.Background(), plan)
+	if err != nil {
+		t.Fatalf("ApplyWorkspacePlan() error = %v, requests = %v", err, requests)
+	}
+	if len(result.Applied) != 4 || result.Failed != nil {
+		t.Errorf("ApplyWorkspacePlan() applied %d changes, failed %v", len(result.Applied), result.Failed)
+	}
+	if volumeGets != 2 {
+		t.Errorf("ApplyWorkspacePlan() polled volume %d times, want 2", volumeGets)
+	}
+	wantNetworks := []interface{}{map[string]interface{}{"networkID": "net-1"}}
+	if !reflect.DeepEqual(instanceBody["networks"], wantNetworks) {
+		t.Errorf("instance networks = %v, want %v", instanceBody["networks"], wantNetworks)
+	}
+	if !reflect.DeepEqual(instanceBody["volumeIDs"], []interface{}{"vol-1"}) {
+		t.Errorf("instance volumeIDs = %v", instanceBody["volumeIDs"])
+	}
+}
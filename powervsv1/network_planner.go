@@ -0,0 +1,190 @@
+package powervsv1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// Sources of a NetworkRange
+const (
+	NetworkRangeSourceNetwork        = "network"
+	NetworkRangeSourcePeeringNetwork = "peeringNetwork"
+	NetworkRangeSourceGreTunnel      = "greTunnel"
+	NetworkRangeSourceVPNPeerSubnet  = "vpnPeerSubnet"
+)
+
+// NetworkRange : Address range already used by a workspace
+type NetworkRange struct {
+	// One of the NetworkRangeSource constants.
+	Source string `json:"source"`
+
+	// ID of the network, cloud connection or VPN connection, project name of a peering network.
+	ID string `json:"id"`
+
+	Name string `json:"name"`
+
+	CIDR string `json:"cidr"`
+}
+
+// String : Source, name and CIDR of the range
+func (r NetworkRange) String() string {
+	return fmt.Sprintf("%s %s (%s)", r.Source, r.Name, r.CIDR)
+}
+
+// NetworkPlanner : Address ranges used by the networks of a workspace, its peering networks, the
+// GRE tunnels of its cloud connections and the peer subnets of its VPN connections
+type NetworkPlanner struct {
+	Ranges []NetworkRange
+}
+
+// NewNetworkPlanner : Collect the address ranges used by a workspace
+func (powervs *PowervsV1) NewNetworkPlanner(ctx context.Context, cloudInstanceID string) (*NetworkPlanner, error) {
+	inventory, err := powervs.GetWorkspaceInventory(ctx, cloudInstanceID)
+	if err != nil {
+		return nil, err
+	}
+	var peeringNetworks []PeeringNetwork
+	if inventory.TenantID != "" {
+		tenant, _, err := powervs.PcloudTenantsGetWithContext(ctx, powervs.NewPcloudTenantsGetOptions(inventory.TenantID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tenant %s: %w", inventory.TenantID, err)
+		}
+		peeringNetworks = tenant.PeeringNetworks
+	}
+	return NewNetworkPlannerFromInventory(inventory, peeringNetworks), nil
+}
+
+// NewNetworkPlannerFromInventory : Collect the address ranges used by an exported inventory
+// and the peering networks of its tenant
+func NewNetworkPlannerFromInventory(inventory *WorkspaceInventory, peeringNetworks []PeeringNetwork) *NetworkPlanner {
+	planner := &NetworkPlanner{}
+	for _, network := range inventory.Networks {
+		if network.CIDR != nil {
+			planner.Ranges = append(planner.Ranges, NetworkRange{NetworkRangeSourceNetwork, core.StringNilMapper(network.NetworkID), core.StringNilMapper(network.Name), *network.CIDR})
+		}
+	}
+	for _, peering := range peeringNetworks {
+		planner.Ranges = append(planner.Ranges, NetworkRange{NetworkRangeSourcePeeringNetwork, core.StringNilMapper(peering.ProjectName), core.StringNilMapper(peering.ProjectName), core.StringNilMapper(peering.CIDR)})
+	}
+	for _, connection := range inventory.CloudConnections {
+		if connection.Classic == nil || connection.Classic.Gre == nil {
+			continue
+		}
+		// The tunnel endpoints are single addresses
+		for _, address := range []*string{connection.Classic.Gre.SourceIPAddress, connection.Classic.Gre.DestIPAddress} {
+			if address != nil {
+				planner.Ranges = append(planner.Ranges, NetworkRange{NetworkRangeSourceGreTunnel, core.StringNilMapper(connection.CloudConnectionID), core.StringNilMapper(connection.Name), *address + "/32"})
+			}
+		}
+	}
+	for _, connection := range inventory.VPNConnections {
+		for _, subnet := range connection.PeerSubnets {
+			planner.Ranges = append(planner.Ranges, NetworkRange{NetworkRangeSourceVPNPeerSubnet, core.StringNilMapper(connection.ID), core.StringNilMapper(connection.Name), subnet})
+		}
+	}
+	return planner
+}
+
+// Overlaps : Used ranges overlapping a CIDR
+func (planner *NetworkPlanner) Overlaps(cidr string) ([]NetworkRange, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+	}
+	var overlaps []NetworkRange
+	for _, r := range planner.Ranges {
+		if _, used, err := net.ParseCIDR(r.CIDR); err == nil && (used.Contains(network.IP) || network.Contains(used.IP)) {
+			overlaps = append(overlaps, r)
+		}
+	}
+	return overlaps, nil
+}
+
+// Check : Validate a network creation with ValidateOptions and report the used ranges its CIDR
+// overlaps, all violations in a ValidationErrors
+func (planner *NetworkPlanner) Check(options *PcloudNetworksPostOptions) error {
+	if err := core.ValidateNotNil(options, "options cannot be nil"); err != nil {
+		return err
+	}
+	var errs ValidationErrors
+	if err := ValidateOptions(options); err != nil && !errors.As(err, &errs) {
+		return err
+	}
+	if options.CIDR != nil {
+		overlaps, err := planner.Overlaps(*options.CIDR)
+		if err == nil {
+			for _, r := range overlaps {
+				errs.add("cidr", "%s overlaps %s", *options.CIDR, r)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// NextFreeCIDR : First IPv4 CIDR with the given prefix length inside the supernet that overlaps
+// no used range
+func (planner *NetworkPlanner) NextFreeCIDR(supernet string, prefixLength int) (string, error) {
+	_, parent, err := net.ParseCIDR(supernet)
+	if err != nil || parent.IP.To4() == nil {
+		return "", fmt.Errorf("invalid IPv4 supernet %q", supernet)
+	}
+	parentLength, _ := parent.Mask.Size()
+	if prefixLength < parentLength || prefixLength > 32 {
+		return "", fmt.Errorf("prefix length %d does not fit in %s", prefixLength, supernet)
+	}
+
+	type span struct{ start, end uint64 }
+	var used []span
+	for _, r := range planner.Ranges {
+		if _, network, err := net.ParseCIDR(r.CIDR); err == nil && network.IP.To4() != nil {
+			start, end := ipv4Span(network)
+			used = append(used, span{start, end})
+		}
+	}
+	sort.Slice(used, func(i, j int) bool { return used[i].start < used[j].start })
+
+	size := uint64(1) << (32 - prefixLength)
+	start, last := ipv4Span(parent)
+	for candidate := start; candidate+size-1 <= last; {
+		next := candidate
+		for _, s := range used {
+			if s.start <= candidate+size-1 && s.end >= candidate && s.end+1 > next {
+				next = s.end + 1
+			}
+		}
+		if next == candidate {
+			return fmt.Sprintf("%s/%d", ipv4String(candidate), prefixLength), nil
+		}
+		// Skip past the overlapping ranges, aligned on the block size
+		candidate = (next + size - 1) / size * size
+	}
+	return "", fmt.Errorf("no free /%d left in %s", prefixLength, supernet)
+}
+
+// PlanNetwork : Options of a vlan network on the next free CIDR of the supernet, with the first
+// address as gateway and the remaining host addresses as range
+func (planner *NetworkPlanner) PlanNetwork(cloudInstanceID string, name string, supernet string, prefixLength int) (*PcloudNetworksPostOptions, error) {
+	if prefixLength > 29 {
+		return nil, fmt.Errorf("prefix length %d leaves no room for a gateway and addresses, use at most 29", prefixLength)
+	}
+	cidr, err := planner.NextFreeCIDR(supernet, prefixLength)
+	if err != nil {
+		return nil, err
+	}
+	_, network, _ := net.ParseCIDR(cidr)
+	start, end := ipv4Span(network)
+	options := (&PcloudNetworksPostOptions{CloudInstanceID: core.StringPtr(cloudInstanceID), Type: core.StringPtr(PcloudNetworksPostOptionsTypeVlanConst)}).
+		SetName(name).
+		SetCIDR(cidr).
+		SetGateway(ipv4String(start + 1)).
+		SetIPAddressRanges([]IPAddressRange{{StartingIPAddress: core.StringPtr(ipv4String(start + 2)), EndingIPAddress: core.StringPtr(ipv4String(end - 1))}})
+	return options, nil
+}
@@ -0,0 +1,74 @@
+package powervsv1
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+func TestNetworkPlanner(t *testing.T) {
+	inventory, err := ParseInventory([]byte(`{"schemaVersion": "powervs.inventory/v1", "cloudInstanceID": "ws",
+		"networks": [{"networkID": "net-1", "name": "private", "cidr": "10.0.0.0/24"}],
+		"cloudConnections": [{"cloudConnectionID": "cc-1", "name": "classic", "classic": {"enabled": true, "gre": {"sourceIPAddress": "10.0.2.5", "destIPAddress": "172.16.0.1"}}}],
+		"vpnConnections": [{"id": "vpn-1", "name": "office", "peerSubnets": ["192.168.0.0/24"]}]}`))
+	if err != nil {
+		t.Fatalf("ParseInventory() error = %v", err)
+	}
+	planner := NewNetworkPlannerFromInventory(inventory, []PeeringNetwork{{ProjectName: core.StringPtr("legacy"), CIDR: core.StringPtr("10.0.1.0/24")}})
+
+	tests := []struct {
+		supernet     string
+		prefixLength int
+		want         string
+	}{
+		{"10.0.0.0/16", 24, "10.0.3.0/24"},
+		{"10.0.0.0/16", 28, "10.0.2.16/28"},
+		{"10.0.0.0/22", 23, ""},
+		{"172.16.0.0/12", 16, "172.17.0.0/16"},
+		{"10.0.0.0/16", 8, ""},
+	}
+	for _, tt := range tests {
+		got, err := planner.NextFreeCIDR(tt.supernet, tt.prefixLength)
+		if got != tt.want || (err != nil) != (tt.want == "") {
+			t.Errorf("NextFreeCIDR(%s, %d) = %s, %v, want %s", tt.supernet, tt.prefixLength, got, err, tt.want)
+		}
+	}
+
+	options := (&PcloudNetworksPostOptions{CloudInstanceID: core.StringPtr("ws"), Type: core.StringPtr("vlan")}).SetCIDR("192.168.0.0/16").SetGateway("192.168.0.1")
+	var errs ValidationErrors
+	if err := planner.Check(options); !errors.As(err, &errs) || !reflect.DeepEqual([]ValidationError(errs), []ValidationError{
+		{"cidr", "192.168.0.0/16 overlaps vpnPeerSubnet office (192.168.0.0/24)"},
+	}) {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	options, err = planner.PlanNetwork("ws", "batch", "10.0.0.0/16", 24)
+	if err != nil {
+		t.Fatalf("PlanNetwork() error = %v", err)
+	}
+	if *options.CIDR != "10.0.3.0/24" || *options.Gateway != "10.0.3.1" || *options.IPAddressRanges[0].StartingIPAddress != "10.0.3.2" || *options.IPAddressRanges[0].EndingIPAddress != "10.0.3.254" {
+		t.Errorf("PlanNetwork() = %+v", options)
+	}
+	if err := planner.Check(options); err != nil {
+		t.Errorf("Check() of planned network error = %v", err)
+	}
+
+	options = (&PcloudNetworksPostOptions{CloudInstanceID: core.StringPtr("ws"), Type: core.StringPtr("vlan")}).SetCIDR("10.1.0.0/24").SetGateway("10.1.0.20").SetIPAddressRanges([]IPAddressRange{
+		{StartingIPAddress: core.StringPtr("10.1.0.100"), EndingIPAddress: core.StringPtr("10.1.0.255")},
+		{StartingIPAddress: core.StringPtr("10.1.0.0"), EndingIPAddress: core.StringPtr("10.1.0.50")},
+		{StartingIPAddress: core.StringPtr("10.1.0.40"), EndingIPAddress: core.StringPtr("10.1.0.60")},
+	})
+	if err := planner.Check(options); !errors.As(err, &errs) || !reflect.DeepEqual([]ValidationError(errs), []ValidationError{
+		{"gateway", "gateway 10.1.0.20 is inside the range 10.1.0.0-10.1.0.50"},
+		{"ipAddressRanges", "range 10.1.0.0-10.1.0.50 includes the network or broadcast address 10.1.0.0"},
+		{"ipAddressRanges", "ranges 10.1.0.0-10.1.0.50 and 10.1.0.40-10.1.0.60 overlap"},
+		{"ipAddressRanges", "range 10.1.0.100-10.1.0.255 includes the network or broadcast address 10.1.0.255"},
+	}) {
+		t.Errorf("Check() of inconsistent ranges error = %v", err)
+	}
+	if err := planner.Check(nil); err == nil {
+		t.Error("Check(nil) expected error")
+	}
+}
@@ -0,0 +1,270 @@
+package powervsv1
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// PlacementGroupMember : Instance of a placement group and the host it runs on
+type PlacementGroupMember struct {
+	PvmInstanceID string `json:"pvmInstanceID"`
+
+	ServerName string `json:"serverName"`
+
+	// Nil while the instance is not placed on a host.
+	HostID *int64 `json:"hostID,omitempty"`
+}
+
+// PlacementGroupReport : Compliance of a placement group with its policy
+type PlacementGroupReport struct {
+	PlacementGroupID string `json:"placementGroupID"`
+
+	Name string `json:"name"`
+
+	Policy string `json:"policy"`
+
+	Members []PlacementGroupMember `json:"members"`
+
+	// Whether the hosts of the members respect the policy.
+	Compliant bool `json:"compliant"`
+
+	Violations []string `json:"violations,omitempty"`
+}
+
+// String : Human readable rendering of the report
+func (report *PlacementGroupReport) String() string {
+	status := "compliant"
+	if !report.Compliant {
+		status = "not compliant"
+	}
+	lines := []string{fmt.Sprintf("%s placement group %s (%s): %s", report.Policy, report.Name, report.PlacementGroupID, status)}
+	for _, member := range report.Members {
+		host := "not placed"
+		if member.HostID != nil {
+			host = fmt.Sprintf("host %d", *member.HostID)
+		}
+		lines = append(lines, fmt.Sprintf("  %s (%s): %s", member.ServerName, member.PvmInstanceID, host))
+	}
+	for _, violation := range report.Violations {
+		lines = append(lines, "  violation: "+violation)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// VerifyPlacementGroup : Check that the members of an anti-affinity placement group run on
+// distinct hosts, and that the members of an affinity placement group share their host
+func (powervs *PowervsV1) VerifyPlacementGroup(ctx context.Context, cloudInstanceID string, placementGroupID string) (*PlacementGroupReport, error) {
+	group, _, err := powervs.PcloudPlacementgroupsGetWithContext(ctx, powervs.NewPcloudPlacementgroupsGetOptions(cloudInstanceID, placementGroupID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get placement group %s: %w", placementGroupID, err)
+	}
+	return powervs.verifyPlacementGroup(ctx, cloudInstanceID, group)
+}
+
+// VerifyPlacementGroups : Verify every placement group of a workspace
+func (powervs *PowervsV1) VerifyPlacementGroups(ctx context.Context, cloudInstanceID string) ([]*PlacementGroupReport, error) {
+	groups, _, err := powervs.PcloudPlacementgroupsGetallWithContext(ctx, powervs.NewPcloudPlacementgroupsGetallOptions(cloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list placement groups: %w", err)
+	}
+	reports := make([]*PlacementGroupReport, 0, len(groups.PlacementGroups))
+	for i := range groups.PlacementGroups {
+		report, err := powervs.verifyPlacementGroup(ctx, cloudInstanceID, &groups.PlacementGroups[i])
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func (powervs *PowervsV1) verifyPlacementGroup(ctx context.Context, cloudInstanceID string, group *PlacementGroup) (*PlacementGroupReport, error) {
+	report := &PlacementGroupReport{
+		PlacementGroupID: *group.ID,
+		Name:             *group.Name,
+		Policy:           *group.Policy,
+		Members:          make([]PlacementGroupMember, len(group.Members)),
+	}
+	err := hydrate(ctx, len(group.Members), nil, func(i int) (string, *core.DetailedResponse, error) {
+		instance, response, err := powervs.PcloudPvminstancesGetWithContext(ctx, powervs.NewPcloudPvminstancesGetOptions(cloudInstanceID, group.Members[i]))
+		if err == nil {
+			report.Members[i] = PlacementGroupMember{PvmInstanceID: group.Members[i], ServerName: core.StringNilMapper(instance.ServerName), HostID: instance.HostID}
+		}
+		return group.Members[i], response, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get members of placement group %s: %w", *group.Name, err)
+	}
+
+	hosts := map[int64][]string{}
+	var hostIDs []int64
+	for _, member := range report.Members {
+		if member.HostID == nil {
+			continue
+		}
+		if _, ok := hosts[*member.HostID]; !ok {
+			hostIDs = append(hostIDs, *member.HostID)
+		}
+		hosts[*member.HostID] = append(hosts[*member.HostID], member.ServerName)
+	}
+	sort.Slice(hostIDs, func(i, j int) bool { return hostIDs[i] < hostIDs[j] })
+	switch report.Policy {
+	case PcloudPlacementgroupsPostOptionsPolicyAntiAffinityConst:
+		for _, hostID := range hostIDs {
+			if len(hosts[hostID]) > 1 {
+				report.Violations = append(report.Violations, fmt.Sprintf("instances %s share host %d", strings.Join(hosts[hostID], ", "), hostID))
+			}
+		}
+	case PcloudPlacementgroupsPostOptionsPolicyAffinityConst:
+		if len(hostIDs) > 1 {
+			var placements []string
+			for _, hostID := range hostIDs {
+				placements = append(placements, fmt.Sprintf("%s on host %d", strings.Join(hosts[hostID], ", "), hostID))
+			}
+			report.Violations = append(report.Violations, "instances are spread over several hosts: "+strings.Join(placements, "; "))
+		}
+	}
+	report.Compliant = len(report.Violations) == 0
+	return report, nil
+}
+
+// PlacementGroupCapacityOptions : The CheckPlacementGroupCapacity options.
+type PlacementGroupCapacityOptions struct {
+	CloudInstanceID string `validate:"required"`
+
+	PlacementGroupID string `validate:"required"`
+
+	// Number of members to add.
+	Count int `validate:"required,min=1"`
+
+	// Processors of each new member.
+	Processors float64 `validate:"required"`
+
+	// Memory in GB of each new member.
+	Memory float64 `validate:"required"`
+
+	// System type of the new members, any system type when empty.
+	SysType string
+}
+
+// PlacementGroupCapacity : Whether new members can be placed according to the group policy
+type PlacementGroupCapacity struct {
+	Feasible bool `json:"feasible"`
+
+	// Number of new members the systems can host.
+	Available int `json:"available"`
+
+	// Systems able to host new members.
+	HostIDs []int64 `json:"hostIDs,omitempty"`
+
+	Message string `json:"message,omitempty"`
+}
+
+// CheckPlacementGroupCapacity : Predict whether Count more members fit in a placement group.
+// Members of an anti-affinity group need one system each, not already hosting a member and with
+// room for one instance. Members of an affinity group all go to the system of the current
+// members, or to a single system with room for all of them when the group is empty.
+func (powervs *PowervsV1) CheckPlacementGroupCapacity(ctx context.Context, options *PlacementGroupCapacityOptions) (*PlacementGroupCapacity, error) {
+	if err := core.ValidateNotNil(options, "options cannot be nil"); err != nil {
+		return nil, err
+	}
+	if err := core.ValidateStruct(options, "placementGroupCapacityOptions"); err != nil {
+		return nil, err
+	}
+	report, err := powervs.VerifyPlacementGroup(ctx, options.CloudInstanceID, options.PlacementGroupID)
+	if err != nil {
+		return nil, err
+	}
+	pools, _, err := powervs.PcloudSystempoolsGetWithContext(ctx, powervs.NewPcloudSystempoolsGetOptions(options.CloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get system pools: %w", err)
+	}
+
+	var systems []System
+	sysTypes := make([]string, 0, len(pools))
+	for sysType := range pools {
+		sysTypes = append(sysTypes, sysType)
+	}
+	sort.Strings(sysTypes)
+	for _, sysType := range sysTypes {
+		if options.SysType == "" || options.SysType == sysType {
+			systems = append(systems, pools[sysType].Systems...)
+		}
+	}
+	used := map[int64]bool{}
+	for _, member := range report.Members {
+		if member.HostID != nil {
+			used[*member.HostID] = true
+		}
+	}
+
+	request := &deployRequest{processors: options.Processors, memory: options.Memory}
+	capacity := &PlacementGroupCapacity{}
+	switch report.Policy {
+	case PcloudPlacementgroupsPostOptionsPolicyAntiAffinityConst:
+		for _, system := range systems {
+			if system.ID != nil && !used[*system.ID] && request.instancesPerSystem(system) >= 1 {
+				capacity.Available++
+				capacity.HostIDs = append(capacity.HostIDs, *system.ID)
+			}
+		}
+		if capacity.Available < options.Count {
+			capacity.Message = fmt.Sprintf("%d systems without a member can host an instance, %d needed", capacity.Available, options.Count)
+		}
+	default:
+		for _, system := range systems {
+			if system.ID == nil || (len(used) > 0 && !used[*system.ID]) {
+				continue
+			}
+			hosted := int(request.instancesPerSystem(system))
+			if hosted >= options.Count {
+				capacity.HostIDs = append(capacity.HostIDs, *system.ID)
+			}
+			if hosted > capacity.Available {
+				capacity.Available = hosted
+			}
+		}
+		if capacity.Available < options.Count {
+			capacity.Message = fmt.Sprintf("the largest eligible system can host %d instances, %d needed on the same host", capacity.Available, options.Count)
+		}
+	}
+	capacity.Feasible = capacity.Available >= options.Count
+	return capacity, nil
+}
+
+// AddPlacementGroupMember : Add an instance to a placement group and wait until it is a member
+func (powervs *PowervsV1) AddPlacementGroupMember(ctx context.Context, cloudInstanceID string, placementGroupID string, pvmInstanceID string) error {
+	_, _, err := powervs.PcloudPlacementgroupsMembersPostWithContext(ctx, powervs.NewPcloudPlacementgroupsMembersPostOptions(cloudInstanceID, placementGroupID, pvmInstanceID))
+	if err != nil {
+		return fmt.Errorf("failed to add instance %s to placement group %s: %w", pvmInstanceID, placementGroupID, err)
+	}
+	return powervs.waitForPlacementGroupMember(ctx, cloudInstanceID, placementGroupID, pvmInstanceID, true)
+}
+
+// RemovePlacementGroupMember : Remove an instance from a placement group and wait until it is no longer a member
+func (powervs *PowervsV1) RemovePlacementGroupMember(ctx context.Context, cloudInstanceID string, placementGroupID string, pvmInstanceID string) error {
+	_, _, err := powervs.PcloudPlacementgroupsMembersDeleteWithContext(ctx, powervs.NewPcloudPlacementgroupsMembersDeleteOptions(cloudInstanceID, placementGroupID, pvmInstanceID))
+	if err != nil {
+		return fmt.Errorf("failed to remove instance %s from placement group %s: %w", pvmInstanceID, placementGroupID, err)
+	}
+	return powervs.waitForPlacementGroupMember(ctx, cloudInstanceID, placementGroupID, pvmInstanceID, false)
+}
+
+func (powervs *PowervsV1) waitForPlacementGroupMember(ctx context.Context, cloudInstanceID, placementGroupID, pvmInstanceID string, member bool) error {
+	return waitFor(ctx, func() (bool, error) {
+		group, _, err := powervs.PcloudPlacementgroupsGetWithContext(ctx, powervs.NewPcloudPlacementgroupsGetOptions(cloudInstanceID, placementGroupID))
+		if err != nil {
+			return false, err
+		}
+		for _, id := range group.Members {
+			if id == pvmInstanceID {
+				return member, nil
+			}
+		}
+		return !member, nil
+	})
+}
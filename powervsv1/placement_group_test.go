@@ -0,0 +1,151 @@
+package powervsv1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+func newFakePlacementGroupServer(t *testing.T) *PowervsV1 {
+	var mu sync.Mutex
+	groups := map[string]*PlacementGroup{
+		"pg-anti": {ID: core.StringPtr("pg-anti"), Name: core.StringPtr("apart"), Policy: core.StringPtr("anti-affinity"), Members: []string{"pvm-1", "pvm-2", "pvm-3"}},
+		"pg-aff":  {ID: core.StringPtr("pg-aff"), Name: core.StringPtr("together"), Policy: core.StringPtr("affinity"), Members: []string{"pvm-4"}},
+	}
+	hosts := map[string]int{"pvm-1": 1, "pvm-2": 2, "pvm-3": 2, "pvm-4": 3}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/pcloud/v1/cloud-instances/ws/"), "/")
+		switch {
+		case parts[0] == "pvm-instances" && len(parts) == 2:
+			fmt.Fprintf(w, `{"pvmInstanceID": %q, "serverName": %q, "hostID": %d}`, parts[1], "vm"+strings.TrimPrefix(parts[1], "pvm-"), hosts[parts[1]])
+		case parts[0] == "system-pools":
+			_, _ = w.Write([]byte(`{"s922": {"type": "s922", "systems": [
+				{"id": 1, "cores": 20, "availableCores": 4, "memory": 512, "availableMemory": 256},
+				{"id": 2, "cores": 20, "availableCores": 4, "memory": 512, "availableMemory": 256},
+				{"id": 3, "cores": 20, "availableCores": 4, "memory": 512, "availableMemory": 64},
+				{"id": 4, "cores": 20, "availableCores": 0.5, "memory": 512, "availableMemory": 256},
+				{"id": 5, "cores": 20, "availableCores": 8, "memory": 512, "availableMemory": 256}]}}`))
+		case parts[0] == "placement-groups" && len(parts) == 1:
+			all := PlacementGroups{}
+			for _, id := range []string{"pg-aff", "pg-anti"} {
+				all.PlacementGroups = append(all.PlacementGroups, *groups[id])
+			}
+			_ = json.NewEncoder(w).Encode(all)
+		case parts[0] == "placement-groups" && len(parts) == 2:
+			_ = json.NewEncoder(w).Encode(groups[parts[1]])
+		case parts[0] == "placement-groups" && parts[2] == "members":
+			body := map[string]string{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			group := groups[parts[1]]
+			// Membership changes become visible after the response
+			defer time.AfterFunc(5*time.Millisecond, func() {
+				mu.Lock()
+				defer mu.Unlock()
+				if r.Method == http.MethodPost {
+					group.Members = append(group.Members, body["id"])
+					return
+				}
+				for i, id := range group.Members {
+					if id == body["id"] {
+						group.Members = append(group.Members[:i], group.Members[i+1:]...)
+					}
+				}
+			})
+			_ = json.NewEncoder(w).Encode(group)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return powervs
+}
+
+func TestVerifyPlacementGroups(t *testing.T) {
+	powervs := newFakePlacementGroupServer(t)
+	reports, err := powervs.VerifyPlacementGroups(context.Background(), "ws")
+	if err != nil {
+		t.Fatalf("VerifyPlacementGroups() error = %v", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("VerifyPlacementGroups() = %d reports", len(reports))
+	}
+	if !reports[0].Compliant {
+		t.Errorf("affinity group report =\n%s", reports[0])
+	}
+	if reports[1].Compliant || !reflect.DeepEqual(reports[1].Violations, []string{"instances vm2, vm3 share host 2"}) {
+		t.Errorf("anti-affinity group report =\n%s", reports[1])
+	}
+}
+
+func TestCheckPlacementGroupCapacity(t *testing.T) {
+	powervs := newFakePlacementGroupServer(t)
+	tests := []struct {
+		name          string
+		group         string
+		count         int
+		processors    float64
+		memory        float64
+		wantFeasible  bool
+		wantAvailable int
+		wantHosts     []int64
+	}{
+		{"anti-affinity fits", "pg-anti", 1, 1, 16, true, 2, []int64{3, 5}},
+		{"anti-affinity too many", "pg-anti", 3, 1, 16, false, 2, []int64{3, 5}},
+		{"affinity fits on member host", "pg-aff", 2, 2, 32, true, 2, []int64{3}},
+		{"affinity member host full", "pg-aff", 3, 1, 32, false, 2, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capacity, err := powervs.CheckPlacementGroupCapacity(context.Background(), &PlacementGroupCapacityOptions{
+				CloudInstanceID:  "ws",
+				PlacementGroupID: tt.group,
+				Count:            tt.count,
+				Processors:       tt.processors,
+				Memory:           tt.memory,
+			})
+			if err != nil {
+				t.Fatalf("CheckPlacementGroupCapacity() error = %v", err)
+			}
+			if capacity.Feasible != tt.wantFeasible || capacity.Available != tt.wantAvailable || !reflect.DeepEqual(capacity.HostIDs, tt.wantHosts) {
+				t.Errorf("CheckPlacementGroupCapacity() = %+v", capacity)
+			}
+		})
+	}
+}
+
+func TestPlacementGroupMembership(t *testing.T) {
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = time.Millisecond
+	powervs := newFakePlacementGroupServer(t)
+
+	if err := powervs.AddPlacementGroupMember(context.Background(), "ws", "pg-aff", "pvm-5"); err != nil {
+		t.Fatalf("AddPlacementGroupMember() error = %v", err)
+	}
+	group, _, _ := powervs.PcloudPlacementgroupsGet(powervs.NewPcloudPlacementgroupsGetOptions("ws", "pg-aff"))
+	if !reflect.DeepEqual(group.Members, []string{"pvm-4", "pvm-5"}) {
+		t.Errorf("members after add = %v", group.Members)
+	}
+	if err := powervs.RemovePlacementGroupMember(context.Background(), "ws", "pg-aff", "pvm-4"); err != nil {
+		t.Fatalf("RemovePlacementGroupMember() error = %v", err)
+	}
+	group, _, _ = powervs.PcloudPlacementgroupsGet(powervs.NewPcloudPlacementgroupsGetOptions("ws", "pg-aff"))
+	if !reflect.DeepEqual(group.Members, []string{"pvm-5"}) {
+		t.Errorf("members after remove = %v", group.Members)
+	}
+}
@@ -0,0 +1,211 @@
+package powervsv1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// DefaultPortReservationRetries Addresses tried per port when other clients take them first
+const DefaultPortReservationRetries = 3
+
+// PortReservationOptions : The ReservePorts options.
+type PortReservationOptions struct {
+	CloudInstanceID string `validate:"required"`
+
+	NetworkID string `validate:"required"`
+
+	// Number of ports to reserve on the next free addresses of the network ranges.
+	Count int `validate:"required_without=IPAddresses,gte=0"`
+
+	// Addresses to reserve, instead of Count free ones.
+	IPAddresses []string `validate:"omitempty,dive,ip4_addr"`
+
+	Description string
+
+	// Instance the reserved ports are attached to, left unattached when empty.
+	PvmInstanceID string
+
+	// Addresses tried per port when another client takes them first, DefaultPortReservationRetries when 0.
+	MaxRetries int
+}
+
+// ReservePorts : Reserve ports on a network, either on the given addresses or on the next
+// free addresses of the network ranges. The network ranges and existing ports are checked
+// first, and an address taken by another client in the meantime is replaced by the next free
+// one. The ports are attached to the instance when one is given. If a step fails the ports
+// already created are released.
+func (powervs *PowervsV1) ReservePorts(ctx context.Context, options *PortReservationOptions) ([]NetworkPort, error) {
+	if err := core.ValidateNotNil(options, "options cannot be nil"); err != nil {
+		return nil, err
+	}
+	if err := core.ValidateStruct(options, "portReservationOptions"); err != nil {
+		return nil, err
+	}
+	maxRetries := options.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = DefaultPortReservationRetries
+	}
+
+	network, _, err := powervs.PcloudNetworksGetWithContext(ctx, powervs.NewPcloudNetworksGetOptions(options.CloudInstanceID, options.NetworkID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network %s: %w", options.NetworkID, err)
+	}
+	count := options.Count
+	if len(options.IPAddresses) > 0 {
+		count = len(options.IPAddresses)
+	}
+	if network.IPAddressMetrics != nil && floatValue(network.IPAddressMetrics.Available) < float64(count) {
+		return nil, fmt.Errorf("network %s has %s free addresses, %d requested", options.NetworkID, formatFloat64(network.IPAddressMetrics.Available), count)
+	}
+	used, err := powervs.usedPortAddresses(ctx, options.CloudInstanceID, network)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []string
+	for _, address := range options.IPAddresses {
+		if used[address] {
+			conflicts = append(conflicts, address+" is in use")
+		} else if !inIPAddressRanges(network.IPAddressRanges, address) {
+			conflicts = append(conflicts, address+" is outside of the network ranges")
+		}
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("cannot reserve ports on network %s: %s", options.NetworkID, strings.Join(conflicts, ", "))
+	}
+
+	var ports []NetworkPort
+	fail := func(cause error) ([]NetworkPort, error) {
+		if err := powervs.ReleasePorts(ctx, options.CloudInstanceID, options.NetworkID, ports); err != nil {
+			return nil, fmt.Errorf("%w (%v)", cause, err)
+		}
+		return nil, cause
+	}
+	create := func(address string) (*NetworkPort, error) {
+		portOptions := powervs.NewPcloudNetworksPortsPostOptions(options.CloudInstanceID, options.NetworkID).SetIPAddress(address)
+		if options.Description != "" {
+			portOptions.SetDescription(options.Description)
+		}
+		port, _, err := powervs.PcloudNetworksPortsPostWithContext(ctx, portOptions)
+		return port, err
+	}
+
+	if len(options.IPAddresses) > 0 {
+		for _, address := range options.IPAddresses {
+			port, err := create(address)
+			if err != nil {
+				return fail(fmt.Errorf("failed to reserve port %s: %w", address, err))
+			}
+			ports = append(ports, *port)
+		}
+	} else {
+		next := freeAddresses(network.IPAddressRanges, used)
+		for len(ports) < count {
+			var port *NetworkPort
+			for attempt := 1; port == nil; attempt++ {
+				address, ok := next()
+				if !ok {
+					return fail(fmt.Errorf("network %s has no free address left", options.NetworkID))
+				}
+				port, err = create(address)
+				if err == nil {
+					break
+				}
+				// Another client may have taken the address, in which case try the next one
+				refreshed, listErr := powervs.usedPortAddresses(ctx, options.CloudInstanceID, network)
+				if listErr != nil || !refreshed[address] || attempt >= maxRetries {
+					return fail(fmt.Errorf("failed to reserve port %s: %w", address, err))
+				}
+				for taken := range refreshed {
+					used[taken] = true
+				}
+			}
+			ports = append(ports, *port)
+		}
+	}
+
+	if options.PvmInstanceID != "" {
+		if err := powervs.AttachPorts(ctx, options.CloudInstanceID, options.PvmInstanceID, options.NetworkID, ports); err != nil {
+			return fail(err)
+		}
+	}
+	return ports, nil
+}
+
+// AttachPorts : Attach an instance to a network on the addresses of reserved ports
+func (powervs *PowervsV1) AttachPorts(ctx context.Context, cloudInstanceID string, pvmInstanceID string, networkID string, ports []NetworkPort) error {
+	for _, port := range ports {
+		address := core.StringNilMapper(port.IPAddress)
+		_, _, err := powervs.PcloudPvminstancesNetworksPostWithContext(ctx, powervs.NewPcloudPvminstancesNetworksPostOptions(cloudInstanceID, pvmInstanceID, networkID).SetIPAddress(address))
+		if err != nil {
+			return fmt.Errorf("failed to attach instance %s to %s: %w", pvmInstanceID, address, err)
+		}
+	}
+	return nil
+}
+
+// ReleasePorts : Delete ports of a network, ignoring those already deleted
+func (powervs *PowervsV1) ReleasePorts(ctx context.Context, cloudInstanceID string, networkID string, ports []NetworkPort) error {
+	var failures []string
+	for _, port := range ports {
+		portID := core.StringNilMapper(port.PortID)
+		_, response, err := powervs.PcloudNetworksPortsDeleteWithContext(ctx, powervs.NewPcloudNetworksPortsDeleteOptions(cloudInstanceID, networkID, portID))
+		if err = ignoreNotFound(response, err); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", portID, err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to release ports %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// usedPortAddresses Addresses of the ports and gateway of a network
+func (powervs *PowervsV1) usedPortAddresses(ctx context.Context, cloudInstanceID string, network *Network) (map[string]bool, error) {
+	ports, _, err := powervs.PcloudNetworksPortsGetallWithContext(ctx, powervs.NewPcloudNetworksPortsGetallOptions(cloudInstanceID, core.StringNilMapper(network.NetworkID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ports of network %s: %w", core.StringNilMapper(network.NetworkID), err)
+	}
+	used := map[string]bool{}
+	if network.Gateway != nil {
+		used[*network.Gateway] = true
+	}
+	for _, port := range ports.Ports {
+		used[core.StringNilMapper(port.IPAddress)] = true
+	}
+	return used, nil
+}
+
+// freeAddresses Iterator over the addresses of the ranges that are not used, in order. Addresses
+// added to used while iterating are skipped too.
+func freeAddresses(ranges []IPAddressRange, used map[string]bool) func() (string, bool) {
+	i := 0
+	var current, end uint64
+	started := false
+	return func() (string, bool) {
+		for i < len(ranges) {
+			if !started {
+				start, okStart := ipv4Value(core.StringNilMapper(ranges[i].StartingIPAddress))
+				last, okEnd := ipv4Value(core.StringNilMapper(ranges[i].EndingIPAddress))
+				if !okStart || !okEnd {
+					i++
+					continue
+				}
+				current, end, started = start, last, true
+			}
+			for current <= end {
+				address := ipv4String(current)
+				current++
+				if !used[address] {
+					return address, true
+				}
+			}
+			i++
+			started = false
+		}
+		return "", false
+	}
+}
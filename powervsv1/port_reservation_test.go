@@ -0,0 +1,149 @@
+package powervsv1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+func TestReservePorts(t *testing.T) {
+	var mu sync.Mutex
+	var ports map[string]string
+	var attached []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.Path, "/pcloud/v1/cloud-instances/ws")
+		switch {
+		case path == "/networks/net-1":
+			_, _ = w.Write([]byte(`{"networkID": "net-1", "name": "private", "type": "vlan", "cidr": "10.0.0.0/28", "gateway": "10.0.0.1",
+				"ipAddressRanges": [{"startingIPAddress": "10.0.0.2", "endingIPAddress": "10.0.0.4"}, {"startingIPAddress": "10.0.0.8", "endingIPAddress": "10.0.0.10"}],
+				"ipAddressMetrics": {"available": 4, "used": 2, "total": 6, "utilization": 33}}`))
+		case path == "/networks/net-1/ports" && r.Method == http.MethodGet:
+			list := NetworkPorts{Ports: []NetworkPort{}}
+			for id, address := range ports {
+				list.Ports = append(list.Ports, NetworkPort{PortID: core.StringPtr(id), IPAddress: core.StringPtr(address)})
+			}
+			_ = json.NewEncoder(w).Encode(list)
+		case path == "/networks/net-1/ports":
+			body := map[string]string{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["ipAddress"] == "10.0.0.3" {
+				// Another client takes the address first
+				ports["port-other"] = "10.0.0.3"
+				w.WriteHeader(http.StatusConflict)
+				_, _ = w.Write([]byte(`{"description": "address in use"}`))
+				return
+			}
+			id := fmt.Sprintf("port-%s", strings.TrimPrefix(body["ipAddress"], "10.0.0."))
+			ports[id] = body["ipAddress"]
+			_ = json.NewEncoder(w).Encode(NetworkPort{PortID: core.StringPtr(id), IPAddress: core.StringPtr(body["ipAddress"])})
+		case strings.HasPrefix(path, "/networks/net-1/ports/") && r.Method == http.MethodDelete:
+			delete(ports, strings.TrimPrefix(path, "/networks/net-1/ports/"))
+			_, _ = w.Write([]byte(`{}`))
+		case path == "/pvm-instances/pvm-1/networks":
+			body := map[string]string{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			attached = append(attached, body["ipAddress"])
+			_, _ = w.Write([]byte(`{"networkID": "net-1"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"description": "failed"}`))
+		}
+	}))
+	defer server.Close()
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addresses := func(list []NetworkPort) []string {
+		var all []string
+		for _, port := range list {
+			all = append(all, *port.IPAddress)
+		}
+		return all
+	}
+	remaining := func() []string {
+		var all []string
+		for _, address := range ports {
+			all = append(all, address)
+		}
+		sort.Strings(all)
+		return all
+	}
+
+	tests := []struct {
+		name          string
+		options       PortReservationOptions
+		want          []string
+		wantErr       string
+		wantRemaining []string
+		wantAttached  []string
+	}{
+		{
+			name:          "next free addresses",
+			options:       PortReservationOptions{Count: 2},
+			want:          []string{"10.0.0.8", "10.0.0.9"},
+			wantRemaining: []string{"10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.8", "10.0.0.9"},
+		},
+		{
+			name:          "too many",
+			options:       PortReservationOptions{Count: 5},
+			wantErr:       "network net-1 has 4 free addresses, 5 requested",
+			wantRemaining: []string{"10.0.0.2", "10.0.0.4"},
+		},
+		{
+			name:          "conflicting addresses",
+			options:       PortReservationOptions{IPAddresses: []string{"10.0.0.2", "10.0.0.5", "10.0.0.9"}},
+			wantErr:       "cannot reserve ports on network net-1: 10.0.0.2 is in use, 10.0.0.5 is outside of the network ranges",
+			wantRemaining: []string{"10.0.0.2", "10.0.0.4"},
+		},
+		{
+			name:          "attach",
+			options:       PortReservationOptions{IPAddresses: []string{"10.0.0.10"}, PvmInstanceID: "pvm-1"},
+			want:          []string{"10.0.0.10"},
+			wantRemaining: []string{"10.0.0.10", "10.0.0.2", "10.0.0.4"},
+			wantAttached:  []string{"10.0.0.10"},
+		},
+		{
+			name:          "released on attach failure",
+			options:       PortReservationOptions{Count: 1, PvmInstanceID: "pvm-2"},
+			wantErr:       "failed to attach instance pvm-2 to 10.0.0.8: Internal Server Error",
+			wantRemaining: []string{"10.0.0.2", "10.0.0.3", "10.0.0.4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ports = map[string]string{"port-2": "10.0.0.2", "port-4": "10.0.0.4"}
+			attached = nil
+			tt.options.CloudInstanceID, tt.options.NetworkID = "ws", "net-1"
+			reserved, err := powervs.ReservePorts(context.Background(), &tt.options)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("ReservePorts() error = %v, want %s", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("ReservePorts() error = %v", err)
+			}
+			if got := addresses(reserved); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReservePorts() = %v, want %v", got, tt.want)
+			}
+			if got := remaining(); !reflect.DeepEqual(got, tt.wantRemaining) {
+				t.Errorf("ports left = %v, want %v", got, tt.wantRemaining)
+			}
+			if !reflect.DeepEqual(attached, tt.wantAttached) {
+				t.Errorf("attached = %v, want %v", attached, tt.wantAttached)
+			}
+		})
+	}
+}
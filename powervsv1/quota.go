@@ -0,0 +1,258 @@
+package powervsv1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// Quota dimensions of CloudInstanceUsageLimits reported by GetQuotaReport
+const (
+	QuotaInstances        = "instances"
+	QuotaProcessors       = "processors"
+	QuotaProcUnits        = "procUnits"
+	QuotaMemory           = "memory"
+	QuotaStorage          = "storage"
+	QuotaStorageSSD       = "storageSSD"
+	QuotaStorageStandard  = "storageStandard"
+	QuotaPeeringNetworks  = "peeringNetworks"
+	QuotaPeeringBandwidth = "peeringBandwidth"
+)
+
+// Status of a QuotaUsage
+const (
+	QuotaStatusOK        = "ok"
+	QuotaStatusWarning   = "warning"
+	QuotaStatusExhausted = "exhausted"
+)
+
+// Formats supported by QuotaReport.Write
+const (
+	QuotaReportFormatText     = "text"
+	QuotaReportFormatJSON     = "json"
+	QuotaReportFormatMarkdown = "markdown"
+)
+
+// DefaultQuotaWarningThreshold Percent of a limit above which a dimension is reported as a warning
+const DefaultQuotaWarningThreshold = 80.0
+
+// quotaDimension Dimension of CloudInstanceUsageLimits, in report order
+type quotaDimension struct {
+	name  string
+	value func(*CloudInstanceUsageLimits) *float64
+}
+
+var quotaDimensions = []quotaDimension{
+	{QuotaInstances, func(l *CloudInstanceUsageLimits) *float64 { return l.Instances }},
+	{QuotaProcessors, func(l *CloudInstanceUsageLimits) *float64 { return l.Processors }},
+	{QuotaProcUnits, func(l *CloudInstanceUsageLimits) *float64 { return l.ProcUnits }},
+	{QuotaMemory, func(l *CloudInstanceUsageLimits) *float64 { return l.Memory }},
+	{QuotaStorage, func(l *CloudInstanceUsageLimits) *float64 { return l.Storage }},
+	{QuotaStorageSSD, func(l *CloudInstanceUsageLimits) *float64 { return l.StorageSsd }},
+	{QuotaStorageStandard, func(l *CloudInstanceUsageLimits) *float64 { return l.StorageStandard }},
+	{QuotaPeeringNetworks, func(l *CloudInstanceUsageLimits) *float64 { return int64AsFloat64(l.PeeringNetworks) }},
+	{QuotaPeeringBandwidth, func(l *CloudInstanceUsageLimits) *float64 { return int64AsFloat64(l.PeeringBandwidth) }},
+}
+
+// QuotaReportOptions : The GetQuotaReport options.
+type QuotaReportOptions struct {
+	// Workspaces to report, every workspace of the account when empty.
+	CloudInstanceIDs []string
+
+	// Percent of a limit above which a dimension is reported as a warning, DefaultQuotaWarningThreshold when 0.
+	WarningThreshold float64
+
+	// Warning thresholds overriding WarningThreshold, by quota dimension.
+	Thresholds map[string]float64
+}
+
+// threshold Warning threshold of a dimension
+func (options *QuotaReportOptions) threshold(dimension string) float64 {
+	if threshold, ok := options.Thresholds[dimension]; ok {
+		return threshold
+	}
+	if options.WarningThreshold > 0 {
+		return options.WarningThreshold
+	}
+	return DefaultQuotaWarningThreshold
+}
+
+// QuotaUsage : Usage of one quota dimension
+type QuotaUsage struct {
+	Dimension string `json:"dimension"`
+
+	Used float64 `json:"used"`
+
+	Limit float64 `json:"limit"`
+
+	Remaining float64 `json:"remaining"`
+
+	Percent float64 `json:"percent"`
+
+	// One of the QuotaStatus constants.
+	Status string `json:"status"`
+}
+
+// WorkspaceQuota : Quota usage of a workspace
+type WorkspaceQuota struct {
+	CloudInstanceID string `json:"cloudInstanceID"`
+
+	Name string `json:"name"`
+
+	Usages []QuotaUsage `json:"usages"`
+}
+
+// QuotaReport : Quota usage by workspace and across workspaces
+type QuotaReport struct {
+	Workspaces []WorkspaceQuota `json:"workspaces"`
+
+	// Used and limit summed over the workspaces reporting each dimension.
+	Total []QuotaUsage `json:"total"`
+}
+
+// GetQuotaReport : Report used, limit, remaining and percent for each quota dimension of the
+// workspaces. Dimensions without a limit are left out. Workspaces that cannot be read are
+// reported in a HydrateErrors error next to the report of the other workspaces.
+func (powervs *PowervsV1) GetQuotaReport(ctx context.Context, options *QuotaReportOptions) (*QuotaReport, error) {
+	if options == nil {
+		options = &QuotaReportOptions{}
+	}
+	cloudInstanceIDs := options.CloudInstanceIDs
+	if len(cloudInstanceIDs) == 0 {
+		workspaces, _, err := powervs.WorkspacesGetallWithContext(ctx, powervs.NewV1WorkspacesGetallOptions())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list workspaces: %w", err)
+		}
+		for _, workspace := range workspaces.Workspaces {
+			cloudInstanceIDs = append(cloudInstanceIDs, *workspace.ID)
+		}
+	}
+
+	cloudInstances := make([]*CloudInstance, len(cloudInstanceIDs))
+	err := hydrate(ctx, len(cloudInstanceIDs), nil, func(i int) (string, *core.DetailedResponse, error) {
+		cloudInstance, response, err := powervs.PcloudCloudinstancesGetWithContext(ctx, powervs.NewPcloudCloudinstancesGetOptions(cloudInstanceIDs[i]))
+		cloudInstances[i] = cloudInstance
+		return cloudInstanceIDs[i], response, err
+	})
+
+	report := &QuotaReport{}
+	totalUsed := map[string]float64{}
+	totalLimit := map[string]float64{}
+	for i, cloudInstance := range cloudInstances {
+		if cloudInstance == nil {
+			continue
+		}
+		workspace := WorkspaceQuota{CloudInstanceID: cloudInstanceIDs[i], Name: core.StringNilMapper(cloudInstance.Name)}
+		for _, dimension := range quotaDimensions {
+			if cloudInstance.Limits == nil {
+				break
+			}
+			// A missing or zero limit is not enforced
+			limit := floatValue(dimension.value(cloudInstance.Limits))
+			if limit <= 0 {
+				continue
+			}
+			used := 0.0
+			if cloudInstance.Usage != nil {
+				used = floatValue(dimension.value(cloudInstance.Usage))
+			}
+			workspace.Usages = append(workspace.Usages, newQuotaUsage(dimension.name, used, limit, options.threshold(dimension.name)))
+			totalUsed[dimension.name] += used
+			totalLimit[dimension.name] += limit
+		}
+		report.Workspaces = append(report.Workspaces, workspace)
+	}
+	for _, dimension := range quotaDimensions {
+		if limit, ok := totalLimit[dimension.name]; ok {
+			report.Total = append(report.Total, newQuotaUsage(dimension.name, totalUsed[dimension.name], limit, options.threshold(dimension.name)))
+		}
+	}
+	return report, err
+}
+
+// newQuotaUsage Compute remaining, percent and status of a dimension
+func newQuotaUsage(dimension string, used float64, limit float64, threshold float64) QuotaUsage {
+	usage := QuotaUsage{Dimension: dimension, Used: used, Limit: limit, Remaining: limit - used, Percent: used / limit * 100, Status: QuotaStatusOK}
+	switch {
+	case used >= limit:
+		usage.Status = QuotaStatusExhausted
+	case usage.Percent >= threshold:
+		usage.Status = QuotaStatusWarning
+	}
+	return usage
+}
+
+// String : Human readable rendering of the report as aligned tables
+func (report *QuotaReport) String() string {
+	var b strings.Builder
+	writeTable := func(title string, usages []QuotaUsage) {
+		fmt.Fprintf(&b, "%s\n", title)
+		tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "DIMENSION\tUSED\tLIMIT\tREMAINING\tPERCENT\tSTATUS")
+		for _, usage := range usages {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%.1f%%\t%s\n", usage.Dimension, formatFloat64(&usage.Used), formatFloat64(&usage.Limit),
+				formatFloat64(&usage.Remaining), usage.Percent, usage.Status)
+		}
+		tw.Flush()
+	}
+	for _, workspace := range report.Workspaces {
+		writeTable(fmt.Sprintf("Workspace %s (%s)", workspace.Name, workspace.CloudInstanceID), workspace.Usages)
+		b.WriteString("\n")
+	}
+	writeTable("Total", report.Total)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Markdown : Rendering of the report as Markdown tables
+func (report *QuotaReport) Markdown() string {
+	var b strings.Builder
+	writeTable := func(title string, usages []QuotaUsage) {
+		fmt.Fprintf(&b, "## %s\n\n", title)
+		b.WriteString("| Dimension | Used | Limit | Remaining | Percent | Status |\n")
+		b.WriteString("|---|---:|---:|---:|---:|---|\n")
+		for _, usage := range usages {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %.1f%% | %s |\n", usage.Dimension, formatFloat64(&usage.Used), formatFloat64(&usage.Limit),
+				formatFloat64(&usage.Remaining), usage.Percent, usage.Status)
+		}
+		b.WriteString("\n")
+	}
+	for _, workspace := range report.Workspaces {
+		writeTable(fmt.Sprintf("Workspace %s (%s)", markdownCell(workspace.Name), workspace.CloudInstanceID), workspace.Usages)
+	}
+	writeTable("Total", report.Total)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Write : Render the report as text, JSON or Markdown
+func (report *QuotaReport) Write(w io.Writer, format string) error {
+	var data []byte
+	switch strings.ToLower(format) {
+	case QuotaReportFormatText, "":
+		data = []byte(report.String() + "\n")
+	case QuotaReportFormatJSON:
+		raw, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(raw, '\n')
+	case QuotaReportFormatMarkdown, "md":
+		data = []byte(report.Markdown())
+	default:
+		return fmt.Errorf("unsupported quota report format %q", format)
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+func int64AsFloat64(i *int64) *float64 {
+	if i == nil {
+		return nil
+	}
+	f := float64(*i)
+	return &f
+}
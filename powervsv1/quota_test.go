@@ -0,0 +1,133 @@
+package powervsv1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+func TestGetQuotaReport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch strings.TrimPrefix(r.URL.Path, "/pcloud/v1") {
+		case "/v1/workspaces":
+			_, _ = w.Write([]byte(`{"workspaces": [{"id": "ws"}, {"id": "ws2"}, {"id": "gone"}]}`))
+		case "/cloud-instances/ws":
+			_, _ = w.Write([]byte(`{"cloudInstanceID": "ws", "name": "prod", "enabled": true, "initialized": true, "openstackID": "o", "region": "dal", "tenantID": "tenant", "capabilities": [], "pvmInstances": [],
+				"limits": {"instances": 10, "memory": 100, "procUnits": 10, "processors": 0, "storage": 1000, "peeringNetworks": 2},
+				"usage": {"instances": 9, "memory": 50, "procUnits": 10, "processors": 4, "storage": 100, "peeringNetworks": 1}}`))
+		case "/cloud-instances/ws2":
+			_, _ = w.Write([]byte(`{"cloudInstanceID": "ws2", "name": "dev", "enabled": true, "initialized": true, "openstackID": "o", "region": "dal", "tenantID": "tenant", "capabilities": [], "pvmInstances": [],
+				"limits": {"instances": 10, "memory": 100, "procUnits": 10, "processors": 0, "storage": 1000},
+				"usage": {"instances": 1, "memory": 20, "procUnits": 2, "processors": 0, "storage": 800}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"description": "not found"}`))
+		}
+	}))
+	defer server.Close()
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := powervs.GetQuotaReport(context.Background(), &QuotaReportOptions{Thresholds: map[string]float64{QuotaStorage: 50}})
+	var hydrateErrors HydrateErrors
+	if !errors.As(err, &hydrateErrors) || len(hydrateErrors) != 1 || hydrateErrors[0].ID != "gone" {
+		t.Errorf("GetQuotaReport() error = %v, want an error for workspace gone", err)
+	}
+	if len(report.Workspaces) != 2 || report.Workspaces[0].Name != "prod" || report.Workspaces[1].Name != "dev" {
+		t.Fatalf("GetQuotaReport() workspaces = %+v", report.Workspaces)
+	}
+
+	tests := []struct {
+		name   string
+		usages []QuotaUsage
+		want   []QuotaUsage
+	}{
+		{"prod", report.Workspaces[0].Usages, []QuotaUsage{
+			{QuotaInstances, 9, 10, 1, 90, QuotaStatusWarning},
+			{QuotaProcUnits, 10, 10, 0, 100, QuotaStatusExhausted},
+			{QuotaMemory, 50, 100, 50, 50, QuotaStatusOK},
+			{QuotaStorage, 100, 1000, 900, 10, QuotaStatusOK},
+			{QuotaPeeringNetworks, 1, 2, 1, 50, QuotaStatusOK},
+		}},
+		{"dev", report.Workspaces[1].Usages, []QuotaUsage{
+			{QuotaInstances, 1, 10, 9, 10, QuotaStatusOK},
+			{QuotaProcUnits, 2, 10, 8, 20, QuotaStatusOK},
+			{QuotaMemory, 20, 100, 80, 20, QuotaStatusOK},
+			{QuotaStorage, 800, 1000, 200, 80, QuotaStatusWarning},
+		}},
+		{"total", report.Total, []QuotaUsage{
+			{QuotaInstances, 10, 20, 10, 50, QuotaStatusOK},
+			{QuotaProcUnits, 12, 20, 8, 60, QuotaStatusOK},
+			{QuotaMemory, 70, 200, 130, 35, QuotaStatusOK},
+			{QuotaStorage, 900, 2000, 1100, 45, QuotaStatusOK},
+			{QuotaPeeringNetworks, 1, 2, 1, 50, QuotaStatusOK},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.usages, tt.want) {
+				t.Errorf("usages =\n%+v\nwant\n%+v", tt.usages, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuotaReportWrite(t *testing.T) {
+	report := &QuotaReport{
+		Workspaces: []WorkspaceQuota{{CloudInstanceID: "ws", Name: "prod", Usages: []QuotaUsage{{QuotaInstances, 9, 10, 1, 90, QuotaStatusWarning}}}},
+		Total:      []QuotaUsage{{QuotaInstances, 9, 10, 1, 90, QuotaStatusWarning}},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{QuotaReportFormatText, strings.Join([]string{
+			"Workspace prod (ws)",
+			"DIMENSION  USED  LIMIT  REMAINING  PERCENT  STATUS",
+			"instances  9     10     1          90.0%    warning",
+			"",
+			"Total",
+			"DIMENSION  USED  LIMIT  REMAINING  PERCENT  STATUS",
+			"instances  9     10     1          90.0%    warning",
+			"",
+		}, "\n")},
+		{QuotaReportFormatMarkdown, strings.Join([]string{
+			"## Workspace prod (ws)",
+			"",
+			"| Dimension | Used | Limit | Remaining | Percent | Status |",
+			"|---|---:|---:|---:|---:|---|",
+			"| instances | 9 | 10 | 1 | 90.0% | warning |",
+			"",
+			"## Total",
+			"",
+			"| Dimension | Used | Limit | Remaining | Percent | Status |",
+			"|---|---:|---:|---:|---:|---|",
+			"| instances | 9 | 10 | 1 | 90.0% | warning |",
+			"",
+		}, "\n")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := report.Write(&out, tt.format); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("Write() =\n%s\nwant\n%s", out.String(), tt.want)
+			}
+		})
+	}
+	if err := report.Write(&bytes.Buffer{}, "xml"); err == nil {
+		t.Error("Write() expected error for unsupported format")
+	}
+}
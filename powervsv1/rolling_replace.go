@@ -0,0 +1,330 @@
+package powervsv1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// Stages reached by an instance during a rolling replacement, in order
+const (
+	ReplacementStageCaptured        = "captured"
+	ReplacementStageVolumesDetached = "volumes-detached"
+	ReplacementStageDeleted         = "deleted"
+	ReplacementStageRedeployed      = "redeployed"
+	ReplacementStageHealthy         = "healthy"
+)
+
+// RollingReplaceOptions : The RollingReplace options.
+type RollingReplaceOptions struct {
+	CloudInstanceID string `validate:"required"`
+
+	// Instances to rebuild, replaced in this order.
+	PvmInstanceIDs []string `validate:"required,min=1"`
+
+	// Image to redeploy the instances from.
+	ImageID string `validate:"required"`
+
+	// Number of instances replaced concurrently, defaults to 1.
+	BatchSize int
+
+	// SSH key to inject into the redeployed instances.
+	KeyPairName string
+
+	// Cloud init user data of the redeployed instances.
+	UserData string
+
+	// Consider an instance ready once ACTIVE, without waiting for its health status to be OK.
+	// Useful for images that do not run RMC.
+	IgnoreHealth bool
+}
+
+// InstanceReplacement : Progress and captured configuration of one replaced instance
+type InstanceReplacement struct {
+	// ID of the original instance.
+	PvmInstanceID string
+
+	// ID of the redeployed instance, once created.
+	NewPvmInstanceID string
+
+	ServerName string
+
+	OriginalImageID string
+
+	// Last stage reached.
+	Stage string
+
+	// Data volumes detached from the original instance.
+	DataVolumeIDs []string
+
+	// Network attachments with the IP addresses of the original instance.
+	Networks []PvmInstanceAddNetwork
+
+	// Error that stopped the replacement.
+	Err error
+
+	// Creation options equivalent to the original instance.
+	deployOptions *PcloudPvminstancesPostOptions
+}
+
+// RollingReplaceReport : Outcome of a rolling replacement
+type RollingReplaceReport struct {
+	CloudInstanceID string
+
+	// Instances processed so far, in order. Instances after the failed batch are not listed.
+	Replacements []*InstanceReplacement
+}
+
+// Failed : Replacements that did not complete
+func (report *RollingReplaceReport) Failed() (failed []*InstanceReplacement) {
+	for _, r := range report.Replacements {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return
+}
+
+// RollingReplace : Rebuild instances from a new image in batches, keeping their IPs and data volumes.
+//
+// For every instance the data volumes are detached, the instance is deleted and redeployed
+// with the same name, sizing and IP addresses and the data volumes attached again. A batch must
+// become healthy before the next one starts. The replacement stops after the first batch with a
+// failure; RollbackInstanceReplacement restores the failed instances from the report.
+func (powervs *PowervsV1) RollingReplace(ctx context.Context, options *RollingReplaceOptions) (*RollingReplaceReport, error) {
+	if err := core.ValidateNotNil(options, "options cannot be nil"); err != nil {
+		return nil, err
+	}
+	if err := core.ValidateStruct(options, "rollingReplaceOptions"); err != nil {
+		return nil, err
+	}
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+
+	report := &RollingReplaceReport{CloudInstanceID: options.CloudInstanceID}
+	for start := 0; start < len(options.PvmInstanceIDs); start += batchSize {
+		end := start + batchSize
+		if end > len(options.PvmInstanceIDs) {
+			end = len(options.PvmInstanceIDs)
+		}
+		batch := make([]*InstanceReplacement, 0, end-start)
+		var wg sync.WaitGroup
+		for _, id := range options.PvmInstanceIDs[start:end] {
+			replacement := &InstanceReplacement{PvmInstanceID: id}
+			batch = append(batch, replacement)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				replacement.Err = powervs.replaceInstance(ctx, options, replacement)
+			}()
+		}
+		wg.Wait()
+		report.Replacements = append(report.Replacements, batch...)
+
+		var errs []string
+		for _, r := range batch {
+			if r.Err != nil {
+				errs = append(errs, fmt.Sprintf("%s (stage %s): %v", r.PvmInstanceID, r.Stage, r.Err))
+			}
+		}
+		if len(errs) > 0 {
+			return report, fmt.Errorf("rolling replace stopped: %s", strings.Join(errs, "; "))
+		}
+	}
+	return report, nil
+}
+
+// replaceInstance Rebuild one instance, recording each stage reached
+func (powervs *PowervsV1) replaceInstance(ctx context.Context, options *RollingReplaceOptions, r *InstanceReplacement) error {
+	cloudInstanceID := options.CloudInstanceID
+	if err := powervs.captureInstance(ctx, cloudInstanceID, r); err != nil {
+		return err
+	}
+	if options.KeyPairName != "" {
+		r.deployOptions.SetKeyPairName(options.KeyPairName)
+	}
+	if options.UserData != "" {
+		r.deployOptions.SetUserData(options.UserData)
+	}
+	r.Stage = ReplacementStageCaptured
+
+	for _, volumeID := range r.DataVolumeIDs {
+		_, _, err := powervs.PcloudPvminstancesVolumesDeleteWithContext(ctx, powervs.NewPcloudPvminstancesVolumesDeleteOptions(cloudInstanceID, r.PvmInstanceID, volumeID))
+		if err != nil {
+			return fmt.Errorf("failed to detach volume %s: %w", volumeID, err)
+		}
+	}
+	for _, volumeID := range r.DataVolumeIDs {
+		if err := powervs.waitForVolumeState(ctx, cloudInstanceID, volumeID, "available"); err != nil {
+			return err
+		}
+	}
+	r.Stage = ReplacementStageVolumesDetached
+
+	_, _, err := powervs.PcloudPvminstancesDeleteWithContext(ctx, powervs.NewPcloudPvminstancesDeleteOptions(cloudInstanceID, r.PvmInstanceID))
+	if err != nil {
+		return fmt.Errorf("failed to delete instance: %w", err)
+	}
+	if err := powervs.waitForPvmInstanceDeleted(ctx, cloudInstanceID, r.PvmInstanceID); err != nil {
+		return err
+	}
+	r.Stage = ReplacementStageDeleted
+
+	deployOptions := *r.deployOptions
+	deployOptions.SetImageID(options.ImageID)
+	if err := powervs.redeployInstance(ctx, cloudInstanceID, &deployOptions, r); err != nil {
+		return err
+	}
+	r.Stage = ReplacementStageRedeployed
+
+	if err := powervs.waitForPvmInstanceReady(ctx, cloudInstanceID, r.NewPvmInstanceID, options.IgnoreHealth); err != nil {
+		return err
+	}
+	r.Stage = ReplacementStageHealthy
+	return nil
+}
+
+// captureInstance Record the configuration, IP addresses and data volumes of an instance
+func (powervs *PowervsV1) captureInstance(ctx context.Context, cloudInstanceID string, r *InstanceReplacement) error {
+	instance, _, err := powervs.PcloudPvminstancesGetWithContext(ctx, powervs.NewPcloudPvminstancesGetOptions(cloudInstanceID, r.PvmInstanceID))
+	if err != nil {
+		return fmt.Errorf("failed to get instance: %w", err)
+	}
+	volumes, _, err := powervs.PcloudPvminstancesVolumesGetallWithContext(ctx, powervs.NewPcloudPvminstancesVolumesGetallOptions(cloudInstanceID, r.PvmInstanceID))
+	if err != nil {
+		return fmt.Errorf("failed to list volumes: %w", err)
+	}
+
+	r.ServerName = core.StringNilMapper(instance.ServerName)
+	r.OriginalImageID = core.StringNilMapper(instance.ImageID)
+	for _, v := range volumes.Volumes {
+		if !boolValue(v.BootVolume) {
+			r.DataVolumeIDs = append(r.DataVolumeIDs, core.StringNilMapper(v.VolumeID))
+		}
+	}
+	for _, n := range instance.Networks {
+		network := PvmInstanceAddNetwork{NetworkID: n.NetworkID}
+		if n.IPAddress != nil {
+			network.IPAddress = n.IPAddress
+		} else if n.IP != nil {
+			network.IPAddress = n.IP
+		}
+		r.Networks = append(r.Networks, network)
+	}
+
+	options := powervs.NewPcloudPvminstancesPostOptions(cloudInstanceID, r.OriginalImageID,
+		floatValue(instance.Memory), core.StringNilMapper(instance.ProcType), floatValue(instance.Processors), r.ServerName)
+	options.Networks = r.Networks
+	options.VolumeIDs = r.DataVolumeIDs
+	options.SysType = instance.SysType
+	options.StorageType = instance.StorageType
+	options.StoragePool = instance.StoragePool
+	options.PlacementGroup = instance.PlacementGroup
+	options.PinPolicy = instance.PinPolicy
+	options.VirtualCores = instance.VirtualCores
+	options.SoftwareLicenses = instance.SoftwareLicenses
+	options.LicenseRepositoryCapacity = instance.LicenseRepositoryCapacity
+	if instance.SharedProcessorPoolID != nil {
+		options.SharedProcessorPool = instance.SharedProcessorPoolID
+	} else {
+		options.SharedProcessorPool = instance.SharedProcessorPool
+	}
+	r.deployOptions = options
+	return nil
+}
+
+// redeployInstance Create the instance again and record its new ID
+func (powervs *PowervsV1) redeployInstance(ctx context.Context, cloudInstanceID string, options *PcloudPvminstancesPostOptions, r *InstanceReplacement) error {
+	created, _, err := powervs.PcloudPvminstancesPostWithContext(ctx, options)
+	if err != nil {
+		return fmt.Errorf("failed to redeploy instance: %w", err)
+	}
+	if len(created) == 0 {
+		return fmt.Errorf("failed to redeploy instance: no instance returned")
+	}
+	r.NewPvmInstanceID = core.StringNilMapper(created[0].PvmInstanceID)
+	return nil
+}
+
+// waitForPvmInstanceReady Wait until an instance is ACTIVE and, unless ignored, its health is OK
+func (powervs *PowervsV1) waitForPvmInstanceReady(ctx context.Context, cloudInstanceID, pvmInstanceID string, ignoreHealth bool) error {
+	return waitFor(ctx, func() (bool, error) {
+		instance, _, err := powervs.PcloudPvminstancesGetWithContext(ctx, powervs.NewPcloudPvminstancesGetOptions(cloudInstanceID, pvmInstanceID))
+		if err != nil {
+			return false, err
+		}
+		status := core.StringNilMapper(instance.Status)
+		if strings.EqualFold(status, "ERROR") {
+			message := ""
+			if instance.Fault != nil {
+				message = core.StringNilMapper(instance.Fault.Message)
+			}
+			return false, fmt.Errorf("instance %s is in error state %s", pvmInstanceID, message)
+		}
+		if !strings.EqualFold(status, "ACTIVE") {
+			return false, nil
+		}
+		return ignoreHealth || (instance.Health != nil && strings.EqualFold(core.StringNilMapper(instance.Health.Status), "OK")), nil
+	})
+}
+
+// RollbackInstanceReplacement : Restore an instance whose replacement failed.
+//
+// Depending on the stage reached, the data volumes are attached back to the original instance,
+// or the redeployed instance is removed and the original image is deployed again with the
+// captured IP addresses and data volumes.
+func (powervs *PowervsV1) RollbackInstanceReplacement(ctx context.Context, cloudInstanceID string, r *InstanceReplacement) error {
+	if r == nil || r.deployOptions == nil {
+		return fmt.Errorf("replacement has no captured configuration to roll back to")
+	}
+	switch r.Stage {
+	case ReplacementStageCaptured, ReplacementStageVolumesDetached:
+		// The original instance still exists, attach back whatever was detached
+		attached := map[string]bool{}
+		volumes, _, err := powervs.PcloudPvminstancesVolumesGetallWithContext(ctx, powervs.NewPcloudPvminstancesVolumesGetallOptions(cloudInstanceID, r.PvmInstanceID))
+		if err != nil {
+			return err
+		}
+		for _, v := range volumes.Volumes {
+			attached[core.StringNilMapper(v.VolumeID)] = true
+		}
+		for _, volumeID := range r.DataVolumeIDs {
+			if attached[volumeID] {
+				continue
+			}
+			if err := powervs.waitForVolumeState(ctx, cloudInstanceID, volumeID, "available"); err != nil {
+				return err
+			}
+			_, _, err := powervs.PcloudPvminstancesVolumesPostWithContext(ctx, powervs.NewPcloudPvminstancesVolumesPostOptions(cloudInstanceID, r.PvmInstanceID, volumeID))
+			if err != nil {
+				return fmt.Errorf("failed to attach volume %s: %w", volumeID, err)
+			}
+		}
+		return nil
+	case ReplacementStageRedeployed, ReplacementStageHealthy:
+		// Free the IP addresses and data volumes held by the new instance
+		_, response, err := powervs.PcloudPvminstancesDeleteWithContext(ctx, powervs.NewPcloudPvminstancesDeleteOptions(cloudInstanceID, r.NewPvmInstanceID).SetDeleteDataVolumes(false))
+		if err := ignoreNotFound(response, err); err != nil {
+			return err
+		}
+		if err := powervs.waitForPvmInstanceDeleted(ctx, cloudInstanceID, r.NewPvmInstanceID); err != nil {
+			return err
+		}
+		for _, volumeID := range r.DataVolumeIDs {
+			if err := powervs.waitForVolumeState(ctx, cloudInstanceID, volumeID, "available"); err != nil {
+				return err
+			}
+		}
+	}
+	deployOptions := *r.deployOptions
+	deployOptions.SetImageID(r.OriginalImageID)
+	if err := powervs.redeployInstance(ctx, cloudInstanceID, &deployOptions, r); err != nil {
+		return err
+	}
+	return powervs.waitForPvmInstanceStatus(ctx, cloudInstanceID, r.NewPvmInstanceID, "ACTIVE")
+}
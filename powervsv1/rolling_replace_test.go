@@ -0,0 +1,183 @@
+package powervsv1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// fakeInstanceServer Serves the instance and volume endpoints used by rolling replacements
+type fakeInstanceServer struct {
+	mu        sync.Mutex
+	instances map[string]map[string]interface{}
+	attached  map[string][]string
+	created   []map[string]interface{}
+	failImage string
+}
+
+func (f *fakeInstanceServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	write := func(status int, body interface{}) {
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/pcloud/v1/cloud-instances/ws/"), "/")
+	switch {
+	case parts[0] == "volumes" && len(parts) == 2:
+		write(200, map[string]interface{}{"volumeID": parts[1], "state": "available"})
+	case parts[0] == "pvm-instances" && len(parts) == 1 && r.Method == "POST":
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["imageID"] == f.failImage {
+			write(400, map[string]interface{}{"description": "bad image"})
+			return
+		}
+		id := fmt.Sprintf("new-%d", len(f.created))
+		f.created = append(f.created, body)
+		f.instances[id] = map[string]interface{}{"pvmInstanceID": id, "status": "ACTIVE", "health": map[string]interface{}{"status": "OK"}}
+		write(201, []interface{}{f.instances[id]})
+	case parts[0] == "pvm-instances" && len(parts) == 2:
+		instance, ok := f.instances[parts[1]]
+		if !ok {
+			write(404, map[string]interface{}{"description": "not found"})
+			return
+		}
+		if r.Method == "DELETE" {
+			delete(f.instances, parts[1])
+			write(200, map[string]interface{}{})
+			return
+		}
+		write(200, instance)
+	case parts[0] == "pvm-instances" && len(parts) == 3 && parts[2] == "volumes":
+		var volumes []interface{}
+		volumes = append(volumes, map[string]interface{}{"volumeID": parts[1] + "-boot", "bootVolume": true})
+		for _, id := range f.attached[parts[1]] {
+			volumes = append(volumes, map[string]interface{}{"volumeID": id, "bootVolume": false})
+		}
+		write(200, map[string]interface{}{"volumes": volumes})
+	case parts[0] == "pvm-instances" && len(parts) == 4 && r.Method == "DELETE":
+		var kept []string
+		for _, id := range f.attached[parts[1]] {
+			if id != parts[3] {
+				kept = append(kept, id)
+			}
+		}
+		f.attached[parts[1]] = kept
+		write(202, map[string]interface{}{})
+	default:
+		write(404, map[string]interface{}{})
+	}
+}
+
+func newFakeInstanceServer() *fakeInstanceServer {
+	instance := func(id, ip string) map[string]interface{} {
+		return map[string]interface{}{
+			"pvmInstanceID": id, "serverName": "srv-" + id, "imageID": "old-image", "status": "ACTIVE",
+			"memory": 8, "processors": 0.5, "procType": "shared", "sysType": "s922",
+			"networks": []interface{}{map[string]interface{}{"networkID": "net-1", "ipAddress": ip}},
+		}
+	}
+	return &fakeInstanceServer{
+		instances: map[string]map[string]interface{}{
+			"a": instance("a", "10.0.0.10"),
+			"b": instance("b", "10.0.0.11"),
+			"c": instance("c", "10.0.0.12"),
+		},
+		attached: map[string][]string{"a": {"vol-a"}, "b": {"vol-b"}, "c": {"vol-c"}},
+	}
+}
+
+func TestRollingReplace(t *testing.T) {
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = time.Millisecond
+
+	fake := newFakeInstanceServer()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := powervs.RollingReplace(context.Background(), &RollingReplaceOptions{
+		CloudInstanceID: "ws",
+		PvmInstanceIDs:  []string{"a", "b", "c"},
+		ImageID:         "new-image",
+		BatchSize:       2,
+	})
+	if err != nil {
+		t.Fatalf("RollingReplace() error = %v", err)
+	}
+	if len(report.Replacements) != 3 || len(report.Failed()) != 0 {
+		t.Fatalf("RollingReplace() report = %+v", report.Replacements)
+	}
+	for _, body := range fake.created {
+		if body["imageID"] != "new-image" {
+			t.Errorf("redeployed with image %v", body["imageID"])
+		}
+	}
+	byName := map[string]map[string]interface{}{}
+	for _, body := range fake.created {
+		byName[body["serverName"].(string)] = body
+	}
+	want := map[string]interface{}{
+		"imageID": "new-image", "serverName": "srv-c", "memory": float64(8), "processors": 0.5, "procType": "shared", "sysType": "s922",
+		"networks":  []interface{}{map[string]interface{}{"networkID": "net-1", "ipAddress": "10.0.0.12"}},
+		"volumeIDs": []interface{}{"vol-c"},
+	}
+	if !reflect.DeepEqual(byName["srv-c"], want) {
+		t.Errorf("redeploy body = %v, want %v", byName["srv-c"], want)
+	}
+}
+
+func TestRollingReplaceStopsOnFailure(t *testing.T) {
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = time.Millisecond
+
+	fake := newFakeInstanceServer()
+	fake.failImage = "new-image"
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := powervs.RollingReplace(context.Background(), &RollingReplaceOptions{
+		CloudInstanceID: "ws",
+		PvmInstanceIDs:  []string{"a", "b"},
+		ImageID:         "new-image",
+	})
+	if err == nil {
+		t.Fatal("RollingReplace() expected error")
+	}
+	if len(report.Replacements) != 1 {
+		t.Fatalf("RollingReplace() processed %d instances after failure, want 1", len(report.Replacements))
+	}
+	failed := report.Failed()[0]
+	if failed.Stage != ReplacementStageDeleted {
+		t.Errorf("failed stage = %s, want %s", failed.Stage, ReplacementStageDeleted)
+	}
+	if _, ok := fake.instances["b"]; !ok {
+		t.Error("instance of the next batch was replaced")
+	}
+
+	if err := powervs.RollbackInstanceReplacement(context.Background(), "ws", failed); err != nil {
+		t.Fatalf("RollbackInstanceReplacement() error = %v", err)
+	}
+	restored := fake.created[len(fake.created)-1]
+	if restored["imageID"] != "old-image" || !reflect.DeepEqual(restored["volumeIDs"], []interface{}{"vol-a"}) {
+		t.Errorf("rollback body = %v", restored)
+	}
+}
@@ -0,0 +1,268 @@
+package powervsv1
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// Storage tiers of the SAP HANA volumes
+const (
+	HanaDataTier   = "tier1"
+	HanaLogTier    = "tier1"
+	HanaSharedTier = "tier3"
+)
+
+const (
+	// hanaStripeCount Number of volumes the HANA data and log file systems are striped over
+	hanaStripeCount = 4
+
+	// Largest sizes in GB of the HANA log and shared file systems
+	hanaMaxLogSize    = 512
+	hanaMaxSharedSize = 1024
+
+	// sapCleanupTimeout Time given to delete the volumes of a failed deployment
+	sapCleanupTimeout = 5 * time.Minute
+)
+
+// SapProfileRequirements : The SelectSapProfile options.
+type SapProfileRequirements struct {
+	CloudInstanceID string `validate:"required"`
+
+	// System type the profile must support.
+	SysType string `validate:"required"`
+
+	// Minimum number of cores.
+	Cores int64
+
+	// Minimum memory in GB.
+	Memory int64
+
+	// Minimum SAPS, converted to cores with SapsPerCore.
+	Saps float64
+
+	// SAPS benchmark of one core of the system type.
+	SapsPerCore float64 `validate:"required_with=Saps"`
+
+	// Profile type such as balanced or memory, any type when empty.
+	Type string
+
+	// Also consider profiles not certified for production.
+	IncludeUncertified bool
+}
+
+// HanaVolume : Volume of the SAP HANA storage layout
+type HanaVolume struct {
+	Name string `json:"name"`
+
+	// File system the volume belongs to, such as /hana/data.
+	MountPoint string `json:"mountPoint"`
+
+	// Size in GB.
+	Size float64 `json:"size"`
+
+	DiskType string `json:"diskType"`
+}
+
+// SapDeploymentOptions : The DeploySapHana options.
+type SapDeploymentOptions struct {
+	CloudInstanceID string `validate:"required"`
+
+	Name string `validate:"required"`
+
+	ImageID string `validate:"required"`
+
+	Networks []PvmInstanceAddNetwork `validate:"required"`
+
+	// Profile to deploy, selected with Requirements when empty.
+	ProfileID string
+
+	Requirements *SapProfileRequirements `validate:"required_without=ProfileID"`
+
+	SysType string
+
+	SshKeyName string
+
+	PlacementGroup string
+
+	// Storage layout, the recommended HANA layout for the memory of the profile when empty.
+	Volumes []HanaVolume
+}
+
+// SapDeployment : Result of DeploySapHana
+type SapDeployment struct {
+	Profile *SapProfile
+
+	Volumes []Volume
+
+	PvmInstances []PvmInstance
+}
+
+// SelectSapProfile : The smallest SAP profile, by cores then memory, supporting the system type and
+// meeting the required cores, memory and SAPS. Only certified profiles are considered unless
+// IncludeUncertified is set.
+func (powervs *PowervsV1) SelectSapProfile(ctx context.Context, requirements *SapProfileRequirements) (*SapProfile, error) {
+	if err := core.ValidateNotNil(requirements, "requirements cannot be nil"); err != nil {
+		return nil, err
+	}
+	if err := core.ValidateStruct(requirements, "sapProfileRequirements"); err != nil {
+		return nil, err
+	}
+
+	profiles, _, err := powervs.PcloudSapGetallWithContext(ctx, powervs.NewPcloudSapGetallOptions(requirements.CloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SAP profiles: %w", err)
+	}
+	cores := requirements.Cores
+	if requirements.Saps > 0 {
+		if sapsCores := int64(math.Ceil(requirements.Saps / requirements.SapsPerCore)); sapsCores > cores {
+			cores = sapsCores
+		}
+	}
+
+	var candidates []SapProfile
+	for _, profile := range profiles.Profiles {
+		if !requirements.IncludeUncertified && !boolValue(profile.Certified) {
+			continue
+		}
+		if requirements.Type != "" && core.StringNilMapper(profile.Type) != requirements.Type {
+			continue
+		}
+		if int64Value(profile.Cores) < cores || int64Value(profile.Memory) < requirements.Memory {
+			continue
+		}
+		if len(profile.SupportedSystems) > 0 {
+			supported := false
+			for _, sysType := range profile.SupportedSystems {
+				supported = supported || sysType == requirements.SysType
+			}
+			if !supported {
+				continue
+			}
+		}
+		candidates = append(candidates, profile)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no SAP profile on %s has %d cores and %dGB of memory", requirements.SysType, cores, requirements.Memory)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if int64Value(a.Cores) != int64Value(b.Cores) {
+			return int64Value(a.Cores) < int64Value(b.Cores)
+		}
+		if int64Value(a.Memory) != int64Value(b.Memory) {
+			return int64Value(a.Memory) < int64Value(b.Memory)
+		}
+		return core.StringNilMapper(a.ProfileID) < core.StringNilMapper(b.ProfileID)
+	})
+	return &candidates[0], nil
+}
+
+// NewHanaStorageLayout : Recommended storage of an SAP HANA instance with memory GB of memory.
+//
+// The data file system is striped over 4 volumes totalling 1.1 times the memory, the log file
+// system over 4 volumes totalling half the memory up to 512GB, both on HanaDataTier and
+// HanaLogTier. The shared file system is a single volume the size of the memory up to 1TB on
+// HanaSharedTier.
+func NewHanaStorageLayout(name string, memory int64) []HanaVolume {
+	var layout []HanaVolume
+	stripe := func(mountPoint, suffix, diskType string, total float64) {
+		size := math.Ceil(total / hanaStripeCount)
+		for i := 1; i <= hanaStripeCount; i++ {
+			layout = append(layout, HanaVolume{Name: fmt.Sprintf("%s-%s-%d", name, suffix, i), MountPoint: mountPoint, Size: size, DiskType: diskType})
+		}
+	}
+	stripe("/hana/data", "data", HanaDataTier, float64(memory*11)/10)
+	stripe("/hana/log", "log", HanaLogTier, math.Min(float64(memory)/2, hanaMaxLogSize))
+	layout = append(layout, HanaVolume{Name: name + "-shared", MountPoint: "/hana/shared", Size: math.Min(float64(memory), hanaMaxSharedSize), DiskType: HanaSharedTier})
+	return layout
+}
+
+// DeploySapHana : Deploy an SAP HANA instance with its storage. The profile is selected from the
+// requirements unless given, the volumes of the storage layout are created and once available
+// the instance is deployed with them. The volumes are deleted if the deployment fails, also when
+// ctx is cancelled, and those that cannot be deleted are listed in the returned error.
+func (powervs *PowervsV1) DeploySapHana(ctx context.Context, options *SapDeploymentOptions) (*SapDeployment, error) {
+	if err := core.ValidateNotNil(options, "options cannot be nil"); err != nil {
+		return nil, err
+	}
+	if err := core.ValidateStruct(options, "sapDeploymentOptions"); err != nil {
+		return nil, err
+	}
+
+	deployment := &SapDeployment{}
+	var err error
+	sysType := options.SysType
+	if options.ProfileID == "" {
+		deployment.Profile, err = powervs.SelectSapProfile(ctx, options.Requirements)
+		if err != nil {
+			return nil, err
+		}
+		sysType = options.Requirements.SysType
+	} else {
+		deployment.Profile, _, err = powervs.PcloudSapGetWithContext(ctx, powervs.NewPcloudSapGetOptions(options.CloudInstanceID, options.ProfileID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get SAP profile %s: %w", options.ProfileID, err)
+		}
+	}
+
+	layout := options.Volumes
+	if len(layout) == 0 {
+		layout = NewHanaStorageLayout(options.Name, int64Value(deployment.Profile.Memory))
+	}
+	var volumeIDs []string
+	// The volumes are deleted even when ctx is cancelled, every deletion is attempted
+	cleanup := func(cause error) error {
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), sapCleanupTimeout)
+		defer cancel()
+		var failures []string
+		for _, volumeID := range volumeIDs {
+			_, response, err := powervs.PcloudCloudinstancesVolumesDeleteWithContext(cleanupCtx, powervs.NewPcloudCloudinstancesVolumesDeleteOptions(options.CloudInstanceID, volumeID))
+			if err = ignoreNotFound(response, err); err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", volumeID, err))
+			}
+		}
+		if len(failures) > 0 {
+			return fmt.Errorf("%w (failed to delete volumes %s)", cause, strings.Join(failures, "; "))
+		}
+		return cause
+	}
+	for _, spec := range layout {
+		volume, _, err := powervs.PcloudCloudinstancesVolumesPostWithContext(ctx, powervs.NewPcloudCloudinstancesVolumesPostOptions(options.CloudInstanceID, spec.Name, spec.Size).SetDiskType(spec.DiskType))
+		if err != nil {
+			return nil, cleanup(fmt.Errorf("failed to create volume %s: %w", spec.Name, err))
+		}
+		volumeIDs = append(volumeIDs, core.StringNilMapper(volume.VolumeID))
+	}
+	for _, volumeID := range volumeIDs {
+		if err := powervs.waitForVolumeState(ctx, options.CloudInstanceID, volumeID, "available"); err != nil {
+			return nil, cleanup(err)
+		}
+		volume, _, err := powervs.PcloudCloudinstancesVolumesGetWithContext(ctx, powervs.NewPcloudCloudinstancesVolumesGetOptions(options.CloudInstanceID, volumeID))
+		if err != nil {
+			return nil, cleanup(fmt.Errorf("failed to get volume %s: %w", volumeID, err))
+		}
+		deployment.Volumes = append(deployment.Volumes, *volume)
+	}
+
+	sapOptions := powervs.NewPcloudSapPostOptions(options.CloudInstanceID, options.ImageID, options.Name, options.Networks, core.StringNilMapper(deployment.Profile.ProfileID)).SetVolumeIDs(volumeIDs)
+	if sysType != "" {
+		sapOptions.SetSysType(sysType)
+	}
+	if options.SshKeyName != "" {
+		sapOptions.SetSshKeyName(options.SshKeyName)
+	}
+	if options.PlacementGroup != "" {
+		sapOptions.SetPlacementGroup(options.PlacementGroup)
+	}
+	deployment.PvmInstances, _, err = powervs.PcloudSapPostWithContext(ctx, sapOptions)
+	if err != nil {
+		return nil, cleanup(fmt.Errorf("failed to deploy SAP instance %s: %w", options.Name, err))
+	}
+	return deployment, nil
+}
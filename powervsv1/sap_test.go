@@ -0,0 +1,178 @@
+package powervsv1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+const fakeSapProfiles = `{"profiles": [
+	{"profileID": "ush1-4x256", "type": "ultra-memory", "cores": 4, "memory": 256, "certified": true, "supportedSystems": ["e980"]},
+	{"profileID": "bh1-16x1600", "type": "balanced", "cores": 16, "memory": 1600, "certified": true, "supportedSystems": ["e980", "s922"]},
+	{"profileID": "bh1-8x256", "type": "balanced", "cores": 8, "memory": 256, "certified": false},
+	{"profileID": "ch1-8x512", "type": "compute", "cores": 8, "memory": 512, "certified": true},
+	{"profileID": "mh1-8x1440", "type": "memory", "cores": 8, "memory": 1440, "certified": true, "supportedSystems": ["s922"]}]}`
+
+func TestSelectSapProfile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch strings.TrimPrefix(r.URL.Path, "/pcloud/v1") {
+		case "/cloud-instances/ws/sap":
+			_, _ = w.Write([]byte(fakeSapProfiles))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		requirements SapProfileRequirements
+		want         string
+	}{
+		{"smallest", SapProfileRequirements{SysType: "e980", Memory: 200}, "ush1-4x256"},
+		{"supported system", SapProfileRequirements{SysType: "s922", Memory: 200}, "ch1-8x512"},
+		{"memory", SapProfileRequirements{SysType: "s922", Memory: 1000}, "mh1-8x1440"},
+		{"saps", SapProfileRequirements{SysType: "s922", Saps: 20000, SapsPerCore: 2000}, "bh1-16x1600"},
+		{"uncertified", SapProfileRequirements{SysType: "s922", Type: "balanced", Cores: 8, IncludeUncertified: true}, "bh1-8x256"},
+		{"none", SapProfileRequirements{SysType: "s922", Cores: 32}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.requirements.CloudInstanceID = "ws"
+			profile, err := powervs.SelectSapProfile(context.Background(), &tt.requirements)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("SelectSapProfile() = %s, want error", *profile.ProfileID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectSapProfile() error = %v", err)
+			}
+			if *profile.ProfileID != tt.want {
+				t.Errorf("SelectSapProfile() = %s, want %s", *profile.ProfileID, tt.want)
+			}
+		})
+	}
+
+	if _, err := powervs.SelectSapProfile(context.Background(), &SapProfileRequirements{CloudInstanceID: "ws", SysType: "s922", Saps: 1000}); err == nil {
+		t.Error("SelectSapProfile() expected error for SAPS without SapsPerCore")
+	}
+}
+
+func TestNewHanaStorageLayout(t *testing.T) {
+	var got []string
+	for _, volume := range NewHanaStorageLayout("hana", 1440) {
+		got = append(got, fmt.Sprintf("%s %s %s %s", volume.Name, volume.MountPoint, formatFloat64(&volume.Size), volume.DiskType))
+	}
+	want := []string{
+		"hana-data-1 /hana/data 396 tier1", "hana-data-2 /hana/data 396 tier1", "hana-data-3 /hana/data 396 tier1", "hana-data-4 /hana/data 396 tier1",
+		"hana-log-1 /hana/log 128 tier1", "hana-log-2 /hana/log 128 tier1", "hana-log-3 /hana/log 128 tier1", "hana-log-4 /hana/log 128 tier1",
+		"hana-shared /hana/shared 1024 tier3",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewHanaStorageLayout() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDeploySapHana(t *testing.T) {
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = time.Millisecond
+
+	var mu sync.Mutex
+	volumes := map[string]*Volume{}
+	var deployed *PcloudSapPostOptions
+	failDeploy := false
+	failDelete := ""
+	cancel := func() {}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.Path, "/pcloud/v1/cloud-instances/ws")
+		switch {
+		case path == "/sap" && r.Method == http.MethodGet:
+			_, _ = w.Write([]byte(fakeSapProfiles))
+		case path == "/sap":
+			if failDeploy {
+				cancel()
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"description": "no capacity"}`))
+				return
+			}
+			deployed = &PcloudSapPostOptions{}
+			_ = json.NewDecoder(r.Body).Decode(deployed)
+			_, _ = w.Write([]byte(`[{"pvmInstanceID": "pvm-1", "serverName": "hana"}]`))
+		case path == "/volumes":
+			body := PcloudCloudinstancesVolumesPostOptions{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			id := fmt.Sprintf("vol-%d", len(volumes)+1)
+			volumes[id] = &Volume{VolumeID: core.StringPtr(id), Name: body.Name, Size: body.Size, DiskType: body.DiskType, State: core.StringPtr("creating")}
+			_ = json.NewEncoder(w).Encode(volumes[id])
+		case r.Method == http.MethodDelete && path == "/volumes/"+failDelete:
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"description": "volume busy"}`))
+		case r.Method == http.MethodDelete:
+			delete(volumes, strings.TrimPrefix(path, "/volumes/"))
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			volume := volumes[strings.TrimPrefix(path, "/volumes/")]
+			_ = json.NewEncoder(w).Encode(volume)
+			volume.State = core.StringPtr("available")
+		}
+	}))
+	defer server.Close()
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := &SapDeploymentOptions{
+		CloudInstanceID: "ws",
+		Name:            "hana",
+		ImageID:         "img-1",
+		Networks:        []PvmInstanceAddNetwork{{NetworkID: core.StringPtr("net-1")}},
+		Requirements:    &SapProfileRequirements{CloudInstanceID: "ws", SysType: "e980", Memory: 256},
+	}
+	deployment, err := powervs.DeploySapHana(context.Background(), options)
+	if err != nil {
+		t.Fatalf("DeploySapHana() error = %v", err)
+	}
+	if *deployment.Profile.ProfileID != "ush1-4x256" || len(deployment.Volumes) != 9 || *deployment.PvmInstances[0].PvmInstanceID != "pvm-1" {
+		t.Errorf("DeploySapHana() = %+v", deployment)
+	}
+	if *deployed.ProfileID != "ush1-4x256" || *deployed.SysType != "e980" || len(deployed.VolumeIDs) != 9 {
+		t.Errorf("DeploySapHana() deployed %+v", deployed)
+	}
+
+	failDeploy = true
+	volumes = map[string]*Volume{}
+	if _, err := powervs.DeploySapHana(context.Background(), options); err == nil || len(volumes) != 0 {
+		t.Errorf("DeploySapHana() error = %v, %d volumes left", err, len(volumes))
+	}
+
+	// Every volume is deleted after a failed deletion, with the cancelled context of the caller
+	volumes = map[string]*Volume{}
+	failDelete = "vol-2"
+	ctx, cancelDeploy := context.WithCancel(context.Background())
+	defer cancelDeploy()
+	cancel = cancelDeploy
+	_, err = powervs.DeploySapHana(ctx, options)
+	if err == nil || !strings.Contains(err.Error(), "failed to delete volumes vol-2:") || len(volumes) != 1 || volumes["vol-2"] == nil {
+		t.Errorf("DeploySapHana() error = %v, volumes left %v", err, volumes)
+	}
+}
@@ -0,0 +1,373 @@
+package powervsv1
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// SharedProcessorPoolPlanOptions : The PlanSharedProcessorPools options.
+type SharedProcessorPoolPlanOptions struct {
+	CloudInstanceID string `validate:"required"`
+
+	// Instances to place.
+	Instances []SharedProcessorPoolInstance `validate:"required,min=1"`
+
+	// Grow existing pools that are too small instead of creating a new pool.
+	AllowResize bool
+
+	// Host group of the pool created for the instances that fit nowhere. Defaults to the host
+	// group of the members of PlacementGroupID when it has an affinity policy.
+	HostGroup string
+
+	// Name of the pool created for the instances that fit nowhere, "<server name>-spp" by default,
+	// after the first of them in Instances.
+	NewPoolName string
+
+	// SPP placement group the created pool joins.
+	PlacementGroupID string
+}
+
+// SharedProcessorPoolInstance : Instance to place in a shared processor pool
+type SharedProcessorPoolInstance struct {
+	ServerName string `validate:"required"`
+
+	// Processing units of the instance.
+	Processors float64 `validate:"required"`
+
+	// ID or name of the pool the instance must use, any pool when empty.
+	SharedProcessorPool string
+}
+
+// SharedProcessorPoolAssignment : Pool recommended for an instance
+type SharedProcessorPoolAssignment struct {
+	ServerName string `json:"serverName"`
+
+	Processors float64 `json:"processors"`
+
+	// Empty for a pool to create until the plan is applied.
+	SharedProcessorPoolID string `json:"sharedProcessorPoolID,omitempty"`
+
+	SharedProcessorPoolName string `json:"sharedProcessorPoolName"`
+
+	// The pool is created by the plan.
+	NewPool bool `json:"newPool,omitempty"`
+}
+
+// SharedProcessorPoolResize : Reserved cores increase of an existing pool
+type SharedProcessorPoolResize struct {
+	ID string `json:"id"`
+
+	Name string `json:"name"`
+
+	ReservedCores int64 `json:"reservedCores"`
+
+	NewReservedCores int64 `json:"newReservedCores"`
+}
+
+// SharedProcessorPoolCreation : Pool to create
+type SharedProcessorPoolCreation struct {
+	Name string `json:"name"`
+
+	HostGroup string `json:"hostGroup"`
+
+	ReservedCores int64 `json:"reservedCores"`
+
+	PlacementGroupID string `json:"placementGroupID,omitempty"`
+}
+
+// SharedProcessorPoolPlan : Placement of instances in shared processor pools
+type SharedProcessorPoolPlan struct {
+	CloudInstanceID string `json:"cloudInstanceID"`
+
+	// One assignment per planned instance, in the order of the options.
+	Assignments []SharedProcessorPoolAssignment `json:"assignments"`
+
+	Resizes []SharedProcessorPoolResize `json:"resizes,omitempty"`
+
+	Creations []SharedProcessorPoolCreation `json:"creations,omitempty"`
+
+	// Reasons the plan cannot be applied, such as a placement group policy violation.
+	Issues []string `json:"issues,omitempty"`
+}
+
+// Feasible : Whether the plan can be applied
+func (plan *SharedProcessorPoolPlan) Feasible() bool {
+	return len(plan.Issues) == 0
+}
+
+// String : Human readable rendering of the plan
+func (plan *SharedProcessorPoolPlan) String() string {
+	lines := []string{}
+	for _, c := range plan.Creations {
+		line := fmt.Sprintf("create pool %s with %d reserved cores in host group %s", c.Name, c.ReservedCores, c.HostGroup)
+		if c.PlacementGroupID != "" {
+			line += " in placement group " + c.PlacementGroupID
+		}
+		lines = append(lines, line)
+	}
+	for _, r := range plan.Resizes {
+		lines = append(lines, fmt.Sprintf("resize pool %s (%s) from %d to %d reserved cores", r.Name, r.ID, r.ReservedCores, r.NewReservedCores))
+	}
+	for _, a := range plan.Assignments {
+		lines = append(lines, fmt.Sprintf("place %s (%s processors) in pool %s", a.ServerName, formatFloat64(&a.Processors), a.SharedProcessorPoolName))
+	}
+	for _, issue := range plan.Issues {
+		lines = append(lines, "issue: "+issue)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// PlanSharedProcessorPools : Recommend the existing shared processor pool each instance fits
+// into, or the pool resizes and the new pool needed to host them. Existing pools are filled
+// best fit first, the largest instances being placed first. SPP placement groups of the pools
+// used by the plan are checked against their affinity or anti-affinity policy.
+func (powervs *PowervsV1) PlanSharedProcessorPools(ctx context.Context, options *SharedProcessorPoolPlanOptions) (*SharedProcessorPoolPlan, error) {
+	if err := core.ValidateNotNil(options, "options cannot be nil"); err != nil {
+		return nil, err
+	}
+	if err := core.ValidateStruct(options, "sharedProcessorPoolPlanOptions"); err != nil {
+		return nil, err
+	}
+	for _, instance := range options.Instances {
+		if err := core.ValidateStruct(instance, "sharedProcessorPoolInstance"); err != nil {
+			return nil, err
+		}
+	}
+
+	pools, _, err := powervs.PcloudSharedprocessorpoolsGetallWithContext(ctx, powervs.NewPcloudSharedprocessorpoolsGetallOptions(options.CloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shared processor pools: %w", err)
+	}
+	placementGroups, _, err := powervs.PcloudSppplacementgroupsGetallWithContext(ctx, powervs.NewPcloudSppplacementgroupsGetallOptions(options.CloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list spp placement groups: %w", err)
+	}
+
+	plan := &SharedProcessorPoolPlan{
+		CloudInstanceID: options.CloudInstanceID,
+		Assignments:     make([]SharedProcessorPoolAssignment, len(options.Instances)),
+	}
+	available := map[string]float64{}
+	for _, pool := range pools.SharedProcessorPools {
+		available[*pool.ID] = floatValue(pool.AvailableCores)
+	}
+
+	// Largest instances first, pinned instances before the others
+	order := make([]int, len(options.Instances))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := options.Instances[order[i]], options.Instances[order[j]]
+		if (a.SharedProcessorPool != "") != (b.SharedProcessorPool != "") {
+			return a.SharedProcessorPool != ""
+		}
+		return a.Processors > b.Processors
+	})
+
+	var unplaced []int
+	for _, i := range order {
+		instance := options.Instances[i]
+		plan.Assignments[i] = SharedProcessorPoolAssignment{ServerName: instance.ServerName, Processors: instance.Processors}
+		var pool *SharedProcessorPool
+		if instance.SharedProcessorPool != "" {
+			pool = findSharedProcessorPool(pools.SharedProcessorPools, instance.SharedProcessorPool)
+			if pool == nil {
+				plan.Issues = append(plan.Issues, fmt.Sprintf("instance %s: shared processor pool %s not found", instance.ServerName, instance.SharedProcessorPool))
+				continue
+			}
+			if available[*pool.ID] < instance.Processors && !options.AllowResize {
+				plan.Issues = append(plan.Issues, fmt.Sprintf("instance %s: shared processor pool %s has %s available cores, %s needed",
+					instance.ServerName, *pool.Name, formatFloat64(core.Float64Ptr(available[*pool.ID])), formatFloat64(&instance.Processors)))
+				continue
+			}
+		} else {
+			pool = bestFitSharedProcessorPool(pools.SharedProcessorPools, available, instance.Processors, options.AllowResize)
+		}
+		if pool == nil {
+			unplaced = append(unplaced, i)
+			continue
+		}
+		available[*pool.ID] -= instance.Processors
+		plan.Assignments[i].SharedProcessorPoolID = *pool.ID
+		plan.Assignments[i].SharedProcessorPoolName = *pool.Name
+	}
+
+	for _, pool := range pools.SharedProcessorPools {
+		if available[*pool.ID] < 0 {
+			reserved := int64Value(pool.ReservedCores)
+			plan.Resizes = append(plan.Resizes, SharedProcessorPoolResize{
+				ID:               *pool.ID,
+				Name:             *pool.Name,
+				ReservedCores:    reserved,
+				NewReservedCores: reserved + int64(math.Ceil(-available[*pool.ID])),
+			})
+		}
+	}
+	used := map[string]bool{}
+	for _, assignment := range plan.Assignments {
+		used[assignment.SharedProcessorPoolID] = true
+	}
+
+	if len(unplaced) > 0 {
+		sort.Ints(unplaced)
+		creation := SharedProcessorPoolCreation{Name: options.NewPoolName, HostGroup: options.HostGroup, PlacementGroupID: options.PlacementGroupID}
+		if creation.Name == "" {
+			creation.Name = options.Instances[unplaced[0]].ServerName + "-spp"
+		}
+		total := 0.0
+		for _, i := range unplaced {
+			total += options.Instances[i].Processors
+			plan.Assignments[i].SharedProcessorPoolName = creation.Name
+			plan.Assignments[i].NewPool = true
+		}
+		creation.ReservedCores = int64(math.Max(1, math.Ceil(total)))
+		plan.Issues = append(plan.Issues, checkNewSharedProcessorPool(&creation, pools.SharedProcessorPools, placementGroups.SppPlacementGroups)...)
+		plan.Creations = append(plan.Creations, creation)
+	}
+
+	for _, group := range placementGroups.SppPlacementGroups {
+		for _, member := range group.MemberSharedProcessorPools {
+			if used[member] {
+				plan.Issues = append(plan.Issues, checkSppPlacementGroup(group, pools.SharedProcessorPools)...)
+				break
+			}
+		}
+	}
+	return plan, nil
+}
+
+// ApplySharedProcessorPoolPlan : Create and resize the pools of a plan, filling the IDs of
+// the created pools in its assignments
+func (powervs *PowervsV1) ApplySharedProcessorPoolPlan(ctx context.Context, plan *SharedProcessorPoolPlan) error {
+	if !plan.Feasible() {
+		return fmt.Errorf("shared processor pool plan cannot be applied: %s", strings.Join(plan.Issues, "; "))
+	}
+	for _, resize := range plan.Resizes {
+		options := powervs.NewPcloudSharedprocessorpoolsPutOptions(plan.CloudInstanceID, resize.ID)
+		options.SetReservedCores(resize.NewReservedCores)
+		if _, _, err := powervs.PcloudSharedprocessorpoolsPutWithContext(ctx, options); err != nil {
+			return fmt.Errorf("failed to resize shared processor pool %s: %w", resize.Name, err)
+		}
+	}
+	for _, creation := range plan.Creations {
+		options := powervs.NewPcloudSharedprocessorpoolsPostOptions(plan.CloudInstanceID, creation.HostGroup, creation.Name, creation.ReservedCores)
+		if creation.PlacementGroupID != "" {
+			options.SetPlacementGroupID(creation.PlacementGroupID)
+		}
+		pool, _, err := powervs.PcloudSharedprocessorpoolsPostWithContext(ctx, options)
+		if err != nil {
+			return fmt.Errorf("failed to create shared processor pool %s: %w", creation.Name, err)
+		}
+		for i := range plan.Assignments {
+			if plan.Assignments[i].NewPool && plan.Assignments[i].SharedProcessorPoolName == creation.Name {
+				plan.Assignments[i].SharedProcessorPoolID = *pool.ID
+			}
+		}
+	}
+	return nil
+}
+
+// findSharedProcessorPool Find a pool by ID or name
+func findSharedProcessorPool(pools []SharedProcessorPool, idOrName string) *SharedProcessorPool {
+	for i := range pools {
+		if *pools[i].ID == idOrName || *pools[i].Name == idOrName {
+			return &pools[i]
+		}
+	}
+	return nil
+}
+
+// bestFitSharedProcessorPool Pool left with the fewest available cores after hosting the
+// processors. When none fits and resizing is allowed, the pool needing the smallest increase.
+func bestFitSharedProcessorPool(pools []SharedProcessorPool, available map[string]float64, processors float64, allowResize bool) *SharedProcessorPool {
+	var best *SharedProcessorPool
+	for i := range pools {
+		left := available[*pools[i].ID] - processors
+		if left < 0 {
+			continue
+		}
+		if best == nil || left < available[*best.ID]-processors {
+			best = &pools[i]
+		}
+	}
+	if best != nil || !allowResize {
+		return best
+	}
+	for i := range pools {
+		if best == nil || available[*pools[i].ID] > available[*best.ID] {
+			best = &pools[i]
+		}
+	}
+	return best
+}
+
+// checkNewSharedProcessorPool Check the host group and placement group of the pool to create
+func checkNewSharedProcessorPool(creation *SharedProcessorPoolCreation, pools []SharedProcessorPool, groups []SppPlacementGroup) (issues []string) {
+	if creation.PlacementGroupID != "" {
+		var group *SppPlacementGroup
+		for i := range groups {
+			if *groups[i].ID == creation.PlacementGroupID || *groups[i].Name == creation.PlacementGroupID {
+				group = &groups[i]
+			}
+		}
+		if group == nil {
+			return []string{fmt.Sprintf("spp placement group %s not found", creation.PlacementGroupID)}
+		}
+		creation.PlacementGroupID = *group.ID
+		if *group.Policy == PcloudSppplacementgroupsPostOptionsPolicyAffinityConst {
+			for _, member := range group.MemberSharedProcessorPools {
+				pool := findSharedProcessorPool(pools, member)
+				if pool == nil || pool.HostGroup == nil {
+					continue
+				}
+				if creation.HostGroup == "" {
+					creation.HostGroup = *pool.HostGroup
+				}
+				if creation.HostGroup != *pool.HostGroup {
+					issues = append(issues, fmt.Sprintf("pool %s must be in host group %s of pool %s to join affinity placement group %s",
+						creation.Name, *pool.HostGroup, *pool.Name, *group.Name))
+				}
+			}
+		}
+	}
+	if creation.HostGroup == "" {
+		issues = append(issues, fmt.Sprintf("a host group is required to create pool %s", creation.Name))
+	}
+	return
+}
+
+// checkSppPlacementGroup Check that the hosts of the member pools respect the group policy
+func checkSppPlacementGroup(group SppPlacementGroup, pools []SharedProcessorPool) (issues []string) {
+	hosts := map[int64][]string{}
+	var hostIDs []int64
+	for _, member := range group.MemberSharedProcessorPools {
+		pool := findSharedProcessorPool(pools, member)
+		if pool == nil || pool.HostID == nil {
+			continue
+		}
+		if _, ok := hosts[*pool.HostID]; !ok {
+			hostIDs = append(hostIDs, *pool.HostID)
+		}
+		hosts[*pool.HostID] = append(hosts[*pool.HostID], *pool.Name)
+	}
+	switch *group.Policy {
+	case PcloudSppplacementgroupsPostOptionsPolicyAffinityConst:
+		if len(hostIDs) > 1 {
+			issues = append(issues, fmt.Sprintf("affinity placement group %s has pools on %d hosts", *group.Name, len(hostIDs)))
+		}
+	case PcloudSppplacementgroupsPostOptionsPolicyAntiAffinityConst:
+		for _, hostID := range hostIDs {
+			if len(hosts[hostID]) > 1 {
+				issues = append(issues, fmt.Sprintf("anti-affinity placement group %s has pools %s on host %d",
+					*group.Name, strings.Join(hosts[hostID], ", "), hostID))
+			}
+		}
+	}
+	return
+}
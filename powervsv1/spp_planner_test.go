@@ -0,0 +1,152 @@
+package powervsv1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+func TestPlanSharedProcessorPools(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.Path, "/pcloud/v1/cloud-instances/ws")
+		switch {
+		case r.Method == http.MethodGet && path == "/shared-processor-pools":
+			_, _ = w.Write([]byte(`{"sharedProcessorPools": [
+				{"id": "spp-1", "name": "small", "allocatedCores": 1, "availableCores": 1, "reservedCores": 2, "hostID": 1, "hostGroup": "hg-a"},
+				{"id": "spp-2", "name": "large", "allocatedCores": 1, "availableCores": 3, "reservedCores": 4, "hostID": 2, "hostGroup": "hg-a"},
+				{"id": "spp-3", "name": "other", "allocatedCores": 0, "availableCores": 2, "reservedCores": 2, "hostID": 2, "hostGroup": "hg-a"}]}`))
+		case r.Method == http.MethodGet && path == "/spp-placement-groups":
+			_, _ = w.Write([]byte(`{"sppPlacementGroups": [
+				{"id": "pg-a", "name": "together", "policy": "affinity", "memberSharedProcessorPools": ["spp-1"]},
+				{"id": "pg-b", "name": "apart", "policy": "anti-affinity", "memberSharedProcessorPools": ["spp-2", "spp-3"]}]}`))
+		case r.Method == http.MethodPost:
+			body := map[string]interface{}{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			requests = append(requests, "POST "+body["name"].(string)+" "+body["hostGroup"].(string))
+			_, _ = w.Write([]byte(`{"id": "spp-new", "name": "` + body["name"].(string) + `", "allocatedCores": 0, "availableCores": 0, "reservedCores": 1}`))
+		case r.Method == http.MethodPut:
+			requests = append(requests, "PUT "+path)
+			_, _ = w.Write([]byte(`{"id": "spp-1", "name": "small", "allocatedCores": 0, "availableCores": 0, "reservedCores": 3}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		options   SharedProcessorPoolPlanOptions
+		wantPools []string
+		wantPlan  SharedProcessorPoolPlan
+	}{
+		{
+			name: "best fit",
+			options: SharedProcessorPoolPlanOptions{Instances: []SharedProcessorPoolInstance{
+				{ServerName: "a", Processors: 0.5}, {ServerName: "b", Processors: 2}, {ServerName: "c", Processors: 1},
+			}},
+			wantPools: []string{"large", "other", "small"},
+			wantPlan: SharedProcessorPoolPlan{
+				Issues: []string{"anti-affinity placement group apart has pools large, other on host 2"},
+			},
+		},
+		{
+			name: "new pool joining affinity group",
+			options: SharedProcessorPoolPlanOptions{
+				Instances:        []SharedProcessorPoolInstance{{ServerName: "a", Processors: 4}, {ServerName: "b", Processors: 0.5}},
+				PlacementGroupID: "together",
+			},
+			wantPools: []string{"a-spp", "small"},
+			wantPlan: SharedProcessorPoolPlan{
+				Creations: []SharedProcessorPoolCreation{{Name: "a-spp", HostGroup: "hg-a", ReservedCores: 4, PlacementGroupID: "pg-a"}},
+			},
+		},
+		{
+			name: "new pool named after the first instance",
+			options: SharedProcessorPoolPlanOptions{
+				Instances: []SharedProcessorPoolInstance{{ServerName: "a", Processors: 4}, {ServerName: "b", Processors: 5}},
+				HostGroup: "hg-a",
+			},
+			wantPools: []string{"a-spp", "a-spp"},
+			wantPlan: SharedProcessorPoolPlan{
+				Creations: []SharedProcessorPoolCreation{{Name: "a-spp", HostGroup: "hg-a", ReservedCores: 9}},
+			},
+		},
+		{
+			name: "resize",
+			options: SharedProcessorPoolPlanOptions{
+				Instances:   []SharedProcessorPoolInstance{{ServerName: "a", Processors: 1.5, SharedProcessorPool: "small"}},
+				AllowResize: true,
+			},
+			wantPools: []string{"small"},
+			wantPlan: SharedProcessorPoolPlan{
+				Resizes: []SharedProcessorPoolResize{{ID: "spp-1", Name: "small", ReservedCores: 2, NewReservedCores: 3}},
+			},
+		},
+		{
+			name: "pinned pool too small",
+			options: SharedProcessorPoolPlanOptions{
+				Instances: []SharedProcessorPoolInstance{{ServerName: "a", Processors: 1.5, SharedProcessorPool: "spp-1"}},
+			},
+			wantPools: []string{""},
+			wantPlan: SharedProcessorPoolPlan{
+				Issues: []string{"instance a: shared processor pool small has 1 available cores, 1.5 needed"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.options.CloudInstanceID = "ws"
+			plan, err := powervs.PlanSharedProcessorPools(context.Background(), &tt.options)
+			if err != nil {
+				t.Fatalf("PlanSharedProcessorPools() error = %v", err)
+			}
+			var pools []string
+			for _, assignment := range plan.Assignments {
+				pools = append(pools, assignment.SharedProcessorPoolName)
+			}
+			if !reflect.DeepEqual(pools, tt.wantPools) {
+				t.Errorf("PlanSharedProcessorPools() pools = %v, want %v", pools, tt.wantPools)
+			}
+			if !reflect.DeepEqual(plan.Creations, tt.wantPlan.Creations) || !reflect.DeepEqual(plan.Resizes, tt.wantPlan.Resizes) ||
+				!reflect.DeepEqual(plan.Issues, tt.wantPlan.Issues) {
+				t.Errorf("PlanSharedProcessorPools() =\n%s", plan)
+			}
+		})
+	}
+
+	plan, err := powervs.PlanSharedProcessorPools(context.Background(), &SharedProcessorPoolPlanOptions{
+		CloudInstanceID: "ws",
+		Instances:       []SharedProcessorPoolInstance{{ServerName: "a", Processors: 1.5, SharedProcessorPool: "small"}, {ServerName: "b", Processors: 5}},
+		HostGroup:       "hg-b",
+		NewPoolName:     "batch",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := powervs.ApplySharedProcessorPoolPlan(context.Background(), plan); err == nil {
+		t.Error("ApplySharedProcessorPoolPlan() expected error for a plan with issues")
+	}
+	plan.Issues = nil
+	plan.Resizes = []SharedProcessorPoolResize{{ID: "spp-1", Name: "small", ReservedCores: 2, NewReservedCores: 3}}
+	if err := powervs.ApplySharedProcessorPoolPlan(context.Background(), plan); err != nil {
+		t.Fatalf("ApplySharedProcessorPoolPlan() error = %v", err)
+	}
+	if want := []string{"PUT /shared-processor-pools/spp-1", "POST batch hg-b"}; !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+	if plan.Assignments[1].SharedProcessorPoolID != "spp-new" {
+		t.Errorf("assignment = %+v, want created pool ID", plan.Assignments[1])
+	}
+}
@@ -0,0 +1,295 @@
+package powervsv1
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// Kinds of TopologyNode
+const (
+	TopologyNodePvmInstance         = "pvmInstance"
+	TopologyNodeNetwork             = "network"
+	TopologyNodeVolume              = "volume"
+	TopologyNodePlacementGroup      = "placementGroup"
+	TopologyNodeSharedProcessorPool = "sharedProcessorPool"
+	TopologyNodeCloudConnection     = "cloudConnection"
+	TopologyNodeVPNConnection       = "vpnConnection"
+	TopologyNodeDhcpServer          = "dhcpServer"
+)
+
+// Formats supported by Topology.Write
+const (
+	TopologyFormatDOT     = "dot"
+	TopologyFormatMermaid = "mermaid"
+)
+
+// TopologyNode : Resource of a workspace graph
+type TopologyNode struct {
+	// Resource ID, unique within the graph.
+	ID string `json:"id"`
+
+	Kind string `json:"kind"`
+
+	Label string `json:"label"`
+}
+
+// TopologyEdge : Link between two resources of a workspace graph
+type TopologyEdge struct {
+	From string `json:"from"`
+
+	To string `json:"to"`
+
+	// IP address for network links, boot or data for volume links.
+	Label string `json:"label,omitempty"`
+
+	// Links to shared volumes are drawn dashed.
+	Dashed bool `json:"dashed,omitempty"`
+}
+
+// Topology : Graph of the resources of a workspace and their links
+type Topology struct {
+	CloudInstanceID string `json:"cloudInstanceID"`
+
+	Nodes []TopologyNode `json:"nodes"`
+
+	Edges []TopologyEdge `json:"edges"`
+
+	index map[string]bool
+}
+
+// GetWorkspaceTopology : Build the graph of a workspace from its live resources
+func (powervs *PowervsV1) GetWorkspaceTopology(ctx context.Context, cloudInstanceID string) (*Topology, error) {
+	inventory, err := powervs.GetWorkspaceInventory(ctx, cloudInstanceID)
+	if err != nil {
+		return nil, err
+	}
+	return NewTopology(inventory), nil
+}
+
+// NewTopology : Build the graph of a workspace from an inventory snapshot. Resources without an
+// ID, as hand written inventories may hold, are left out.
+func NewTopology(inventory *WorkspaceInventory) *Topology {
+	topology := &Topology{CloudInstanceID: inventory.CloudInstanceID, index: map[string]bool{}}
+
+	for _, network := range inventory.Networks {
+		if network.NetworkID == nil {
+			continue
+		}
+		label := core.StringNilMapper(network.Name)
+		if network.CIDR != nil {
+			label += "\n" + *network.CIDR
+		}
+		topology.addNode(*network.NetworkID, TopologyNodeNetwork, label)
+	}
+	for _, pool := range inventory.SharedProcessorPools {
+		if pool.ID == nil {
+			continue
+		}
+		topology.addNode(*pool.ID, TopologyNodeSharedProcessorPool, core.StringNilMapper(pool.Name))
+	}
+	for _, group := range inventory.PlacementGroups {
+		if group.ID == nil {
+			continue
+		}
+		topology.addNode(*group.ID, TopologyNodePlacementGroup, fmt.Sprintf("%s\n%s", core.StringNilMapper(group.Name), core.StringNilMapper(group.Policy)))
+	}
+
+	for _, instance := range inventory.Instances {
+		if instance.PvmInstanceID == nil {
+			continue
+		}
+		id := *instance.PvmInstanceID
+		topology.addNode(id, TopologyNodePvmInstance, core.StringNilMapper(instance.ServerName))
+		for _, network := range instance.Networks {
+			if network.NetworkID == nil {
+				continue
+			}
+			if !topology.index[*network.NetworkID] {
+				topology.addNode(*network.NetworkID, TopologyNodeNetwork, core.StringNilMapper(network.NetworkName))
+			}
+			ip := core.StringNilMapper(network.IPAddress)
+			if ip == "" {
+				ip = core.StringNilMapper(network.IP)
+			}
+			topology.addEdge(TopologyEdge{From: id, To: *network.NetworkID, Label: ip})
+		}
+		for _, volume := range instance.Volumes {
+			if volume.VolumeID == nil {
+				continue
+			}
+			label := "data"
+			if boolValue(volume.BootVolume) {
+				label = "boot"
+			}
+			shared := boolValue(volume.Shareable)
+			volumeLabel := fmt.Sprintf("%s\n%sGB", core.StringNilMapper(volume.Name), formatFloat64(volume.Size))
+			if shared {
+				volumeLabel += " shared"
+			}
+			if !topology.index[*volume.VolumeID] {
+				topology.addNode(*volume.VolumeID, TopologyNodeVolume, volumeLabel)
+			}
+			topology.addEdge(TopologyEdge{From: id, To: *volume.VolumeID, Label: label, Dashed: shared})
+		}
+		if instance.SharedProcessorPoolID != nil && topology.index[*instance.SharedProcessorPoolID] {
+			topology.addEdge(TopologyEdge{From: id, To: *instance.SharedProcessorPoolID})
+		}
+	}
+	for _, group := range inventory.PlacementGroups {
+		if group.ID == nil {
+			continue
+		}
+		for _, member := range group.Members {
+			if topology.index[member] {
+				topology.addEdge(TopologyEdge{From: member, To: *group.ID})
+			}
+		}
+	}
+
+	for _, connection := range inventory.CloudConnections {
+		if connection.CloudConnectionID == nil {
+			continue
+		}
+		id := *connection.CloudConnectionID
+		topology.addNode(id, TopologyNodeCloudConnection, fmt.Sprintf("%s\n%s Mbps", core.StringNilMapper(connection.Name), formatInt64(connection.Speed)))
+		for _, network := range connection.Networks {
+			if network.NetworkID != nil && topology.index[*network.NetworkID] {
+				topology.addEdge(TopologyEdge{From: *network.NetworkID, To: id})
+			}
+		}
+	}
+	for _, vpn := range inventory.VPNConnections {
+		if vpn.ID == nil {
+			continue
+		}
+		id := *vpn.ID
+		topology.addNode(id, TopologyNodeVPNConnection, fmt.Sprintf("%s\npeer %s", core.StringNilMapper(vpn.Name), core.StringNilMapper(vpn.PeerGatewayAddress)))
+		for _, networkID := range vpn.NetworkIDs {
+			if topology.index[networkID] {
+				topology.addEdge(TopologyEdge{From: networkID, To: id})
+			}
+		}
+	}
+	for _, dhcp := range inventory.DhcpServers {
+		if dhcp.ID == nil {
+			continue
+		}
+		id := *dhcp.ID
+		topology.addNode(id, TopologyNodeDhcpServer, "DHCP "+core.StringNilMapper(dhcp.Status))
+		if dhcp.Network != nil && dhcp.Network.ID != nil && topology.index[*dhcp.Network.ID] {
+			topology.addEdge(TopologyEdge{From: *dhcp.Network.ID, To: id})
+		}
+	}
+	return topology
+}
+
+// DOT : Rendering of the graph in the Graphviz DOT language
+func (topology *Topology) DOT() string {
+	shapes := map[string]string{
+		TopologyNodePvmInstance:         "box",
+		TopologyNodeNetwork:             "ellipse",
+		TopologyNodeVolume:              "cylinder",
+		TopologyNodePlacementGroup:      "folder",
+		TopologyNodeSharedProcessorPool: "component",
+		TopologyNodeCloudConnection:     "hexagon",
+		TopologyNodeVPNConnection:       "hexagon",
+		TopologyNodeDhcpServer:          "note",
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "graph %s {\n", dotQuote(topology.CloudInstanceID))
+	b.WriteString("  rankdir=LR;\n")
+	for _, node := range topology.Nodes {
+		fmt.Fprintf(&b, "  %s [label=%s, shape=%s];\n", dotQuote(node.ID), dotQuote(node.Label), shapes[node.Kind])
+	}
+	for _, edge := range topology.Edges {
+		var attributes []string
+		if edge.Label != "" {
+			attributes = append(attributes, "label="+dotQuote(edge.Label))
+		}
+		if edge.Dashed {
+			attributes = append(attributes, "style=dashed")
+		}
+		fmt.Fprintf(&b, "  %s -- %s", dotQuote(edge.From), dotQuote(edge.To))
+		if len(attributes) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(attributes, ", "))
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid : Rendering of the graph as a Mermaid flowchart
+func (topology *Topology) Mermaid() string {
+	shapes := map[string][2]string{
+		TopologyNodePvmInstance:         {"[", "]"},
+		TopologyNodeNetwork:             {"([", "])"},
+		TopologyNodeVolume:              {"[(", ")]"},
+		TopologyNodePlacementGroup:      {"[[", "]]"},
+		TopologyNodeSharedProcessorPool: {"[[", "]]"},
+		TopologyNodeCloudConnection:     {"{{", "}}"},
+		TopologyNodeVPNConnection:       {"{{", "}}"},
+		TopologyNodeDhcpServer:          {"[/", "/]"},
+	}
+	// Mermaid identifiers cannot hold every character of a resource ID
+	ids := make(map[string]string, len(topology.Nodes))
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, node := range topology.Nodes {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+		shape := shapes[node.Kind]
+		fmt.Fprintf(&b, "  %s%s%s%s\n", ids[node.ID], shape[0], mermaidQuote(node.Label), shape[1])
+	}
+	for _, edge := range topology.Edges {
+		arrow := "---"
+		if edge.Dashed {
+			arrow = "-.-"
+		}
+		if edge.Label != "" {
+			fmt.Fprintf(&b, "  %s %s|%s| %s\n", ids[edge.From], arrow, mermaidQuote(edge.Label), ids[edge.To])
+		} else {
+			fmt.Fprintf(&b, "  %s %s %s\n", ids[edge.From], arrow, ids[edge.To])
+		}
+	}
+	return b.String()
+}
+
+// Write : Render the graph in the DOT or Mermaid format
+func (topology *Topology) Write(w io.Writer, format string) error {
+	var data string
+	switch strings.ToLower(format) {
+	case TopologyFormatDOT, "graphviz":
+		data = topology.DOT()
+	case TopologyFormatMermaid:
+		data = topology.Mermaid()
+	default:
+		return fmt.Errorf("unsupported topology format %q", format)
+	}
+	_, err := io.WriteString(w, data)
+	return err
+}
+
+// addNode Add a resource to the graph
+func (topology *Topology) addNode(id string, kind string, label string) {
+	topology.index[id] = true
+	topology.Nodes = append(topology.Nodes, TopologyNode{ID: id, Kind: kind, Label: label})
+}
+
+// addEdge Link two resources of the graph
+func (topology *Topology) addEdge(edge TopologyEdge) {
+	topology.Edges = append(topology.Edges, edge)
+}
+
+// dotQuote Quote a DOT identifier or label
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// mermaidQuote Quote a Mermaid label
+func mermaidQuote(s string) string {
+	return `"` + strings.NewReplacer(`"`, "#quot;", "\n", "<br/>").Replace(s) + `"`
+}
@@ -0,0 +1,109 @@
+package powervsv1
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWorkspaceTopology(t *testing.T) {
+	inventory, err := ParseInventory([]byte(`{"schemaVersion": "powervs.inventory/v1", "cloudInstanceID": "ws",
+		"instances": [
+			{"pvmInstanceID": "pvm-1", "serverName": "app", "networks": [{"networkID": "net-1", "ipAddress": "10.0.0.10"}],
+			 "volumes": [{"volumeID": "vol-1", "name": "boot", "bootVolume": true}, {"volumeID": "vol-2", "name": "data"}]},
+			{"pvmInstanceID": "pvm-2", "serverName": "db"}],
+		"volumes": [{"volumeID": "vol-1", "name": "boot"}, {"volumeID": "vol-2", "name": "data"}, {"volumeID": "vol-3", "name": "spare"}],
+		"networks": [{"networkID": "net-1", "name": "private"}],
+		"placementGroups": [{"id": "pg-1", "name": "ha", "members": ["pvm-1"]}],
+		"cloudConnections": [{"cloudConnectionID": "cc-1", "name": "to-vpc", "networks": [{"networkID": "net-1"}]}],
+		"vpnConnections": [{"id": "vpn-1", "name": "office", "networkIDs": ["net-1"]}],
+		"dhcpServers": [{"id": "dhcp-1", "network": {"id": "net-1", "name": "private"}}]}`))
+	if err != nil {
+		t.Fatalf("ParseInventory() error = %v", err)
+	}
+	topology := NewTopology(inventory)
+
+	edges := map[string]TopologyEdge{}
+	for _, edge := range topology.Edges {
+		edges[edge.From+"/"+edge.To] = edge
+	}
+	tests := []struct {
+		from, to string
+		label    string
+	}{
+		{"pvm-1", "net-1", "10.0.0.10"},
+		{"pvm-1", "vol-1", "boot"},
+		{"pvm-1", "vol-2", "data"},
+		{"pvm-1", "pg-1", ""},
+		{"net-1", "cc-1", ""},
+		{"net-1", "vpn-1", ""},
+		{"net-1", "dhcp-1", ""},
+	}
+	for _, tt := range tests {
+		edge, ok := edges[tt.from+"/"+tt.to]
+		if !ok || edge.Label != tt.label {
+			t.Errorf("edge %s -- %s = %+v, want label %q", tt.from, tt.to, edge, tt.label)
+		}
+	}
+	if len(topology.Edges) != len(tests) {
+		t.Errorf("NewTopology() has %d edges, want %d: %+v", len(topology.Edges), len(tests), topology.Edges)
+	}
+}
+
+func TestTopologyWithoutIDs(t *testing.T) {
+	inventory, err := ParseInventory([]byte(`{"schemaVersion": "powervs.inventory/v1", "cloudInstanceID": "ws",
+		"networks": [{"name": "n"}, {"networkID": "net-1"}], "sharedProcessorPools": [{}], "placementGroups": [{"members": ["pvm-1"]}],
+		"instances": [{"serverName": "a"}, {"pvmInstanceID": "pvm-1", "volumes": [{"name": "v"}], "networks": [{"networkName": "n"}, {"networkID": "net-1"}]}],
+		"cloudConnections": [{}], "vpnConnections": [{}], "dhcpServers": [{}]}`))
+	if err != nil {
+		t.Fatalf("ParseInventory() error = %v", err)
+	}
+	topology := NewTopology(inventory)
+	if len(topology.Nodes) != 2 || len(topology.Edges) != 1 {
+		t.Errorf("NewTopology() = %+v", topology)
+	}
+}
+
+func TestTopologyWrite(t *testing.T) {
+	topology := &Topology{
+		CloudInstanceID: "ws",
+		Nodes: []TopologyNode{
+			{ID: "pvm-1", Kind: TopologyNodePvmInstance, Label: `app "1"`},
+			{ID: "vol-1", Kind: TopologyNodeVolume, Label: "shared\n10GB"},
+		},
+		Edges: []TopologyEdge{{From: "pvm-1", To: "vol-1", Label: "data", Dashed: true}},
+	}
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{TopologyFormatDOT, []string{
+			`graph "ws" {`,
+			`  "pvm-1" [label="app \"1\"", shape=box];`,
+			`  "vol-1" [label="shared\n10GB", shape=cylinder];`,
+			`  "pvm-1" -- "vol-1" [label="data", style=dashed];`,
+		}},
+		{TopologyFormatMermaid, []string{
+			"flowchart LR",
+			`  n0["app #quot;1#quot;"]`,
+			`  n1[("shared<br/>10GB")]`,
+			`  n0 -.-|"data"| n1`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := topology.Write(&buf, tt.format); err != nil {
+				t.Fatal(err)
+			}
+			for _, line := range tt.want {
+				if !strings.Contains(buf.String(), line+"\n") {
+					t.Errorf("Write() missing line %s in\n%s", line, buf.String())
+				}
+			}
+		})
+	}
+	if err := topology.Write(&bytes.Buffer{}, "svg"); err == nil {
+		t.Error("Write() expected error for unsupported format")
+	}
+}
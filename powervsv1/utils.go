@@ -2,13 +2,18 @@ package powervsv1
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/IBM-Cloud/power-go-client/power/client"
 	"github.com/IBM/go-sdk-core/v5/core"
@@ -21,6 +26,162 @@ const (
 	SCHEME_HTTP  = "http"
 )
 
+// pollInterval Delay between two status checks while waiting on a resource
+var pollInterval = 10 * time.Second
+
+// waitFor Call check every pollInterval until it reports done, fails or ctx is cancelled
+func waitFor(ctx context.Context, check func() (bool, error)) error {
+	for {
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// boolValue Value of an optional flag, false when unset
+func boolValue(b *bool) bool {
+	return b != nil && *b
+}
+
+// formatFloat64 Shortest decimal notation of an optional number, empty when unset
+func formatFloat64(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'f', -1, 64)
+}
+
+// floatValue Value of an optional number, 0 when unset
+func floatValue(f *float64) float64 {
+	if f == nil {
+		return 0
+	}
+	return *f
+}
+
+// int64Value Value of an optional integer, 0 when unset
+func int64Value(i *int64) int64 {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
+
+// containsString Whether a list holds a value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ipv4Span First and last address of an IPv4 network
+func ipv4Span(network *net.IPNet) (uint64, uint64) {
+	start := uint64(binary.BigEndian.Uint32(network.IP.To4()))
+	ones, _ := network.Mask.Size()
+	return start, start + (uint64(1) << (32 - ones)) - 1
+}
+
+// ipv4Value Numeric value of an IPv4 address
+func ipv4Value(address string) (uint64, bool) {
+	ip := net.ParseIP(address).To4()
+	if ip == nil {
+		return 0, false
+	}
+	return uint64(binary.BigEndian.Uint32(ip)), true
+}
+
+// ipv4String Dotted notation of an IPv4 address
+func ipv4String(address uint64) string {
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, uint32(address))
+	return ip.String()
+}
+
+// inIPAddressRanges Whether an IPv4 address belongs to one of the ranges
+func inIPAddressRanges(ranges []IPAddressRange, address string) bool {
+	value, ok := ipv4Value(address)
+	if !ok {
+		return false
+	}
+	for _, r := range ranges {
+		start, okStart := ipv4Value(core.StringNilMapper(r.StartingIPAddress))
+		end, okEnd := ipv4Value(core.StringNilMapper(r.EndingIPAddress))
+		if okStart && okEnd && start <= value && value <= end {
+			return true
+		}
+	}
+	return false
+}
+
+// transportLayer http.RoundTripper installed on the service HTTP client in front of another one,
+// such as the cache and the options validation
+type transportLayer interface {
+	http.RoundTripper
+	// nextTransport returns the field holding the wrapped transport.
+	nextTransport() *http.RoundTripper
+}
+
+// addTransportLayer Put the layer returned by wrap in front of the transport of the service HTTP client
+func (powervs *PowervsV1) addTransportLayer(wrap func(next http.RoundTripper) transportLayer) {
+	current := powervs.Service.GetHTTPClient()
+	if current == nil {
+		current = core.DefaultHTTPClient()
+	}
+	client := *current
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	client.Transport = wrap(next)
+	powervs.Service.SetHTTPClient(&client)
+}
+
+// findTransportLayer First layer of the service HTTP client transport chain accepted by match, nil when none is
+func (powervs *PowervsV1) findTransportLayer(match func(transportLayer) bool) transportLayer {
+	client := powervs.Service.GetHTTPClient()
+	if client == nil {
+		return nil
+	}
+	layer, ok := client.Transport.(transportLayer)
+	for ok && !match(layer) {
+		layer, ok = (*layer.nextTransport()).(transportLayer)
+	}
+	if !ok {
+		return nil
+	}
+	return layer
+}
+
+// removeTransportLayer Unlink the first layer accepted by match from the service HTTP client transport chain,
+// wherever the other layers put it
+func (powervs *PowervsV1) removeTransportLayer(match func(transportLayer) bool) {
+	client := powervs.Service.GetHTTPClient()
+	if client == nil {
+		return
+	}
+	if layer, ok := client.Transport.(transportLayer); ok && match(layer) {
+		updated := *client
+		updated.Transport = *layer.nextTransport()
+		powervs.Service.SetHTTPClient(&updated)
+		return
+	}
+	for outer, ok := client.Transport.(transportLayer); ok; outer, ok = (*outer.nextTransport()).(transportLayer) {
+		if layer, ok := (*outer.nextTransport()).(transportLayer); ok && match(layer) {
+			*outer.nextTransport() = *layer.nextTransport()
+			return
+		}
+	}
+}
+
 // fetchAuthorizationData Fetch Authorization token using the Authenticator
 func fetchAuthorizationData(a core.Authenticator) (string, error) {
 	req := &http.Request{
@@ -0,0 +1,349 @@
+package powervsv1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+const (
+	// minInstanceMemory Smallest memory in GB of an instance
+	minInstanceMemory = 2.0
+
+	// maxServerNameLength Longest instance name accepted by the service
+	maxServerNameLength = 47
+
+	// maxUserDataSize Largest base64 encoded user data accepted by the service
+	maxUserDataSize = 63 * 1024
+
+	// Range of the network MTU
+	minNetworkMtu = 1450
+	maxNetworkMtu = 9000
+)
+
+// serverNamePattern Characters allowed in an instance name
+var serverNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// validatedOperations Request bodies checked by the transport installed by EnableOptionsValidation
+var validatedOperations = []struct {
+	method     string
+	path       string
+	newOptions func() interface{}
+}{
+	{http.MethodPost, "/pcloud/v1/cloud-instances/{}/pvm-instances", func() interface{} { return &PcloudPvminstancesPostOptions{} }},
+	{http.MethodPut, "/pcloud/v1/cloud-instances/{}/pvm-instances/{}", func() interface{} { return &PcloudPvminstancesPutOptions{} }},
+	{http.MethodPost, "/pcloud/v1/cloud-instances/{}/volumes", func() interface{} { return &PcloudCloudinstancesVolumesPostOptions{} }},
+	{http.MethodPut, "/pcloud/v1/cloud-instances/{}/volumes/{}", func() interface{} { return &PcloudCloudinstancesVolumesPutOptions{} }},
+	{http.MethodPost, "/pcloud/v1/cloud-instances/{}/networks", func() interface{} { return &PcloudNetworksPostOptions{} }},
+	{http.MethodPost, "/pcloud/v1/cloud-instances/{}/vpn/vpn-connections", func() interface{} { return &PcloudVpnconnectionsPostOptions{} }},
+	{http.MethodPost, "/pcloud/v1/cloud-instances/{}/vpn/ike-policies", func() interface{} { return &PcloudIkepoliciesPostOptions{} }},
+	{http.MethodPost, "/pcloud/v1/cloud-instances/{}/vpn/ipsec-policies", func() interface{} { return &PcloudIpsecpoliciesPostOptions{} }},
+	{http.MethodPost, "/pcloud/v1/cloud-instances/{}/shared-processor-pools", func() interface{} { return &PcloudSharedprocessorpoolsPostOptions{} }},
+	{http.MethodPut, "/pcloud/v1/cloud-instances/{}/shared-processor-pools/{}", func() interface{} { return &PcloudSharedprocessorpoolsPutOptions{} }},
+}
+
+// ValidationError : Option value the service would reject
+type ValidationError struct {
+	// JSON name of the option.
+	Field string
+
+	Message string
+}
+
+// Error : Field and message
+func (e ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationErrors : Every rejected option value of a request
+type ValidationErrors []ValidationError
+
+// Error : Summary of the violations
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, e := range errs {
+		messages = append(messages, e.Error())
+	}
+	return fmt.Sprintf("invalid options: %s", strings.Join(messages, "; "))
+}
+
+// add Record a violation
+func (errs *ValidationErrors) add(field string, format string, args ...interface{}) {
+	*errs = append(*errs, ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateOptions : Check the values of instance, volume, network, VPN connection, VPN policy
+// and shared processor pool create and update options against the rules the service enforces, reporting
+// every violation in a ValidationErrors. Other options are not checked.
+func ValidateOptions(options interface{}) error {
+	var errs ValidationErrors
+	switch o := options.(type) {
+	case *PcloudPvminstancesPostOptions:
+		errs.checkServerName(o.ServerName)
+		errs.checkProcessors(o.ProcType, o.Processors)
+		errs.checkMemory(o.Memory)
+		if o.Replicants != nil && *o.Replicants > 1 {
+			for _, network := range o.Networks {
+				if core.StringNilMapper(network.IPAddress) != "" {
+					errs.add("networks", "a fixed IP address cannot be assigned to %s replicants, remove ipAddress", formatFloat64(o.Replicants))
+					break
+				}
+			}
+		}
+		if o.UserData != nil && len(*o.UserData) > maxUserDataSize {
+			errs.add("userData", "user data is %d bytes, the limit is %d bytes", len(*o.UserData), maxUserDataSize)
+		}
+		if o.SharedProcessorPool != nil && core.StringNilMapper(o.ProcType) == PcloudPvminstancesPostOptionsProcTypeDedicatedConst {
+			errs.add("sharedProcessorPool", "shared processor pools only host shared or capped instances")
+		}
+	case *PcloudPvminstancesPutOptions:
+		if o.ServerName != nil {
+			errs.checkServerName(o.ServerName)
+		}
+		if o.ProcType != nil && o.Processors != nil {
+			errs.checkProcessors(o.ProcType, o.Processors)
+		} else if o.Processors != nil && *o.Processors <= 0 {
+			errs.add("processors", "processors must be positive")
+		}
+		if o.Memory != nil {
+			errs.checkMemory(o.Memory)
+		}
+	case *PcloudCloudinstancesVolumesPostOptions:
+		errs.checkVolumeSize(o.Size)
+		switch core.StringNilMapper(o.AffinityPolicy) {
+		case PcloudCloudinstancesVolumesPostOptionsAffinityPolicyAffinityConst:
+			if o.AffinityPvmInstance == nil && o.AffinityVolume == nil {
+				errs.add("affinityPolicy", "affinity needs affinityPVMInstance or affinityVolume")
+			}
+		case PcloudCloudinstancesVolumesPostOptionsAffinityPolicyAntiAffinityConst:
+			if len(o.AntiAffinityPvmInstances) == 0 && len(o.AntiAffinityVolumes) == 0 {
+				errs.add("affinityPolicy", "anti-affinity needs antiAffinityPVMInstances or antiAffinityVolumes")
+			}
+		}
+		if o.AffinityPolicy != nil && o.VolumePool != nil {
+			errs.add("affinityPolicy", "affinityPolicy is ignored when volumePool is set, remove one of them")
+		}
+	case *PcloudCloudinstancesVolumesPutOptions:
+		if o.Size != nil {
+			errs.checkVolumeSize(o.Size)
+		}
+	case *PcloudNetworksPostOptions:
+		errs.checkNetwork(o)
+	case *PcloudVpnconnectionsPostOptions:
+		mode := core.StringNilMapper(o.Mode)
+		if mode != PcloudVpnconnectionsPostOptionsModePolicyConst && mode != PcloudVpnconnectionsPostOptionsModeRouteConst {
+			errs.add("mode", "unknown mode %q, use policy or route", mode)
+		}
+		if ip := net.ParseIP(core.StringNilMapper(o.PeerGatewayAddress)); ip == nil || ip.To4() == nil {
+			errs.add("peerGatewayAddress", "%q is not an IPv4 address", core.StringNilMapper(o.PeerGatewayAddress))
+		}
+		if len(o.PeerSubnets) == 0 {
+			errs.add("peerSubnets", "at least one peer subnet is required")
+		}
+		for _, subnet := range o.PeerSubnets {
+			if _, _, err := net.ParseCIDR(subnet); err != nil {
+				errs.add("peerSubnets", "%q is not a CIDR", subnet)
+			}
+		}
+		if len(o.Networks) == 0 {
+			errs.add("networks", "at least one network is required")
+		}
+	case *PcloudIkepoliciesPostOptions:
+		errs.checkIkePolicy(o.Version, o.Encryption, o.Authentication, o.DhGroup, o.KeyLifetime)
+	case *PcloudIpsecpoliciesPostOptions:
+		errs.checkIPSecPolicy(o.Encryption, o.Authentication, o.DhGroup, o.KeyLifetime)
+	case *PcloudSharedprocessorpoolsPostOptions:
+		if int64Value(o.ReservedCores) < 1 {
+			errs.add("reservedCores", "a pool reserves at least 1 core")
+		}
+	case *PcloudSharedprocessorpoolsPutOptions:
+		if o.ReservedCores != nil && *o.ReservedCores < 1 {
+			errs.add("reservedCores", "a pool reserves at least 1 core")
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (errs *ValidationErrors) checkServerName(name *string) {
+	value := core.StringNilMapper(name)
+	if len(value) > maxServerNameLength {
+		errs.add("serverName", "%q is longer than %d characters", value, maxServerNameLength)
+	}
+	if !serverNamePattern.MatchString(value) {
+		errs.add("serverName", "%q may only hold letters, digits, '-', '_' and '.', starting with a letter or digit", value)
+	}
+}
+
+func (errs *ValidationErrors) checkProcessors(procType *string, processors *float64) {
+	value := floatValue(processors)
+	switch core.StringNilMapper(procType) {
+	case PcloudPvminstancesPostOptionsProcTypeDedicatedConst:
+		if value < 1 || value != math.Trunc(value) {
+			errs.add("processors", "dedicated instances need a whole number of processors, got %s", formatFloat64(&value))
+		}
+	case PcloudPvminstancesPostOptionsProcTypeSharedConst, PcloudPvminstancesPostOptionsProcTypeCappedConst:
+		if value < defaultSharedProcessorStep || math.Mod(math.Round(value*100), defaultSharedProcessorStep*100) != 0 {
+			errs.add("processors", "%s processors must be a multiple of %s, got %s", *procType, formatFloat64(core.Float64Ptr(defaultSharedProcessorStep)), formatFloat64(&value))
+		}
+	default:
+		errs.add("procType", "unknown processor type %q, use dedicated, shared or capped", core.StringNilMapper(procType))
+	}
+}
+
+func (errs *ValidationErrors) checkMemory(memory *float64) {
+	if value := floatValue(memory); value < minInstanceMemory {
+		errs.add("memory", "memory must be at least %sGB, got %s", formatFloat64(core.Float64Ptr(minInstanceMemory)), formatFloat64(&value))
+	}
+}
+
+func (errs *ValidationErrors) checkVolumeSize(size *float64) {
+	if value := floatValue(size); value < 1 || value != math.Trunc(value) {
+		errs.add("size", "volume size must be a whole number of GB, got %s", formatFloat64(&value))
+	}
+}
+
+func (errs *ValidationErrors) checkNetwork(o *PcloudNetworksPostOptions) {
+	networkType := core.StringNilMapper(o.Type)
+	if networkType == PcloudNetworksPostOptionsTypePubVlanConst {
+		if o.CIDR != nil || o.Gateway != nil || len(o.IPAddressRanges) > 0 {
+			errs.add("cidr", "public networks get their addresses from the service, remove cidr, gateway and ipAddressRanges")
+		}
+	} else if o.CIDR == nil {
+		errs.add("cidr", "%s networks need a CIDR", networkType)
+	}
+	var cidr *net.IPNet
+	if o.CIDR != nil {
+		var err error
+		if _, cidr, err = net.ParseCIDR(*o.CIDR); err != nil {
+			errs.add("cidr", "%q is not a CIDR", *o.CIDR)
+		}
+	}
+	inCIDR := func(field string, address string) net.IP {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			errs.add(field, "%q is not an IP address", address)
+		} else if cidr != nil && !cidr.Contains(ip) {
+			errs.add(field, "%s is outside of %s", address, cidr)
+		}
+		return ip
+	}
+	var gateway net.IP
+	if o.Gateway != nil {
+		gateway = inCIDR("gateway", *o.Gateway)
+	}
+	type addressRange struct{ start, end net.IP }
+	var ranges []addressRange
+	for _, r := range o.IPAddressRanges {
+		start := inCIDR("ipAddressRanges", core.StringNilMapper(r.StartingIPAddress))
+		end := inCIDR("ipAddressRanges", core.StringNilMapper(r.EndingIPAddress))
+		if start == nil || end == nil {
+			continue
+		}
+		if bytes.Compare(start.To16(), end.To16()) > 0 {
+			errs.add("ipAddressRanges", "range %s-%s ends before it starts", start, end)
+			continue
+		}
+		ranges = append(ranges, addressRange{start, end})
+	}
+	within := func(ip net.IP, r addressRange) bool {
+		return bytes.Compare(r.start.To16(), ip.To16()) <= 0 && bytes.Compare(ip.To16(), r.end.To16()) <= 0
+	}
+	// The network and broadcast addresses of IPv4 networks cannot be allocated
+	var reserved []net.IP
+	if cidr != nil && cidr.IP.To4() != nil {
+		if ones, _ := cidr.Mask.Size(); ones <= 30 {
+			first, last := ipv4Span(cidr)
+			reserved = []net.IP{net.ParseIP(ipv4String(first)), net.ParseIP(ipv4String(last))}
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool { return bytes.Compare(ranges[i].start.To16(), ranges[j].start.To16()) < 0 })
+	for i, r := range ranges {
+		if gateway != nil && within(gateway, r) {
+			errs.add("gateway", "gateway %s is inside the range %s-%s", gateway, r.start, r.end)
+		}
+		for _, ip := range reserved {
+			if within(ip, r) {
+				errs.add("ipAddressRanges", "range %s-%s includes the network or broadcast address %s", r.start, r.end, ip)
+			}
+		}
+		if i > 0 && bytes.Compare(r.start.To16(), ranges[i-1].end.To16()) <= 0 {
+			errs.add("ipAddressRanges", "ranges %s-%s and %s-%s overlap", ranges[i-1].start, ranges[i-1].end, r.start, r.end)
+		}
+	}
+	for _, server := range o.DnsServers {
+		if net.ParseIP(server) == nil {
+			errs.add("dnsServers", "%q is not an IP address", server)
+		}
+	}
+	if o.Mtu != nil && (*o.Mtu < minNetworkMtu || *o.Mtu > maxNetworkMtu) {
+		errs.add("mtu", "MTU must be between %d and %d, got %d", minNetworkMtu, maxNetworkMtu, *o.Mtu)
+	}
+}
+
+// EnableOptionsValidation : Check the body of instance, volume, network, VPN connection, VPN
+// policy and shared processor pool create and update requests with ValidateOptions before sending them.
+// Requests breaking a rule fail without reaching the service. The HTTP client wraps the ValidationErrors
+// in a *url.Error, so use errors.As to get it. It can be combined with EnableRetries and EnableCache
+// in any order.
+func (powervs *PowervsV1) EnableOptionsValidation() {
+	powervs.DisableOptionsValidation()
+	powervs.addTransportLayer(func(next http.RoundTripper) transportLayer {
+		return &validationTransport{next: next}
+	})
+}
+
+// DisableOptionsValidation : Send requests without checking their options
+func (powervs *PowervsV1) DisableOptionsValidation() {
+	powervs.removeTransportLayer(func(layer transportLayer) bool {
+		_, ok := layer.(*validationTransport)
+		return ok
+	})
+}
+
+// validationTransport http.RoundTripper rejecting requests whose options break a validation rule
+type validationTransport struct {
+	next http.RoundTripper
+}
+
+// nextTransport Transport sending the requests whose options are valid
+func (t *validationTransport) nextTransport() *http.RoundTripper {
+	return &t.next
+}
+
+// RoundTrip Decode the body of validated operations into their options and check them
+func (t *validationTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body == nil {
+		return t.next.RoundTrip(request)
+	}
+	for _, operation := range validatedOperations {
+		if operation.method != request.Method || !matchPathTemplate(request.URL.Path, operation.path) {
+			continue
+		}
+		body, err := io.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		request.Body = io.NopCloser(bytes.NewReader(body))
+		options := operation.newOptions()
+		if err := json.Unmarshal(body, options); err == nil {
+			if err := ValidateOptions(options); err != nil {
+				return nil, err
+			}
+		}
+		break
+	}
+	return t.next.RoundTrip(request)
+}
@@ -0,0 +1,149 @@
+package powervsv1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+func TestValidateOptions(t *testing.T) {
+	powervs := &PowervsV1{}
+	instance := func(procType string, processors, memory float64, name string) *PcloudPvminstancesPostOptions {
+		return powervs.NewPcloudPvminstancesPostOptions("ws", "img", memory, procType, processors, name)
+	}
+	network := func(networkType string) *PcloudNetworksPostOptions {
+		return powervs.NewPcloudNetworksPostOptions("ws", networkType)
+	}
+	ipRange := func(start, end string) IPAddressRange {
+		return IPAddressRange{StartingIPAddress: core.StringPtr(start), EndingIPAddress: core.StringPtr(end)}
+	}
+
+	tests := []struct {
+		name    string
+		options interface{}
+		want    []ValidationError
+	}{
+		{"valid instance", instance("shared", 0.5, 4, "vm-1"), nil},
+		{"instance", instance("dedicated", 0.5, 1, "-vm"), []ValidationError{
+			{"serverName", `"-vm" may only hold letters, digits, '-', '_' and '.', starting with a letter or digit`},
+			{"processors", "dedicated instances need a whole number of processors, got 0.5"},
+			{"memory", "memory must be at least 2GB, got 1"},
+		}},
+		{"processor step", instance("capped", 0.3, 4, "vm"), []ValidationError{
+			{"processors", "capped processors must be a multiple of 0.25, got 0.3"},
+		}},
+		{"replicants with fixed IP", instance("shared", 1, 4, "vm").SetReplicants(2).SetNetworks([]PvmInstanceAddNetwork{{NetworkID: core.StringPtr("net-1"), IPAddress: core.StringPtr("10.0.0.5")}}), []ValidationError{
+			{"networks", "a fixed IP address cannot be assigned to 2 replicants, remove ipAddress"},
+		}},
+		{"dedicated in pool", instance("dedicated", 1, 4, "vm").SetSharedProcessorPool("spp"), []ValidationError{
+			{"sharedProcessorPool", "shared processor pools only host shared or capped instances"},
+		}},
+		{"instance update", powervs.NewPcloudPvminstancesPutOptions("ws", "pvm-1").SetProcessors(0), []ValidationError{
+			{"processors", "processors must be positive"},
+		}},
+		{"volume size", powervs.NewPcloudCloudinstancesVolumesPostOptions("ws", "data", 10.5), []ValidationError{
+			{"size", "volume size must be a whole number of GB, got 10.5"},
+		}},
+		{"volume affinity", powervs.NewPcloudCloudinstancesVolumesPostOptions("ws", "data", 10).SetAffinityPolicy("anti-affinity").SetVolumePool("p1"), []ValidationError{
+			{"affinityPolicy", "anti-affinity needs antiAffinityPVMInstances or antiAffinityVolumes"},
+			{"affinityPolicy", "affinityPolicy is ignored when volumePool is set, remove one of them"},
+		}},
+		{"valid network", network("vlan").SetCIDR("10.0.0.0/24").SetGateway("10.0.0.1").SetIPAddressRanges([]IPAddressRange{ipRange("10.0.0.2", "10.0.0.254")}), nil},
+		{"network", network("vlan").SetCIDR("10.0.0.0/24").SetGateway("10.0.1.1").SetIPAddressRanges([]IPAddressRange{ipRange("10.0.0.200", "10.0.0.100")}).SetDnsServers([]string{"dns"}).SetMtu(9100), []ValidationError{
+			{"gateway", "10.0.1.1 is outside of 10.0.0.0/24"},
+			{"ipAddressRanges", "range 10.0.0.200-10.0.0.100 ends before it starts"},
+			{"dnsServers", `"dns" is not an IP address`},
+			{"mtu", "MTU must be between 1450 and 9000, got 9100"},
+		}},
+		{"vlan without cidr", network("vlan"), []ValidationError{{"cidr", "vlan networks need a CIDR"}}},
+		{"public network", network("pub-vlan").SetCIDR("10.0.0.0/24"), []ValidationError{
+			{"cidr", "public networks get their addresses from the service, remove cidr, gateway and ipAddressRanges"},
+		}},
+		{"vpn", powervs.NewPcloudVpnconnectionsPostOptions("ws", "ike-1", "ipsec-1", "tunnel", "vpn", []string{"net-1"}, "gw", []string{"192.168.0.0/33"}), []ValidationError{
+			{"mode", `unknown mode "tunnel", use policy or route`},
+			{"peerGatewayAddress", `"gw" is not an IPv4 address`},
+			{"peerSubnets", `"192.168.0.0/33" is not a CIDR`},
+		}},
+		{"shared processor pool", powervs.NewPcloudSharedprocessorpoolsPostOptions("ws", "s922", "spp", 0), []ValidationError{
+			{"reservedCores", "a pool reserves at least 1 core"},
+		}},
+		{"not validated", powervs.NewPcloudImagesGetallOptions(), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOptions(tt.options)
+			var got ValidationErrors
+			if err != nil && !errors.As(err, &got) {
+				t.Fatalf("ValidateOptions() error = %v", err)
+			}
+			if !reflect.DeepEqual([]ValidationError(got), tt.want) {
+				t.Errorf("ValidateOptions() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnableOptionsValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(powervs *PowervsV1)
+	}{
+		{"validation before cache", func(powervs *PowervsV1) {
+			powervs.EnableOptionsValidation()
+			powervs.EnableCache(&CacheOptions{})
+		}},
+		{"cache before validation", func(powervs *PowervsV1) {
+			powervs.EnableCache(&CacheOptions{})
+			powervs.EnableOptionsValidation()
+		}},
+		{"retries last", func(powervs *PowervsV1) {
+			powervs.EnableCache(&CacheOptions{})
+			powervs.EnableOptionsValidation()
+			powervs.EnableRetries(0, time.Millisecond)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"volumeID": "vol-1", "name": "data"}`))
+			}))
+			defer server.Close()
+			powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setup(powervs)
+
+			_, _, err = powervs.PcloudCloudinstancesVolumesPostWithContext(context.Background(), powervs.NewPcloudCloudinstancesVolumesPostOptions("ws", "data", 0))
+			var errs ValidationErrors
+			if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != "size" || requests != 0 {
+				t.Errorf("invalid request: error = %v after %d requests", err, requests)
+			}
+			if _, _, err = powervs.PcloudCloudinstancesVolumesPostWithContext(context.Background(), powervs.NewPcloudCloudinstancesVolumesPostOptions("ws", "data", 10)); err != nil || requests != 1 {
+				t.Errorf("valid request: error = %v after %d requests", err, requests)
+			}
+
+			powervs.DisableCache()
+			if powervs.cacheTransport() != nil {
+				t.Error("cache still enabled")
+			}
+			if _, _, err = powervs.PcloudCloudinstancesVolumesPostWithContext(context.Background(), powervs.NewPcloudCloudinstancesVolumesPostOptions("ws", "data", 0)); !errors.As(err, &errs) || requests != 1 {
+				t.Errorf("disabled cache: error = %v after %d requests", err, requests)
+			}
+
+			powervs.DisableOptionsValidation()
+			if _, _, err = powervs.PcloudCloudinstancesVolumesPostWithContext(context.Background(), powervs.NewPcloudCloudinstancesVolumesPostOptions("ws", "data", 0)); err != nil || requests != 2 {
+				t.Errorf("disabled validation: error = %v after %d requests", err, requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package powervsv1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// VolumePlacementOptions : The RecommendVolumePlacement options.
+type VolumePlacementOptions struct {
+	CloudInstanceID string `validate:"required"`
+
+	// Size of the volume in GB.
+	Size float64 `validate:"required"`
+
+	// Storage tier such as tier1 or tier3, any active tier when empty.
+	DiskType string
+
+	// The pool must support replication.
+	ReplicationEnabled bool
+
+	// "affinity" to use the pool of the affinity volumes and instances, "anti-affinity" to avoid it.
+	AffinityPolicy string `validate:"omitempty,oneof=affinity anti-affinity"`
+
+	// Volumes (ID or name) the policy is based on.
+	AffinityVolumes []string
+
+	// Instances (ID or name) whose volumes the policy is based on.
+	AffinityPvmInstances []string
+}
+
+// VolumePoolCandidate : Storage pool considered by RecommendVolumePlacement
+type VolumePoolCandidate struct {
+	PoolName string `json:"poolName"`
+
+	StorageType string `json:"storageType"`
+
+	AvailableCapacity int64 `json:"availableCapacity"`
+
+	Eligible bool `json:"eligible"`
+
+	// Constraints the pool does not satisfy.
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// VolumePlacement : Storage pool recommended for a new volume
+type VolumePlacement struct {
+	// Empty when no pool satisfies the constraints.
+	VolumePool string `json:"volumePool,omitempty"`
+
+	DiskType string `json:"diskType,omitempty"`
+
+	// Why the pool was chosen, or why none was.
+	Explanation string `json:"explanation"`
+
+	Candidates []VolumePoolCandidate `json:"candidates"`
+}
+
+// ApplyToVolume : Set the pool and tier of a volume creation
+func (placement *VolumePlacement) ApplyToVolume(options *PcloudCloudinstancesVolumesPostOptions) *PcloudCloudinstancesVolumesPostOptions {
+	options.SetVolumePool(placement.VolumePool)
+	options.SetDiskType(placement.DiskType)
+	// The pool replaces the affinity policy
+	options.AffinityPolicy = nil
+	return options
+}
+
+// ApplyToInstance : Set the storage pool and type of an instance creation
+func (placement *VolumePlacement) ApplyToInstance(options *PcloudPvminstancesPostOptions) *PcloudPvminstancesPostOptions {
+	options.SetStoragePool(placement.VolumePool)
+	options.SetStorageType(placement.DiskType)
+	return options
+}
+
+// RecommendVolumePlacement : Pick the storage pool for a new volume among the pools of active tiers
+// with room for it, supporting replication when required and satisfying the affinity policy.
+// The eligible pool with the most available capacity is recommended. When no pool is eligible
+// the placement explains why each pool was rejected and an error is returned with it.
+func (powervs *PowervsV1) RecommendVolumePlacement(ctx context.Context, options *VolumePlacementOptions) (*VolumePlacement, error) {
+	if err := core.ValidateNotNil(options, "options cannot be nil"); err != nil {
+		return nil, err
+	}
+	if err := core.ValidateStruct(options, "volumePlacementOptions"); err != nil {
+		return nil, err
+	}
+
+	pools, _, err := powervs.PcloudStoragecapacityPoolsGetallWithContext(ctx, powervs.NewPcloudStoragecapacityPoolsGetallOptions(options.CloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage pools capacity: %w", err)
+	}
+	tiers, _, err := powervs.PcloudCloudinstancesStoragetiersGetallWithContext(ctx, powervs.NewPcloudCloudinstancesStoragetiersGetallOptions(options.CloudInstanceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list storage tiers: %w", err)
+	}
+	tierStates := map[string]string{}
+	for _, tier := range tiers {
+		tierStates[core.StringNilMapper(tier.Name)] = core.StringNilMapper(tier.State)
+	}
+	affinityPools, err := powervs.affinityVolumePools(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	placement := &VolumePlacement{}
+	for _, pool := range pools.StoragePoolsCapacity {
+		candidate := VolumePoolCandidate{
+			PoolName:          core.StringNilMapper(pool.PoolName),
+			StorageType:       core.StringNilMapper(pool.StorageType),
+			AvailableCapacity: int64Value(pool.AvailableCapacity),
+		}
+		if options.DiskType != "" && candidate.StorageType != options.DiskType {
+			candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("storage type is %s", candidate.StorageType))
+		}
+		if state, ok := tierStates[candidate.StorageType]; ok && !strings.EqualFold(state, "active") {
+			candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("tier %s is %s", candidate.StorageType, state))
+		}
+		if float64(int64Value(pool.MaxAllocationSize)) < options.Size {
+			candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("largest allocation is %dGB", int64Value(pool.MaxAllocationSize)))
+		}
+		if float64(candidate.AvailableCapacity) < options.Size {
+			candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("only %dGB available", candidate.AvailableCapacity))
+		}
+		if options.ReplicationEnabled && !boolValue(pool.ReplicationEnabled) {
+			candidate.Reasons = append(candidate.Reasons, "replication not enabled")
+		}
+		switch options.AffinityPolicy {
+		case PcloudCloudinstancesVolumesPostOptionsAffinityPolicyAffinityConst:
+			if !affinityPools[candidate.PoolName] {
+				candidate.Reasons = append(candidate.Reasons, "does not hold the affinity volumes")
+			}
+		case PcloudCloudinstancesVolumesPostOptionsAffinityPolicyAntiAffinityConst:
+			if affinityPools[candidate.PoolName] {
+				candidate.Reasons = append(candidate.Reasons, "holds an anti-affinity volume")
+			}
+		}
+		candidate.Eligible = len(candidate.Reasons) == 0
+		placement.Candidates = append(placement.Candidates, candidate)
+	}
+	sort.SliceStable(placement.Candidates, func(i, j int) bool {
+		a, b := placement.Candidates[i], placement.Candidates[j]
+		if a.Eligible != b.Eligible {
+			return a.Eligible
+		}
+		return a.AvailableCapacity > b.AvailableCapacity
+	})
+
+	eligible := 0
+	for _, candidate := range placement.Candidates {
+		if candidate.Eligible {
+			eligible++
+		}
+	}
+	if eligible == 0 {
+		var rejections []string
+		for _, candidate := range placement.Candidates {
+			rejections = append(rejections, fmt.Sprintf("%s: %s", candidate.PoolName, strings.Join(candidate.Reasons, ", ")))
+		}
+		placement.Explanation = fmt.Sprintf("no storage pool can hold a %sGB volume (%s)", formatFloat64(&options.Size), strings.Join(rejections, "; "))
+		return placement, errors.New(placement.Explanation)
+	}
+	best := placement.Candidates[0]
+	placement.VolumePool = best.PoolName
+	placement.DiskType = best.StorageType
+	placement.Explanation = fmt.Sprintf("%s pool %s has the most available capacity (%dGB) of %d eligible pools", best.StorageType, best.PoolName, best.AvailableCapacity, eligible)
+	return placement, nil
+}
+
+// affinityVolumePools Pools holding the affinity volumes and the volumes of the affinity instances
+func (powervs *PowervsV1) affinityVolumePools(ctx context.Context, options *VolumePlacementOptions) (map[string]bool, error) {
+	pools := map[string]bool{}
+	if options.AffinityPolicy == "" {
+		return pools, nil
+	}
+	for _, volumeID := range options.AffinityVolumes {
+		volume, _, err := powervs.PcloudCloudinstancesVolumesGetWithContext(ctx, powervs.NewPcloudCloudinstancesVolumesGetOptions(options.CloudInstanceID, volumeID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get affinity volume %s: %w", volumeID, err)
+		}
+		pools[core.StringNilMapper(volume.VolumePool)] = true
+	}
+	for _, pvmInstanceID := range options.AffinityPvmInstances {
+		volumes, _, err := powervs.PcloudPvminstancesVolumesGetallWithContext(ctx, powervs.NewPcloudPvminstancesVolumesGetallOptions(options.CloudInstanceID, pvmInstanceID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list volumes of affinity instance %s: %w", pvmInstanceID, err)
+		}
+		for _, volume := range volumes.Volumes {
+			pools[core.StringNilMapper(volume.VolumePool)] = true
+		}
+	}
+	delete(pools, "")
+	return pools, nil
+}
@@ -0,0 +1,82 @@
+package powervsv1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+func TestRecommendVolumePlacement(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch strings.TrimPrefix(r.URL.Path, "/pcloud/v1") {
+		case "/cloud-instances/ws/storage-capacity/storage-pools":
+			_, _ = w.Write([]byte(`{"storagePoolsCapacity": [
+				{"poolName": "p1", "storageType": "tier1", "availableCapacity": 300, "maxAllocationSize": 300, "replicationEnabled": true},
+				{"poolName": "p2", "storageType": "tier1", "availableCapacity": 900, "maxAllocationSize": 500},
+				{"poolName": "p3", "storageType": "tier3", "availableCapacity": 5000, "maxAllocationSize": 5000},
+				{"poolName": "p4", "storageType": "tier5k", "availableCapacity": 8000, "maxAllocationSize": 8000}]}`))
+		case "/cloud-instances/ws/storage-tiers":
+			_, _ = w.Write([]byte(`[{"name": "tier1", "state": "active"}, {"name": "tier3", "state": "active"}, {"name": "tier5k", "state": "inactive"}]`))
+		case "/cloud-instances/ws/volumes/vol-2":
+			_, _ = w.Write([]byte(`{"volumeID": "vol-2", "name": "data", "size": 100, "volumePool": "p1"}`))
+		case "/cloud-instances/ws/pvm-instances/pvm-1/volumes":
+			_, _ = w.Write([]byte(`{"volumes": [{"volumeID": "vol-1", "name": "boot", "diskType": "tier3", "volumePool": "p3"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		options  VolumePlacementOptions
+		wantPool string
+		wantErr  bool
+	}{
+		{"most capacity", VolumePlacementOptions{Size: 100}, "p3", false},
+		{"tier", VolumePlacementOptions{Size: 100, DiskType: "tier1"}, "p2", false},
+		{"replication", VolumePlacementOptions{Size: 100, ReplicationEnabled: true}, "p1", false},
+		{"max allocation", VolumePlacementOptions{Size: 600, DiskType: "tier1"}, "", true},
+		{"affinity volume", VolumePlacementOptions{Size: 100, AffinityPolicy: "affinity", AffinityVolumes: []string{"vol-2"}}, "p1", false},
+		{"anti-affinity instance", VolumePlacementOptions{Size: 100, AffinityPolicy: "anti-affinity", AffinityPvmInstances: []string{"pvm-1"}}, "p2", false},
+		{"inactive tier", VolumePlacementOptions{Size: 100, DiskType: "tier5k"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.options.CloudInstanceID = "ws"
+			placement, err := powervs.RecommendVolumePlacement(context.Background(), &tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RecommendVolumePlacement() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if placement.VolumePool != tt.wantPool {
+				t.Errorf("RecommendVolumePlacement() = %s, want %s: %s", placement.VolumePool, tt.wantPool, placement.Explanation)
+			}
+		})
+	}
+
+	placement, err := powervs.RecommendVolumePlacement(context.Background(), &VolumePlacementOptions{CloudInstanceID: "ws", Size: 100, DiskType: "tier5k"})
+	if want := "no storage pool can hold a 100GB volume (p4: tier tier5k is inactive; p3: storage type is tier3; p2: storage type is tier1; p1: storage type is tier1)"; err == nil || placement.Explanation != want {
+		t.Errorf("RecommendVolumePlacement() explanation = %q", placement.Explanation)
+	}
+
+	placement, _ = powervs.RecommendVolumePlacement(context.Background(), &VolumePlacementOptions{CloudInstanceID: "ws", Size: 100, DiskType: "tier1"})
+	volumeOptions := powervs.NewPcloudCloudinstancesVolumesPostOptions("ws", "data", 100).SetAffinityPolicy("affinity")
+	placement.ApplyToVolume(volumeOptions)
+	if *volumeOptions.VolumePool != "p2" || *volumeOptions.DiskType != "tier1" || volumeOptions.AffinityPolicy != nil {
+		t.Errorf("ApplyToVolume() = %+v", volumeOptions)
+	}
+	instanceOptions := powervs.NewPcloudPvminstancesPostOptions("ws", "img-1", 8, "shared", 1, "vm")
+	placement.ApplyToInstance(instanceOptions)
+	if *instanceOptions.StoragePool != "p2" || *instanceOptions.StorageType != "tier1" {
+		t.Errorf("ApplyToInstance() = %+v", instanceOptions)
+	}
+}
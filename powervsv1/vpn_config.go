@@ -0,0 +1,400 @@
+package powervsv1
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+// Formats of VPNConfiguration.PeerConfig
+const (
+	VPNPeerConfigStrongSwan = "strongswan"
+	VPNPeerConfigLibreswan  = "libreswan"
+	VPNPeerConfigSummary    = "summary"
+)
+
+const (
+	// Range of the IKE and IPSec key lifetimes in seconds
+	minVPNKeyLifetime = 180
+	maxVPNKeyLifetime = 86400
+
+	// vpnPresharedKeyPlaceholder Secret written in peer configurations when no key is given
+	vpnPresharedKeyPlaceholder = "<pre-shared key>"
+)
+
+// vpnDhGroups strongSwan and libreswan names of the Diffie-Hellman groups accepted by the service
+var vpnDhGroups = map[int64][2]string{
+	1:  {"modp768", "modp768"},
+	2:  {"modp1024", "modp1024"},
+	5:  {"modp1536", "modp1536"},
+	14: {"modp2048", "modp2048"},
+	19: {"ecp256", "dh19"},
+	20: {"ecp384", "dh20"},
+	24: {"modp2048s256", "dh24"},
+}
+
+// vpnEncryptions strongSwan and libreswan names of the encryptions
+var vpnEncryptions = map[string][2]string{
+	IPSecPolicyEncryptionAes128CbcConst: {"aes128", "aes128"},
+	IPSecPolicyEncryptionAes192CbcConst: {"aes192", "aes192"},
+	IPSecPolicyEncryptionAes256CbcConst: {"aes256", "aes256"},
+	IPSecPolicyEncryptionAes128GcmConst: {"aes128gcm16", "aes_gcm128"},
+	IPSecPolicyEncryptionAes192GcmConst: {"aes192gcm16", "aes_gcm192"},
+	IPSecPolicyEncryptionAes256GcmConst: {"aes256gcm16", "aes_gcm256"},
+}
+
+// vpnAuthentications strongSwan and libreswan names of the IKE and IPSec authentications
+var vpnAuthentications = map[string][2]string{
+	IkePolicyAuthenticationSha1Const:            {"sha1", "sha1"},
+	IkePolicyAuthenticationSha256Const:          {"sha256", "sha2_256"},
+	IkePolicyAuthenticationSha384Const:          {"sha384", "sha2_384"},
+	IPSecPolicyAuthenticationHmacSha196Const:    {"sha1", "sha1"},
+	IPSecPolicyAuthenticationHmacSha256128Const: {"sha256", "sha2_256"},
+}
+
+// VPNConfiguration : VPN connection with its IKE and IPSec policies and the CIDRs of its
+// workspace networks
+type VPNConfiguration struct {
+	Connection *VPNConnection
+
+	IkePolicy *IkePolicy
+
+	IPSecPolicy *IPSecPolicy
+
+	// CIDRs of the networks of the connection.
+	LocalSubnets []string
+}
+
+// GetVPNConfiguration : Get a VPN connection, its policies and the CIDRs of its networks
+func (powervs *PowervsV1) GetVPNConfiguration(ctx context.Context, cloudInstanceID string, vpnConnectionID string) (*VPNConfiguration, error) {
+	connection, _, err := powervs.PcloudVpnconnectionsGetWithContext(ctx, powervs.NewPcloudVpnconnectionsGetOptions(cloudInstanceID, vpnConnectionID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get VPN connection %s: %w", vpnConnectionID, err)
+	}
+	config := &VPNConfiguration{Connection: connection}
+	if connection.IkePolicy != nil && connection.IkePolicy.ID != nil {
+		config.IkePolicy, _, err = powervs.PcloudIkepoliciesGetWithContext(ctx, powervs.NewPcloudIkepoliciesGetOptions(cloudInstanceID, *connection.IkePolicy.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get IKE policy %s: %w", *connection.IkePolicy.ID, err)
+		}
+	}
+	if connection.IPSecPolicy != nil && connection.IPSecPolicy.ID != nil {
+		config.IPSecPolicy, _, err = powervs.PcloudIpsecpoliciesGetWithContext(ctx, powervs.NewPcloudIpsecpoliciesGetOptions(cloudInstanceID, *connection.IPSecPolicy.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get IPSec policy %s: %w", *connection.IPSecPolicy.ID, err)
+		}
+	}
+	for _, networkID := range connection.NetworkIDs {
+		network, _, err := powervs.PcloudNetworksGetWithContext(ctx, powervs.NewPcloudNetworksGetOptions(cloudInstanceID, networkID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get network %s: %w", networkID, err)
+		}
+		if network.CIDR != nil {
+			config.LocalSubnets = append(config.LocalSubnets, *network.CIDR)
+		}
+	}
+	return config, nil
+}
+
+// Validate : Check the connection and policy combinations the service rejects, reporting every
+// violation in a ValidationErrors
+func (config *VPNConfiguration) Validate() error {
+	var errs ValidationErrors
+	if config.Connection == nil {
+		errs.add("vpnConnection", "the VPN connection is missing")
+		return errs
+	}
+	connection := config.Connection
+	if err := ValidateOptions(&PcloudVpnconnectionsPostOptions{
+		Mode:               connection.Mode,
+		PeerGatewayAddress: connection.PeerGatewayAddress,
+		PeerSubnets:        connection.PeerSubnets,
+		Networks:           connection.NetworkIDs,
+	}); err != nil {
+		errs = append(errs, err.(ValidationErrors)...)
+	}
+	if dpd := connection.DeadPeerDetection; dpd != nil {
+		if action := core.StringNilMapper(dpd.Action); action != DeadPeerDetectionActionRestartConst {
+			errs.add("deadPeerDetection", "unknown action %q, use restart", action)
+		}
+		if int64Value(dpd.Interval) < 1 || int64Value(dpd.Threshold) < 1 {
+			errs.add("deadPeerDetection", "interval and threshold must be at least 1")
+		}
+	}
+	// Policy based connections route the peer subnets through the tunnel, they cannot be local
+	if core.StringNilMapper(connection.Mode) == VPNConnectionModePolicyConst {
+		for _, peer := range connection.PeerSubnets {
+			_, peerNetwork, err := net.ParseCIDR(peer)
+			if err != nil {
+				continue
+			}
+			for _, local := range config.LocalSubnets {
+				if _, localNetwork, err := net.ParseCIDR(local); err == nil && (localNetwork.Contains(peerNetwork.IP) || peerNetwork.Contains(localNetwork.IP)) {
+					errs.add("peerSubnets", "%s overlaps the workspace network %s", peer, local)
+				}
+			}
+		}
+	}
+
+	if config.IkePolicy == nil {
+		errs.add("ikePolicy", "the IKE policy is missing")
+	} else {
+		ike := config.IkePolicy
+		errs.checkIkePolicy(ike.Version, ike.Encryption, ike.Authentication, ike.DhGroup, ike.KeyLifetime)
+	}
+	if config.IPSecPolicy == nil {
+		errs.add("ipSecPolicy", "the IPSec policy is missing")
+	} else {
+		ipsec := config.IPSecPolicy
+		errs.checkIPSecPolicy(ipsec.Encryption, ipsec.Authentication, ipsec.DhGroup, ipsec.KeyLifetime)
+		if config.IkePolicy != nil && ipsec.KeyLifetime != nil && config.IkePolicy.KeyLifetime != nil && *ipsec.KeyLifetime > *config.IkePolicy.KeyLifetime {
+			errs.add("keyLifetime", "the IPSec key lifetime %d must not exceed the IKE key lifetime %d", *ipsec.KeyLifetime, *config.IkePolicy.KeyLifetime)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// PeerConfig : Configuration of the on-premises gateway matching the connection, in one of the
+// VPNPeerConfig formats. The service does not return the pre-shared key, a placeholder is
+// written when presharedKey is empty.
+func (config *VPNConfiguration) PeerConfig(format string, presharedKey string) (string, error) {
+	if err := config.Validate(); err != nil {
+		return "", err
+	}
+	if presharedKey == "" {
+		presharedKey = vpnPresharedKeyPlaceholder
+	}
+	switch format {
+	case VPNPeerConfigStrongSwan:
+		return config.strongSwan(presharedKey), nil
+	case VPNPeerConfigLibreswan:
+		return config.libreswan(presharedKey), nil
+	case VPNPeerConfigSummary:
+		return config.summary(), nil
+	}
+	return "", fmt.Errorf("unknown peer configuration format %q, use %s, %s or %s", format, VPNPeerConfigStrongSwan, VPNPeerConfigLibreswan, VPNPeerConfigSummary)
+}
+
+// strongSwan swanctl.conf of the peer gateway
+func (config *VPNConfiguration) strongSwan(presharedKey string) string {
+	connection, ike, ipsec := config.Connection, config.IkePolicy, config.IPSecPolicy
+	name := config.peerConfigName()
+	gateway := core.StringNilMapper(connection.VPNGatewayAddress)
+	var b strings.Builder
+	fmt.Fprintf(&b, "connections {\n")
+	fmt.Fprintf(&b, "    %s {\n", name)
+	fmt.Fprintf(&b, "        version = %d\n", int64Value(ike.Version))
+	fmt.Fprintf(&b, "        local_addrs = %s\n", core.StringNilMapper(connection.PeerGatewayAddress))
+	fmt.Fprintf(&b, "        remote_addrs = %s\n", gateway)
+	fmt.Fprintf(&b, "        proposals = %s\n", config.strongSwanIkeProposal())
+	fmt.Fprintf(&b, "        rekey_time = %ds\n", int64Value(ike.KeyLifetime))
+	if dpd := connection.DeadPeerDetection; dpd != nil {
+		fmt.Fprintf(&b, "        dpd_delay = %ds\n", int64Value(dpd.Interval))
+		if int64Value(ike.Version) == 1 {
+			fmt.Fprintf(&b, "        dpd_timeout = %ds\n", int64Value(dpd.Interval)*int64Value(dpd.Threshold))
+		}
+	}
+	fmt.Fprintf(&b, "        local {\n            auth = psk\n            id = %s\n        }\n", core.StringNilMapper(connection.PeerGatewayAddress))
+	fmt.Fprintf(&b, "        remote {\n            auth = psk\n            id = %s\n        }\n", gateway)
+	fmt.Fprintf(&b, "        children {\n")
+	fmt.Fprintf(&b, "            %s {\n", name)
+	fmt.Fprintf(&b, "                local_ts = %s\n", strings.Join(connection.PeerSubnets, ","))
+	fmt.Fprintf(&b, "                remote_ts = %s\n", strings.Join(config.LocalSubnets, ","))
+	fmt.Fprintf(&b, "                esp_proposals = %s\n", config.strongSwanEspProposal())
+	fmt.Fprintf(&b, "                rekey_time = %ds\n", int64Value(ipsec.KeyLifetime))
+	if connection.DeadPeerDetection != nil {
+		fmt.Fprintf(&b, "                dpd_action = %s\n", core.StringNilMapper(connection.DeadPeerDetection.Action))
+	}
+	fmt.Fprintf(&b, "                start_action = start\n")
+	fmt.Fprintf(&b, "            }\n        }\n    }\n}\n")
+	fmt.Fprintf(&b, "secrets {\n    ike-%s {\n        id = %s\n        secret = %q\n    }\n}\n", name, gateway, presharedKey)
+	return b.String()
+}
+
+// strongSwanIkeProposal IKE proposal of a swanctl.conf, GCM encryptions take a PRF instead of
+// an integrity algorithm
+func (config *VPNConfiguration) strongSwanIkeProposal() string {
+	ike := config.IkePolicy
+	encryption := vpnEncryptions[core.StringNilMapper(ike.Encryption)][0]
+	integrity := "prfsha256"
+	if authentication, ok := vpnAuthentications[core.StringNilMapper(ike.Authentication)]; ok {
+		integrity = authentication[0]
+	}
+	return fmt.Sprintf("%s-%s-%s", encryption, integrity, vpnDhGroups[int64Value(ike.DhGroup)][0])
+}
+
+// strongSwanEspProposal ESP proposal of a swanctl.conf, with the DH group only with PFS
+func (config *VPNConfiguration) strongSwanEspProposal() string {
+	ipsec := config.IPSecPolicy
+	parts := []string{vpnEncryptions[core.StringNilMapper(ipsec.Encryption)][0]}
+	if authentication, ok := vpnAuthentications[core.StringNilMapper(ipsec.Authentication)]; ok {
+		parts = append(parts, authentication[0])
+	}
+	if boolValue(ipsec.Pfs) {
+		parts = append(parts, vpnDhGroups[int64Value(ipsec.DhGroup)][0])
+	}
+	return strings.Join(parts, "-")
+}
+
+// libreswan ipsec.conf connection and ipsec.secrets line of the peer gateway
+func (config *VPNConfiguration) libreswan(presharedKey string) string {
+	connection, ike, ipsec := config.Connection, config.IkePolicy, config.IPSecPolicy
+	ikev2 := "yes"
+	if int64Value(ike.Version) == 1 {
+		ikev2 = "no"
+	}
+	pfs := "no"
+	if boolValue(ipsec.Pfs) {
+		pfs = "yes"
+	}
+	ikeProposal := vpnEncryptions[core.StringNilMapper(ike.Encryption)][1]
+	if authentication, ok := vpnAuthentications[core.StringNilMapper(ike.Authentication)]; ok {
+		ikeProposal += "-" + authentication[1]
+	}
+	ikeProposal += "-" + vpnDhGroups[int64Value(ike.DhGroup)][1]
+	espProposal := vpnEncryptions[core.StringNilMapper(ipsec.Encryption)][1]
+	if authentication, ok := vpnAuthentications[core.StringNilMapper(ipsec.Authentication)]; ok {
+		espProposal += "-" + authentication[1]
+	}
+	if boolValue(ipsec.Pfs) {
+		espProposal += "-" + vpnDhGroups[int64Value(ipsec.DhGroup)][1]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# ipsec.conf\n")
+	fmt.Fprintf(&b, "conn %s\n", config.peerConfigName())
+	fmt.Fprintf(&b, "    authby=secret\n")
+	fmt.Fprintf(&b, "    auto=start\n")
+	fmt.Fprintf(&b, "    ikev2=%s\n", ikev2)
+	fmt.Fprintf(&b, "    left=%s\n", core.StringNilMapper(connection.PeerGatewayAddress))
+	fmt.Fprintf(&b, "    leftsubnets={%s}\n", strings.Join(connection.PeerSubnets, " "))
+	fmt.Fprintf(&b, "    right=%s\n", core.StringNilMapper(connection.VPNGatewayAddress))
+	fmt.Fprintf(&b, "    rightsubnets={%s}\n", strings.Join(config.LocalSubnets, " "))
+	fmt.Fprintf(&b, "    ike=%s\n", ikeProposal)
+	fmt.Fprintf(&b, "    ikelifetime=%ds\n", int64Value(ike.KeyLifetime))
+	fmt.Fprintf(&b, "    esp=%s\n", espProposal)
+	fmt.Fprintf(&b, "    salifetime=%ds\n", int64Value(ipsec.KeyLifetime))
+	fmt.Fprintf(&b, "    pfs=%s\n", pfs)
+	if dpd := connection.DeadPeerDetection; dpd != nil {
+		fmt.Fprintf(&b, "    dpddelay=%d\n", int64Value(dpd.Interval))
+		fmt.Fprintf(&b, "    dpdtimeout=%d\n", int64Value(dpd.Interval)*int64Value(dpd.Threshold))
+		fmt.Fprintf(&b, "    dpdaction=%s\n", core.StringNilMapper(dpd.Action))
+	}
+	fmt.Fprintf(&b, "# ipsec.secrets\n")
+	fmt.Fprintf(&b, "%s %s : PSK %q\n", core.StringNilMapper(connection.PeerGatewayAddress), core.StringNilMapper(connection.VPNGatewayAddress), presharedKey)
+	return b.String()
+}
+
+// summary Vendor neutral list of the parameters to set on the peer gateway
+func (config *VPNConfiguration) summary() string {
+	connection, ike, ipsec := config.Connection, config.IkePolicy, config.IPSecPolicy
+	pfs := "disabled"
+	if boolValue(ipsec.Pfs) {
+		pfs = fmt.Sprintf("group %d", int64Value(ipsec.DhGroup))
+	}
+	rows := [][2]string{
+		{"Connection", core.StringNilMapper(connection.Name)},
+		{"Mode", core.StringNilMapper(connection.Mode)},
+		{"Local gateway", core.StringNilMapper(connection.PeerGatewayAddress)},
+		{"Local subnets", strings.Join(connection.PeerSubnets, ", ")},
+		{"Remote gateway", core.StringNilMapper(connection.VPNGatewayAddress)},
+		{"Remote subnets", strings.Join(config.LocalSubnets, ", ")},
+		{"Authentication", "pre-shared key"},
+		{"IKE version", fmt.Sprint(int64Value(ike.Version))},
+		{"IKE encryption", core.StringNilMapper(ike.Encryption)},
+		{"IKE integrity", core.StringNilMapper(ike.Authentication)},
+		{"IKE DH group", fmt.Sprint(int64Value(ike.DhGroup))},
+		{"IKE lifetime", fmt.Sprintf("%ds", int64Value(ike.KeyLifetime))},
+		{"IPSec protocol", "ESP"},
+		{"IPSec encryption", core.StringNilMapper(ipsec.Encryption)},
+		{"IPSec integrity", core.StringNilMapper(ipsec.Authentication)},
+		{"IPSec PFS", pfs},
+		{"IPSec lifetime", fmt.Sprintf("%ds", int64Value(ipsec.KeyLifetime))},
+	}
+	if dpd := connection.DeadPeerDetection; dpd != nil {
+		rows = append(rows, [2]string{"Dead peer detection", fmt.Sprintf("%s after %d missed checks every %ds", core.StringNilMapper(dpd.Action), int64Value(dpd.Threshold), int64Value(dpd.Interval))})
+	}
+	var b strings.Builder
+	for _, row := range rows {
+		fmt.Fprintf(&b, "%-20s %s\n", row[0]+":", row[1])
+	}
+	return b.String()
+}
+
+// peerConfigName Connection name usable in peer configuration files
+func (config *VPNConfiguration) peerConfigName() string {
+	name := core.StringNilMapper(config.Connection.Name)
+	if name == "" {
+		name = core.StringNilMapper(config.Connection.ID)
+	}
+	return "powervs-" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, name)
+}
+
+// checkIkePolicy Check the version, encryption, authentication, DH group and key lifetime of an
+// IKE policy
+func (errs *ValidationErrors) checkIkePolicy(version *int64, encryption *string, authentication *string, dhGroup *int64, keyLifetime *int64) {
+	if value := int64Value(version); value != 1 && value != 2 {
+		errs.add("version", "unknown IKE version %d, use 1 or 2", value)
+	}
+	if core.StringNilMapper(encryption) == IPSecPolicyEncryptionAes192GcmConst {
+		errs.add("encryption", "%s encryption is only available for IPSec policies", IPSecPolicyEncryptionAes192GcmConst)
+	}
+	gcm := errs.checkEncryption(encryption, authentication, IkePolicyAuthenticationNoneConst)
+	if gcm && int64Value(version) == 1 {
+		errs.add("encryption", "%s encryption needs IKE version 2", core.StringNilMapper(encryption))
+	}
+	errs.checkDhGroup(dhGroup)
+	errs.checkKeyLifetime(keyLifetime)
+}
+
+// checkIPSecPolicy Check the encryption, authentication, DH group and key lifetime of an IPSec
+// policy
+func (errs *ValidationErrors) checkIPSecPolicy(encryption *string, authentication *string, dhGroup *int64, keyLifetime *int64) {
+	errs.checkEncryption(encryption, authentication, IPSecPolicyAuthenticationNoneConst)
+	errs.checkDhGroup(dhGroup)
+	errs.checkKeyLifetime(keyLifetime)
+}
+
+// checkEncryption Check that GCM encryptions, which authenticate on their own, have no
+// authentication and that CBC encryptions have one. Returns whether the encryption is GCM.
+func (errs *ValidationErrors) checkEncryption(encryption *string, authentication *string, none string) bool {
+	value := core.StringNilMapper(encryption)
+	if _, ok := vpnEncryptions[value]; !ok {
+		errs.add("encryption", "unknown encryption %q", value)
+		return false
+	}
+	auth := core.StringNilMapper(authentication)
+	if auth == "" {
+		auth = none
+	}
+	gcm := strings.HasSuffix(value, "-gcm")
+	if gcm && auth != none {
+		errs.add("authentication", "%s encryption needs authentication %s, got %s", value, none, auth)
+	} else if !gcm && auth == none {
+		errs.add("authentication", "%s encryption needs an authentication", value)
+	}
+	return gcm
+}
+
+func (errs *ValidationErrors) checkDhGroup(dhGroup *int64) {
+	if _, ok := vpnDhGroups[int64Value(dhGroup)]; !ok {
+		errs.add("dhGroup", "unsupported DH group %d, use 1, 2, 5, 14, 19, 20 or 24", int64Value(dhGroup))
+	}
+}
+
+func (errs *ValidationErrors) checkKeyLifetime(keyLifetime *int64) {
+	if value := int64Value(keyLifetime); value < minVPNKeyLifetime || value > maxVPNKeyLifetime {
+		errs.add("keyLifetime", "key lifetime must be between %d and %d seconds, got %d", minVPNKeyLifetime, maxVPNKeyLifetime, value)
+	}
+}
@@ -0,0 +1,198 @@
+package powervsv1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+)
+
+func TestValidateVPNConfiguration(t *testing.T) {
+	valid := func() *VPNConfiguration {
+		return &VPNConfiguration{
+			Connection: &VPNConnection{Mode: core.StringPtr("policy"), PeerGatewayAddress: core.StringPtr("2.2.2.2"), PeerSubnets: []string{"192.168.0.0/24"}, NetworkIDs: []string{"net-1"},
+				DeadPeerDetection: &DeadPeerDetection{Action: core.StringPtr("restart"), Interval: core.Int64Ptr(30), Threshold: core.Int64Ptr(3)}},
+			IkePolicy:    &IkePolicy{Version: core.Int64Ptr(2), Encryption: core.StringPtr("aes-256-gcm"), Authentication: core.StringPtr("none"), DhGroup: core.Int64Ptr(20), KeyLifetime: core.Int64Ptr(28800)},
+			IPSecPolicy:  &IPSecPolicy{Encryption: core.StringPtr("aes-256-cbc"), Authentication: core.StringPtr("hmac-sha-256-128"), DhGroup: core.Int64Ptr(14), KeyLifetime: core.Int64Ptr(3600), Pfs: core.BoolPtr(true)},
+			LocalSubnets: []string{"10.0.0.0/24"},
+		}
+	}
+	tests := []struct {
+		name   string
+		change func(config *VPNConfiguration)
+		want   []ValidationError
+	}{
+		{"valid", func(config *VPNConfiguration) {}, nil},
+		{
+			name: "ike v1 with gcm and authentication",
+			change: func(config *VPNConfiguration) {
+				config.IkePolicy.Version = core.Int64Ptr(1)
+				config.IkePolicy.Authentication = core.StringPtr("sha-256")
+			},
+			want: []ValidationError{
+				{"authentication", "aes-256-gcm encryption needs authentication none, got sha-256"},
+				{"encryption", "aes-256-gcm encryption needs IKE version 2"},
+			},
+		},
+		{
+			name: "cbc without authentication",
+			change: func(config *VPNConfiguration) {
+				config.IPSecPolicy.Authentication = core.StringPtr("none")
+				config.IPSecPolicy.DhGroup = core.Int64Ptr(15)
+			},
+			want: []ValidationError{
+				{"authentication", "aes-256-cbc encryption needs an authentication"},
+				{"dhGroup", "unsupported DH group 15, use 1, 2, 5, 14, 19, 20 or 24"},
+			},
+		},
+		{
+			name: "lifetimes",
+			change: func(config *VPNConfiguration) {
+				config.IkePolicy.KeyLifetime = core.Int64Ptr(100000)
+				config.IPSecPolicy.KeyLifetime = core.Int64Ptr(120)
+			},
+			want: []ValidationError{
+				{"keyLifetime", "key lifetime must be between 180 and 86400 seconds, got 100000"},
+				{"keyLifetime", "key lifetime must be between 180 and 86400 seconds, got 120"},
+			},
+		},
+		{
+			name: "connection",
+			change: func(config *VPNConfiguration) {
+				config.Connection.PeerSubnets = []string{"10.0.0.128/25"}
+				config.Connection.DeadPeerDetection.Action = core.StringPtr("hold")
+				config.IPSecPolicy = nil
+			},
+			want: []ValidationError{
+				{"deadPeerDetection", `unknown action "hold", use restart`},
+				{"peerSubnets", "10.0.0.128/25 overlaps the workspace network 10.0.0.0/24"},
+				{"ipSecPolicy", "the IPSec policy is missing"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid()
+			tt.change(config)
+			err := config.Validate()
+			var errs ValidationErrors
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+			} else if !errors.As(err, &errs) || !reflect.DeepEqual([]ValidationError(errs), tt.want) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	options := (&PowervsV1{}).NewPcloudIkepoliciesPostOptions("ws", 2, "aes-128-gcm", 86400, "ike", "key", 2).SetAuthentication("sha1")
+	if err := ValidateOptions(options); err == nil || err.Error() != "invalid options: authentication: aes-128-gcm encryption needs authentication none, got sha1" {
+		t.Errorf("ValidateOptions() error = %v", err)
+	}
+}
+
+func TestPeerConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch strings.TrimPrefix(r.URL.Path, "/pcloud/v1") {
+		case "/cloud-instances/ws/vpn/vpn-connections/vpn-1":
+			_, _ = w.Write([]byte(`{"id": "vpn-1", "name": "office", "mode": "policy", "status": "active", "localGatewayAddress": "1.1.1.1", "peerGatewayAddress": "2.2.2.2", "vpnGatewayAddress": "3.3.3.3",
+				"networkIDs": ["net-1"], "peerSubnets": ["192.168.0.0/24"], "deadPeerDetection": {"action": "restart", "interval": 30, "threshold": 3},
+				"ikePolicy": {"id": "ike-1", "name": "ike", "href": "h"}, "ipSecPolicy": {"id": "ipsec-1", "name": "ipsec", "href": "h"}}`))
+		case "/cloud-instances/ws/vpn/ike-policies/ike-1":
+			_, _ = w.Write([]byte(`{"id": "ike-1", "name": "ike", "authentication": "sha-256", "dhGroup": 14, "encryption": "aes-256-cbc", "keyLifetime": 28800, "version": 2}`))
+		case "/cloud-instances/ws/vpn/ipsec-policies/ipsec-1":
+			_, _ = w.Write([]byte(`{"id": "ipsec-1", "name": "ipsec", "authentication": "none", "dhGroup": 19, "encryption": "aes-128-gcm", "keyLifetime": 3600, "pfs": true}`))
+		case "/cloud-instances/ws/networks/net-1":
+			_, _ = w.Write([]byte(`{"networkID": "net-1", "name": "private", "type": "vlan", "vlanID": 10, "cidr": "10.0.0.0/24", "gateway": "10.0.0.1",
+				"dnsServers": ["9.9.9.9"], "ipAddressMetrics": {"available": 250, "used": 3, "total": 253, "utilization": 1}, "ipAddressRanges": [{"startingIPAddress": "10.0.0.2", "endingIPAddress": "10.0.0.254"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	powervs, err := NewPowervsV1(&PowervsV1Options{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := powervs.GetVPNConfiguration(context.Background(), "ws", "vpn-1")
+	if err != nil {
+		t.Fatalf("GetVPNConfiguration() error = %v", err)
+	}
+	if !reflect.DeepEqual(config.LocalSubnets, []string{"10.0.0.0/24"}) {
+		t.Errorf("LocalSubnets = %v", config.LocalSubnets)
+	}
+
+	got, err := config.PeerConfig(VPNPeerConfigStrongSwan, "")
+	if err != nil {
+		t.Fatalf("PeerConfig() error = %v", err)
+	}
+	want := `connections {
+    powervs-office {
+        version = 2
+        local_addrs = 2.2.2.2
+        remote_addrs = 3.3.3.3
+        proposals = aes256-sha256-modp2048
+        rekey_time = 28800s
+        dpd_delay = 30s
+        local {
+            auth = psk
+            id = 2.2.2.2
+        }
+        remote {
+            auth = psk
+            id = 3.3.3.3
+        }
+        children {
+            powervs-office {
+                local_ts = 192.168.0.0/24
+                remote_ts = 10.0.0.0/24
+                esp_proposals = aes128gcm16-ecp256
+                rekey_time = 3600s
+                dpd_action = restart
+                start_action = start
+            }
+        }
+    }
+}
+secrets {
+    ike-powervs-office {
+        id = 3.3.3.3
+        secret = "<pre-shared key>"
+    }
+}
+`
+	if got != want {
+		t.Errorf("PeerConfig(strongswan) =\n%s\nwant\n%s", got, want)
+	}
+
+	got, err = config.PeerConfig(VPNPeerConfigLibreswan, "hunter2")
+	if err != nil {
+		t.Fatalf("PeerConfig() error = %v", err)
+	}
+	for _, line := range []string{"conn powervs-office", "    ike=aes256-sha2_256-modp2048", "    esp=aes_gcm128-dh19", "    dpdtimeout=90", `2.2.2.2 3.3.3.3 : PSK "hunter2"`} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("PeerConfig(libreswan) misses %q:\n%s", line, got)
+		}
+	}
+
+	got, err = config.PeerConfig(VPNPeerConfigSummary, "")
+	if err != nil {
+		t.Fatalf("PeerConfig() error = %v", err)
+	}
+	for _, line := range []string{"Remote subnets:      10.0.0.0/24", "IPSec PFS:           group 19", "Dead peer detection: restart after 3 missed checks every 30s"} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("PeerConfig(summary) misses %q:\n%s", line, got)
+		}
+	}
+
+	if _, err := config.PeerConfig("cisco", ""); err == nil {
+		t.Errorf("PeerConfig(cisco) expected error")
+	}
+}